@@ -1,6 +1,12 @@
 package main
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
 
 // Iterator interface
 type Iterator interface {
@@ -110,5 +116,45 @@ func main() {
 		fmt.Printf("Radio Station: %s FM\n", station)
 	}
 	
+	// Walk a paged source; the next page is fetched while this one is printed
+	fmt.Println("\nIterating a paged source (page size 2):")
+	source := NewMemoryPageSource(
+		NewRadioStation(87.9), NewRadioStation(91.3), NewRadioStation(95.5),
+		NewRadioStation(99.9), NewRadioStation(103.1),
+	)
+	source.Latency = 10 * time.Millisecond
+	paged := NewPagedIterator(context.Background(), source, 2)
+	for paged.HasNext() {
+		station := paged.Next().(RadioStation)
+		fmt.Printf("Radio Station: %s FM\n", station)
+	}
+	if err := paged.Err(); err != nil {
+		fmt.Printf("Iteration failed: %v\n", err)
+	}
+
+	// Stream a JSON-lines catalogue without loading it whole
+	fmt.Println("\nIterating a JSON-lines catalogue with a bad record:")
+	dir, err := os.MkdirTemp("", "stations")
+	if err != nil {
+		fmt.Printf("Cannot create catalogue: %v\n", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	catalogue := filepath.Join(dir, "stations.jsonl")
+	content := "{\"frequency\": 88.5}\n{\"frequency\": 93.1}\n{\"frequency\": 97.7}\nnot json\n"
+	if err := os.WriteFile(catalogue, []byte(content), 0o644); err != nil {
+		fmt.Printf("Cannot write catalogue: %v\n", err)
+		return
+	}
+	fileIterator := NewPagedIterator(context.Background(), NewFilePageSource(catalogue, JSONLines), 2)
+	defer fileIterator.Close()
+	for fileIterator.HasNext() {
+		station := fileIterator.Next().(RadioStation)
+		fmt.Printf("Radio Station: %s FM\n", station)
+	}
+	if err := fileIterator.Err(); err != nil {
+		fmt.Printf("Iteration stopped: %v\n", err)
+	}
+
 	fmt.Println("\nIterator provides sequential access to elements!")
 }
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// FileFormat selects how FilePageSource decodes each line
+type FileFormat int
+
+const (
+	// CSV expects the frequency in the first column; a "frequency" header row is skipped
+	CSV FileFormat = iota
+	// JSONLines expects one {"frequency": 89.1} object per line
+	JSONLines
+)
+
+// FilePageSource reads stations from a CSV or JSON-lines file without loading
+// it whole. The cursor is the byte offset where the next page starts, so each
+// fetch seeks straight to it and reads only one page worth of lines.
+type FilePageSource struct {
+	path   string
+	format FileFormat
+}
+
+func NewFilePageSource(path string, format FileFormat) *FilePageSource {
+	return &FilePageSource{path: path, format: format}
+}
+
+func (fs *FilePageSource) FetchPage(ctx context.Context, cursor string, size int) (Page, error) {
+	var offset int64
+	if cursor != "" {
+		var err error
+		offset, err = strconv.ParseInt(cursor, 10, 64)
+		if err != nil || offset < 0 {
+			return Page{}, fmt.Errorf("%w: %q", errInvalidCursor, cursor)
+		}
+	}
+
+	file, err := os.Open(fs.path)
+	if err != nil {
+		return Page{}, err
+	}
+	defer file.Close()
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return Page{}, err
+	}
+
+	reader := bufio.NewReader(file)
+	page := Page{Stations: make([]RadioStation, 0, size)}
+	for len(page.Stations) < size {
+		if err := ctx.Err(); err != nil {
+			return Page{}, err
+		}
+
+		line, err := reader.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return Page{}, err
+		}
+		lineOffset := offset
+		offset += int64(len(line))
+
+		if text := strings.TrimSpace(line); text != "" {
+			station, ok, decodeErr := fs.decode(text)
+			if decodeErr != nil {
+				return Page{}, fmt.Errorf("%s at byte %d: %w", fs.path, lineOffset, decodeErr)
+			}
+			if ok {
+				page.Stations = append(page.Stations, station)
+			}
+		}
+
+		if errors.Is(err, io.EOF) {
+			return page, nil
+		}
+	}
+
+	// Only hand out a cursor if something is actually left to read
+	if _, err := reader.Peek(1); err == nil {
+		page.NextCursor = strconv.FormatInt(offset, 10)
+	}
+	return page, nil
+}
+
+// decode parses one non-empty line; ok is false for lines that carry no station
+func (fs *FilePageSource) decode(line string) (RadioStation, bool, error) {
+	switch fs.format {
+	case CSV:
+		fields, err := csv.NewReader(strings.NewReader(line)).Read()
+		if err != nil {
+			return RadioStation{}, false, err
+		}
+		if strings.EqualFold(strings.TrimSpace(fields[0]), "frequency") {
+			return RadioStation{}, false, nil
+		}
+		frequency, err := strconv.ParseFloat(strings.TrimSpace(fields[0]), 64)
+		if err != nil {
+			return RadioStation{}, false, err
+		}
+		return NewRadioStation(frequency), true, nil
+	case JSONLines:
+		var record struct {
+			Frequency *float64 `json:"frequency"`
+		}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			return RadioStation{}, false, err
+		}
+		if record.Frequency == nil {
+			return RadioStation{}, false, errors.New("missing frequency")
+		}
+		return NewRadioStation(*record.Frequency), true, nil
+	default:
+		return RadioStation{}, false, fmt.Errorf("unknown file format %d", fs.format)
+	}
+}
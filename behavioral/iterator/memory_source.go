@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// MemoryPageSource is an offset-based PageSource over a fixed slice. It is the
+// in-memory stand-in for a real backend; Latency simulates a slow one.
+type MemoryPageSource struct {
+	stations []RadioStation
+	Latency  time.Duration
+}
+
+func NewMemoryPageSource(stations ...RadioStation) *MemoryPageSource {
+	return &MemoryPageSource{stations: stations}
+}
+
+func (ms *MemoryPageSource) FetchPage(ctx context.Context, cursor string, size int) (Page, error) {
+	offset := 0
+	if cursor != "" {
+		var err error
+		offset, err = strconv.Atoi(cursor)
+		if err != nil || offset < 0 || offset > len(ms.stations) {
+			return Page{}, fmt.Errorf("%w: %q", errInvalidCursor, cursor)
+		}
+	}
+
+	if ms.Latency > 0 {
+		select {
+		case <-time.After(ms.Latency):
+		case <-ctx.Done():
+			return Page{}, ctx.Err()
+		}
+	}
+
+	end := offset + size
+	if end > len(ms.stations) {
+		end = len(ms.stations)
+	}
+	page := Page{Stations: append([]RadioStation(nil), ms.stations[offset:end]...)}
+	if end < len(ms.stations) {
+		page.NextCursor = strconv.Itoa(end)
+	}
+	return page, nil
+}
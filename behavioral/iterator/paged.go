@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"errors"
+)
+
+// Page is one batch of stations returned by a PageSource
+type Page struct {
+	Stations []RadioStation
+	// NextCursor points at the following page; empty means this was the last one
+	NextCursor string
+}
+
+// PageSource serves stations one page at a time. The cursor is opaque to the
+// iterator: offset-based sources encode a position, cursor-based sources pass
+// through whatever token their backend hands out. An empty cursor asks for the
+// first page.
+type PageSource interface {
+	FetchPage(ctx context.Context, cursor string, size int) (Page, error)
+}
+
+type pageResult struct {
+	page Page
+	err  error
+}
+
+// PagedIterator walks a PageSource lazily. While the caller consumes the
+// current page, the next one is already being fetched in the background.
+type PagedIterator struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	pages   chan pageResult
+	current []RadioStation
+	index   int
+	last    bool // current is the last page
+	done    bool
+	closed  bool
+	err     error
+}
+
+// NewPagedIterator starts fetching from source right away; call Close when
+// abandoning the iteration early so the prefetcher stops.
+func NewPagedIterator(ctx context.Context, source PageSource, pageSize int) *PagedIterator {
+	if pageSize <= 0 {
+		pageSize = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	pi := &PagedIterator{
+		ctx:    ctx,
+		cancel: cancel,
+		// Unbuffered, so the prefetcher holds at most the one page it fetched
+		// while the caller consumes the current one: exactly one page ahead
+		pages: make(chan pageResult),
+	}
+	go pi.prefetch(source, pageSize)
+	return pi
+}
+
+func (pi *PagedIterator) prefetch(source PageSource, pageSize int) {
+	defer close(pi.pages)
+
+	cursor := ""
+	for {
+		page, err := source.FetchPage(pi.ctx, cursor, pageSize)
+		select {
+		case pi.pages <- pageResult{page: page, err: err}:
+		case <-pi.ctx.Done():
+			return
+		}
+		if err != nil || page.NextCursor == "" {
+			return
+		}
+		cursor = page.NextCursor
+	}
+}
+
+func (pi *PagedIterator) HasNext() bool {
+	for pi.index >= len(pi.current) {
+		if pi.done {
+			return false
+		}
+		// Exhausted before any error can happen; a context cancelled after
+		// the last page arrived stopped nothing
+		if pi.last {
+			pi.finish()
+			return false
+		}
+		result, ok := <-pi.pages
+		if !ok {
+			if err := pi.ctx.Err(); err != nil && !pi.closed {
+				pi.err = err
+			}
+			pi.finish()
+			return false
+		}
+		if result.err != nil {
+			pi.err = result.err
+			pi.finish()
+			return false
+		}
+		pi.current = result.page.Stations
+		pi.index = 0
+		pi.last = result.page.NextCursor == ""
+	}
+	return true
+}
+
+func (pi *PagedIterator) Next() interface{} {
+	if pi.HasNext() {
+		station := pi.current[pi.index]
+		pi.index++
+		return station
+	}
+	return nil
+}
+
+// Err reports the error that stopped the iteration, if any. It should be
+// checked once HasNext returns false.
+func (pi *PagedIterator) Err() error {
+	return pi.err
+}
+
+// Close stops the background prefetch. It is safe to call more than once.
+func (pi *PagedIterator) Close() {
+	pi.closed = true
+	pi.finish()
+}
+
+func (pi *PagedIterator) finish() {
+	pi.done = true
+	pi.current = nil
+	pi.cancel()
+}
+
+var errInvalidCursor = errors.New("invalid page cursor")
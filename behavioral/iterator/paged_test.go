@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func stations(frequencies ...float64) []RadioStation {
+	result := make([]RadioStation, len(frequencies))
+	for i, f := range frequencies {
+		result[i] = NewRadioStation(f)
+	}
+	return result
+}
+
+func frequencies(it *PagedIterator) []float64 {
+	var result []float64
+	for it.HasNext() {
+		result = append(result, it.Next().(RadioStation).GetFrequency())
+	}
+	return result
+}
+
+// recordingSource serves a MemoryPageSource and records the cursors asked for
+type recordingSource struct {
+	*MemoryPageSource
+	failAt string // cursor that fails
+
+	mu      sync.Mutex
+	cursors []string
+	fetched chan struct{}
+}
+
+func newRecordingSource(frequencies ...float64) *recordingSource {
+	return &recordingSource{
+		MemoryPageSource: NewMemoryPageSource(stations(frequencies...)...),
+		fetched:          make(chan struct{}, 100),
+	}
+}
+
+var errBackend = errors.New("backend down")
+
+func (rs *recordingSource) FetchPage(ctx context.Context, cursor string, size int) (Page, error) {
+	rs.mu.Lock()
+	rs.cursors = append(rs.cursors, cursor)
+	rs.mu.Unlock()
+	rs.fetched <- struct{}{}
+	if cursor == rs.failAt && cursor != "" {
+		return Page{}, errBackend
+	}
+	return rs.MemoryPageSource.FetchPage(ctx, cursor, size)
+}
+
+func (rs *recordingSource) fetches() []string {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	return slices.Clone(rs.cursors)
+}
+
+func TestPagedIteratorPages(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		n, size int
+		cursors []string
+	}{
+		{"empty", 0, 3, []string{""}},
+		{"one partial page", 2, 3, []string{""}},
+		{"exact pages", 6, 3, []string{"", "3"}},
+		{"last page partial", 7, 3, []string{"", "3", "6"}},
+		{"size below one", 2, 0, []string{"", "1"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var want []float64
+			for i := 0; i < tc.n; i++ {
+				want = append(want, float64(88+i))
+			}
+			source := newRecordingSource(want...)
+			it := NewPagedIterator(context.Background(), source, tc.size)
+			defer it.Close()
+
+			if got := frequencies(it); !slices.Equal(got, want) {
+				t.Errorf("iterated %v, want %v", got, want)
+			}
+			if err := it.Err(); err != nil {
+				t.Errorf("Err() = %v", err)
+			}
+			if got := source.fetches(); !slices.Equal(got, tc.cursors) {
+				t.Errorf("fetched cursors %q, want %q", got, tc.cursors)
+			}
+			if it.Next() != nil {
+				t.Error("Next after the end returned a station")
+			}
+		})
+	}
+}
+
+func TestPagedIteratorStaysOnePageAhead(t *testing.T) {
+	source := newRecordingSource(1, 2, 3, 4, 5, 6, 7, 8)
+	it := NewPagedIterator(context.Background(), source, 2)
+	defer it.Close()
+
+	if !it.HasNext() {
+		t.Fatal("HasNext() = false on the first page")
+	}
+	// The first page is being consumed and the second fetched; give the
+	// prefetcher time to wrongly fetch a third
+	<-source.fetched
+	<-source.fetched
+	time.Sleep(20 * time.Millisecond)
+	if got := source.fetches(); len(got) != 2 {
+		t.Errorf("fetched %q while on the first page, want two pages", got)
+	}
+}
+
+func TestPagedIteratorErr(t *testing.T) {
+	source := newRecordingSource(1, 2, 3, 4, 5)
+	source.failAt = "2"
+	it := NewPagedIterator(context.Background(), source, 2)
+	defer it.Close()
+
+	if got := frequencies(it); !slices.Equal(got, []float64{1, 2}) {
+		t.Errorf("iterated %v before the failure, want [1 2]", got)
+	}
+	if err := it.Err(); !errors.Is(err, errBackend) {
+		t.Errorf("Err() = %v, want %v", err, errBackend)
+	}
+	if it.HasNext() {
+		t.Error("HasNext() = true after the failure")
+	}
+}
+
+func TestPagedIteratorParentCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	source := newRecordingSource(1, 2, 3, 4, 5, 6)
+	source.Latency = time.Hour
+	it := NewPagedIterator(ctx, source, 2)
+	defer it.Close()
+
+	cancel()
+	if it.HasNext() {
+		t.Error("HasNext() = true after the context was cancelled")
+	}
+	if err := it.Err(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Err() = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestPagedIteratorCancelledAfterLastPage(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	it := NewPagedIterator(ctx, newRecordingSource(1, 2, 3), 2)
+	defer it.Close()
+
+	var got []float64
+	for len(got) < 3 && it.HasNext() {
+		got = append(got, it.Next().(RadioStation).GetFrequency())
+	}
+	// Everything was consumed before the cancel, so nothing was lost
+	cancel()
+	if it.HasNext() {
+		t.Error("HasNext() = true past the end")
+	}
+	if err := it.Err(); err != nil {
+		t.Errorf("Err() = %v after a complete iteration, want nil", err)
+	}
+}
+
+func TestPagedIteratorCloseStopsPrefetch(t *testing.T) {
+	source := newRecordingSource(1, 2, 3, 4, 5, 6)
+	source.Latency = time.Hour
+	it := NewPagedIterator(context.Background(), source, 2)
+	<-source.fetched
+
+	it.Close()
+	it.Close()
+	// The prefetcher closes pages when it returns; a page it raced to send
+	// before seeing the cancellation is drained here
+	stopped := make(chan struct{})
+	go func() {
+		for range it.pages {
+		}
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("prefetcher still running a second after Close")
+	}
+	if it.HasNext() {
+		t.Error("HasNext() = true after Close")
+	}
+	if err := it.Err(); err != nil {
+		t.Errorf("Err() = %v after Close, want nil", err)
+	}
+}
+
+func writeCatalogue(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFilePageSourceResumesAtByteOffset(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		format  FileFormat
+		content string
+		cursors []string
+	}{
+		{
+			name:    "csv",
+			format:  CSV,
+			content: "frequency,name\n88.5,Jazz\n93.1,News\n\n97.7,\"Rock, Classic\"\n",
+			cursors: []string{"", "35"},
+		},
+		{
+			name:    "json lines",
+			format:  JSONLines,
+			content: "{\"frequency\": 88.5}\n{\"frequency\": 93.1}\n\n{\"frequency\": 97.7}",
+			cursors: []string{"", "40"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeCatalogue(t, "stations", tc.content)
+			ctx := context.Background()
+
+			var got []float64
+			var cursors []string
+			cursor := ""
+			for {
+				cursors = append(cursors, cursor)
+				// A new source each time: the cursor alone is enough to resume
+				page, err := NewFilePageSource(path, tc.format).FetchPage(ctx, cursor, 2)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, station := range page.Stations {
+					got = append(got, station.GetFrequency())
+				}
+				if page.NextCursor == "" {
+					break
+				}
+				cursor = page.NextCursor
+			}
+			if want := []float64{88.5, 93.1, 97.7}; !slices.Equal(got, want) {
+				t.Errorf("read %v, want %v", got, want)
+			}
+			if !slices.Equal(cursors, tc.cursors) {
+				t.Errorf("cursors %q, want %q", cursors, tc.cursors)
+			}
+		})
+	}
+}
+
+func TestFilePageSourceErrors(t *testing.T) {
+	path := writeCatalogue(t, "stations.jsonl", "{\"frequency\": 88.5}\n{\"name\": \"Jazz\"}\n")
+	source := NewFilePageSource(path, JSONLines)
+	ctx := context.Background()
+
+	if _, err := source.FetchPage(ctx, "-1", 2); !errors.Is(err, errInvalidCursor) {
+		t.Errorf("negative cursor: err = %v, want %v", err, errInvalidCursor)
+	}
+	if _, err := source.FetchPage(ctx, "page-2", 2); !errors.Is(err, errInvalidCursor) {
+		t.Errorf("malformed cursor: err = %v, want %v", err, errInvalidCursor)
+	}
+	if _, err := source.FetchPage(ctx, "", 2); err == nil || err.Error() != path+" at byte 20: missing frequency" {
+		t.Errorf("record without a frequency: err = %v", err)
+	}
+
+	it := NewPagedIterator(ctx, source, 1)
+	defer it.Close()
+	if got := frequencies(it); !slices.Equal(got, []float64{88.5}) {
+		t.Errorf("iterated %v before the bad record, want [88.5]", got)
+	}
+	if it.Err() == nil {
+		t.Error("Err() = nil after a bad record")
+	}
+}
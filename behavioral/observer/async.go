@@ -0,0 +1,288 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// OverflowPolicy decides what an async subscriber does when its queue is full
+type OverflowPolicy int
+
+const (
+	// Block makes the publisher wait until the subscriber catches up
+	Block OverflowPolicy = iota
+	// DropOldest discards the oldest queued notification to make room
+	DropOldest
+	// DropNewest discards the incoming notification
+	DropNewest
+	// Disconnect unsubscribes the slow subscriber altogether
+	Disconnect
+)
+
+func (p OverflowPolicy) String() string {
+	switch p {
+	case Block:
+		return "block"
+	case DropOldest:
+		return "drop oldest"
+	case DropNewest:
+		return "drop newest"
+	case Disconnect:
+		return "disconnect"
+	default:
+		return fmt.Sprintf("OverflowPolicy(%d)", int(p))
+	}
+}
+
+// AsyncOptions configures an async subscriber
+type AsyncOptions struct {
+	// BufferSize bounds the subscriber's queue; values below 1 mean 1
+	BufferSize int
+	Overflow   OverflowPolicy
+	// OnPanic is called on the subscriber's goroutine with the recovered
+	// value whenever Update panics. Delivery continues with the next job.
+	OnPanic func(recovered any)
+}
+
+// DeliveryStats is a point-in-time view of an async subscriber
+type DeliveryStats struct {
+	Enqueued     uint64
+	Delivered    uint64
+	Dropped      uint64
+	Panics       uint64
+	Pending      int
+	Disconnected bool
+}
+
+func (s DeliveryStats) String() string {
+	return fmt.Sprintf("enqueued=%d delivered=%d dropped=%d panics=%d pending=%d disconnected=%t",
+		s.Enqueued, s.Delivered, s.Dropped, s.Panics, s.Pending, s.Disconnected)
+}
+
+// mailbox gives one subscriber its own bounded queue and goroutine, so a slow
+// or panicking subscriber cannot hold up the publisher or anyone else.
+type mailbox[E any] struct {
+	deliver func(E)
+	options AsyncOptions
+	queue   chan E
+	stop    chan struct{}
+	stopped chan struct{} // closed when the worker has exited
+	once    sync.Once
+
+	mu           sync.Mutex
+	sub          *Subscription
+	disconnected bool
+	closed       bool           // no push may start once set
+	pushes       sync.WaitGroup // pushes under way, which the worker waits for before its last drain
+
+	testHookAccepted func() // called by push once it may enqueue; set by tests only
+
+	enqueued  atomic.Uint64
+	delivered atomic.Uint64
+	dropped   atomic.Uint64
+	panics    atomic.Uint64
+}
+
+func newMailbox[E any](deliver func(E), options AsyncOptions) *mailbox[E] {
+	if options.BufferSize < 1 {
+		options.BufferSize = 1
+	}
+	m := &mailbox[E]{
+		deliver: deliver,
+		options: options,
+		queue:   make(chan E, options.BufferSize),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go m.run()
+	return m
+}
+
+// bind records the subscription a Disconnect policy has to cancel
+func (m *mailbox[E]) bind(sub *Subscription) {
+	m.mu.Lock()
+	m.sub = sub
+	disconnected := m.disconnected
+	m.mu.Unlock()
+
+	if disconnected {
+		sub.Unsubscribe()
+	}
+}
+
+func (m *mailbox[E]) push(event E) {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		m.dropped.Add(1)
+		return
+	}
+	m.pushes.Add(1)
+	m.mu.Unlock()
+	defer m.pushes.Done()
+	if m.testHookAccepted != nil {
+		m.testHookAccepted()
+	}
+
+	switch m.options.Overflow {
+	case Block:
+		select {
+		case m.queue <- event:
+			m.enqueued.Add(1)
+		case <-m.stop:
+			m.dropped.Add(1)
+		}
+	case DropOldest:
+		for {
+			select {
+			case m.queue <- event:
+				m.enqueued.Add(1)
+				return
+			default:
+			}
+			select {
+			case <-m.queue:
+				m.dropped.Add(1)
+			default:
+			}
+		}
+	case DropNewest:
+		select {
+		case m.queue <- event:
+			m.enqueued.Add(1)
+		default:
+			m.dropped.Add(1)
+		}
+	case Disconnect:
+		select {
+		case m.queue <- event:
+			m.enqueued.Add(1)
+		default:
+			m.dropped.Add(1)
+			m.disconnect()
+		}
+	}
+}
+
+func (m *mailbox[E]) disconnect() {
+	m.mu.Lock()
+	if m.disconnected {
+		m.mu.Unlock()
+		return
+	}
+	m.disconnected = true
+	sub := m.sub
+	m.mu.Unlock()
+
+	m.close()
+	if sub != nil {
+		sub.Unsubscribe()
+	}
+}
+
+func (m *mailbox[E]) run() {
+	defer close(m.stopped)
+	for {
+		select {
+		case event := <-m.queue:
+			m.handle(event)
+		case <-m.stop:
+			// Deliver what was accepted before stopping, including by pushes
+			// that raced with close and enqueued after the stop
+			m.pushes.Wait()
+			for {
+				select {
+				case event := <-m.queue:
+					m.handle(event)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (m *mailbox[E]) handle(event E) {
+	defer func() {
+		if r := recover(); r != nil {
+			m.panics.Add(1)
+			if m.options.OnPanic != nil {
+				m.options.OnPanic(r)
+			}
+		}
+	}()
+	m.deliver(event)
+	m.delivered.Add(1)
+}
+
+// close stops accepting notifications; the worker drains the queue and exits
+func (m *mailbox[E]) close() {
+	m.once.Do(func() {
+		m.mu.Lock()
+		m.closed = true
+		m.mu.Unlock()
+		close(m.stop)
+	})
+}
+
+func (m *mailbox[E]) stats() DeliveryStats {
+	m.mu.Lock()
+	disconnected := m.disconnected
+	m.mu.Unlock()
+
+	return DeliveryStats{
+		Enqueued:     m.enqueued.Load(),
+		Delivered:    m.delivered.Load(),
+		Dropped:      m.dropped.Load(),
+		Panics:       m.panics.Load(),
+		Pending:      len(m.queue),
+		Disconnected: disconnected,
+	}
+}
+
+// AsyncSubscription is the handle for an observer attached with AttachAsync
+type AsyncSubscription struct {
+	*Subscription
+	mailbox *mailbox[string]
+}
+
+// Stats returns the subscriber's delivery metrics
+func (as *AsyncSubscription) Stats() DeliveryStats {
+	return as.mailbox.stats()
+}
+
+// Close unsubscribes and waits until every job already queued has been
+// delivered. It must not be called from inside the observer's Update.
+func (as *AsyncSubscription) Close() {
+	as.Unsubscribe()
+	<-as.mailbox.stopped
+}
+
+// AttachAsync subscribes observer behind its own bounded queue and goroutine.
+// Notify only enqueues, so the observer never blocks the publisher unless its
+// policy is Block, and a panic in Update is recovered and counted.
+func (jp *JobPostings) AttachAsync(observer Observer, options AsyncOptions) *AsyncSubscription {
+	box := newMailbox(observer.Update, options)
+	sub := jp.observers.addWithCleanup(asyncObserver{box}, box.close)
+	box.bind(sub)
+	return &AsyncSubscription{Subscription: sub, mailbox: box}
+}
+
+// AttachAsyncContext is like AttachAsync, but the subscription is also
+// cancelled once ctx is done.
+func (jp *JobPostings) AttachAsyncContext(ctx context.Context, observer Observer, options AsyncOptions) *AsyncSubscription {
+	as := jp.AttachAsync(observer, options)
+	as.watch(ctx)
+	return as
+}
+
+// asyncObserver adapts a mailbox to the Observer interface
+type asyncObserver struct {
+	mailbox *mailbox[string]
+}
+
+func (ao asyncObserver) Update(jobTitle string) {
+	ao.mailbox.push(jobTitle)
+}
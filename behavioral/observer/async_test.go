@@ -0,0 +1,242 @@
+package main
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// gatedObserver blocks every Update until its gate opens, announcing each
+// job it starts on
+type gatedObserver struct {
+	gate    chan struct{}
+	started chan string
+
+	mu   sync.Mutex
+	jobs []string
+}
+
+func newGatedObserver() *gatedObserver {
+	return &gatedObserver{gate: make(chan struct{}), started: make(chan string, 100)}
+}
+
+func (g *gatedObserver) Update(jobTitle string) {
+	g.started <- jobTitle
+	<-g.gate
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.jobs = append(g.jobs, jobTitle)
+}
+
+func (g *gatedObserver) received() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return slices.Clone(g.jobs)
+}
+
+func TestAsyncOverflowPolicies(t *testing.T) {
+	for _, tc := range []struct {
+		policy   OverflowPolicy
+		received []string
+		stats    DeliveryStats
+		active   bool
+	}{
+		// a is being delivered and b fills the queue when c and d arrive;
+		// Disconnect drops c and unsubscribes, so d never reaches it
+		{DropOldest, []string{"a", "d"}, DeliveryStats{Enqueued: 4, Delivered: 2, Dropped: 2}, true},
+		{DropNewest, []string{"a", "b"}, DeliveryStats{Enqueued: 2, Delivered: 2, Dropped: 2}, true},
+		{Disconnect, []string{"a", "b"}, DeliveryStats{Enqueued: 2, Delivered: 2, Dropped: 1, Disconnected: true}, false},
+	} {
+		t.Run(tc.policy.String(), func(t *testing.T) {
+			jobPostings := NewJobPostings()
+			observer := newGatedObserver()
+			sub := jobPostings.AttachAsync(observer, AsyncOptions{BufferSize: 1, Overflow: tc.policy})
+
+			jobPostings.Notify("a")
+			<-observer.started
+			for _, job := range []string{"b", "c", "d"} {
+				jobPostings.Notify(job)
+			}
+			if got := sub.Active(); got != tc.active {
+				t.Errorf("Active() = %t with a full queue, want %t", got, tc.active)
+			}
+			close(observer.gate)
+			sub.Close()
+
+			if got := observer.received(); !slices.Equal(got, tc.received) {
+				t.Errorf("received %v, want %v", got, tc.received)
+			}
+			if got := sub.Stats(); got != tc.stats {
+				t.Errorf("Stats() = %v, want %v", got, tc.stats)
+			}
+			if jobPostings.Count() != 0 {
+				t.Errorf("Count() = %d after Close", jobPostings.Count())
+			}
+		})
+	}
+}
+
+func TestAsyncBlockWaitsForRoom(t *testing.T) {
+	jobPostings := NewJobPostings()
+	observer := newGatedObserver()
+	sub := jobPostings.AttachAsync(observer, AsyncOptions{BufferSize: 1, Overflow: Block})
+
+	jobPostings.Notify("a")
+	<-observer.started
+	jobPostings.Notify("b")
+	notified := make(chan struct{})
+	go func() {
+		jobPostings.Notify("c")
+		close(notified)
+	}()
+
+	select {
+	case <-notified:
+		t.Fatal("Notify returned with a full queue under Block")
+	case <-time.After(20 * time.Millisecond):
+	}
+	if got := sub.Stats().Pending; got != 1 {
+		t.Errorf("Pending = %d while blocked, want 1", got)
+	}
+	close(observer.gate)
+	<-notified
+	sub.Close()
+
+	if got := observer.received(); !slices.Equal(got, []string{"a", "b", "c"}) {
+		t.Errorf("received %v, want [a b c]", got)
+	}
+	if got, want := sub.Stats(), (DeliveryStats{Enqueued: 3, Delivered: 3}); got != want {
+		t.Errorf("Stats() = %v, want %v", got, want)
+	}
+}
+
+func TestAsyncPanicIsIsolated(t *testing.T) {
+	jobPostings := NewJobPostings()
+	var mu sync.Mutex
+	var panics []any
+	var delivered []string
+	sub := jobPostings.AttachAsync(ObserverFunc(func(jobTitle string) {
+		if jobTitle == "bad" {
+			panic("cannot handle " + jobTitle)
+		}
+		mu.Lock()
+		delivered = append(delivered, jobTitle)
+		mu.Unlock()
+	}), AsyncOptions{BufferSize: 8, OnPanic: func(recovered any) {
+		mu.Lock()
+		panics = append(panics, recovered)
+		mu.Unlock()
+	}})
+	steady := newRecorder()
+	jobPostings.Attach(steady)
+
+	for _, job := range []string{"good", "bad", "also good"} {
+		jobPostings.Notify(job)
+	}
+	sub.Close()
+
+	if steady.total() != 3 {
+		t.Errorf("a synchronous observer received %d jobs, want 3", steady.total())
+	}
+	if !slices.Equal(delivered, []string{"good", "also good"}) {
+		t.Errorf("delivered %v, want [good also good]", delivered)
+	}
+	if !slices.Equal(panics, []any{"cannot handle bad"}) {
+		t.Errorf("OnPanic got %v", panics)
+	}
+	if got, want := sub.Stats(), (DeliveryStats{Enqueued: 3, Delivered: 2, Panics: 1}); got != want {
+		t.Errorf("Stats() = %v, want %v", got, want)
+	}
+}
+
+func TestAsyncJobsQueuedBeforeUnsubscribeAreDelivered(t *testing.T) {
+	jobPostings := NewJobPostings()
+	observer := newGatedObserver()
+	sub := jobPostings.AttachAsync(observer, AsyncOptions{BufferSize: 4})
+
+	for _, job := range []string{"a", "b", "c"} {
+		jobPostings.Notify(job)
+	}
+	sub.Unsubscribe()
+	jobPostings.Notify("late")
+	close(observer.gate)
+	sub.Close()
+
+	if got := observer.received(); !slices.Equal(got, []string{"a", "b", "c"}) {
+		t.Errorf("received %v, want [a b c]", got)
+	}
+}
+
+// Run with go test -race: pushes under every policy race with close, and
+// every push must end up either delivered or counted as dropped
+func TestMailboxPushRacesWithClose(t *testing.T) {
+	const (
+		pushers = 4
+		pushes  = 50
+		rounds  = 200
+	)
+	for _, policy := range []OverflowPolicy{Block, DropOldest, DropNewest, Disconnect} {
+		t.Run(policy.String(), func(t *testing.T) {
+			for round := 0; round < rounds; round++ {
+				var mu sync.Mutex
+				delivered := 0
+				box := newMailbox(func(int) {
+					mu.Lock()
+					delivered++
+					mu.Unlock()
+				}, AsyncOptions{BufferSize: 2, Overflow: policy})
+
+				var wg sync.WaitGroup
+				for p := 0; p < pushers; p++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						for i := 0; i < pushes; i++ {
+							box.push(i)
+						}
+					}()
+				}
+				go box.close()
+				wg.Wait()
+				<-box.stopped
+
+				// DropOldest counts a job evicted from the queue as enqueued
+				// and dropped, so only this sum holds under every policy
+				stats := box.stats()
+				if got := uint64(delivered) + stats.Dropped; got != pushers*pushes || stats.Pending != 0 {
+					t.Fatalf("round %d: %d delivered and %d dropped of %d pushes, %d pending",
+						round, delivered, stats.Dropped, pushers*pushes, stats.Pending)
+				}
+			}
+		})
+	}
+}
+
+// A push that was accepted before close must still be delivered or counted
+// as dropped, however long the worker has been stopping
+func TestMailboxCloseDuringPush(t *testing.T) {
+	for _, policy := range []OverflowPolicy{Block, DropOldest, DropNewest, Disconnect} {
+		t.Run(policy.String(), func(t *testing.T) {
+			var delivered atomic.Uint64
+			box := newMailbox(func(int) { delivered.Add(1) }, AsyncOptions{BufferSize: 1, Overflow: policy})
+			box.testHookAccepted = func() {
+				box.close()
+				// Give the worker every chance to drain and exit before the
+				// push goes on
+				select {
+				case <-box.stopped:
+				case <-time.After(20 * time.Millisecond):
+				}
+			}
+			box.push(1)
+			<-box.stopped
+
+			if got := delivered.Load() + box.stats().Dropped; got != 1 {
+				t.Errorf("%d delivered and %d dropped, want the job either delivered or dropped",
+					delivered.Load(), box.stats().Dropped)
+			}
+		})
+	}
+}
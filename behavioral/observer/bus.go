@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"strings"
+)
+
+// Predicate decides whether a subscriber wants an event
+type Predicate[E any] func(event E) bool
+
+// All matches when every predicate matches; no predicates match everything
+func All[E any](predicates ...Predicate[E]) Predicate[E] {
+	return func(event E) bool {
+		for _, p := range predicates {
+			if !p(event) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Any matches when at least one predicate matches
+func Any[E any](predicates ...Predicate[E]) Predicate[E] {
+	return func(event E) bool {
+		for _, p := range predicates {
+			if p(event) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not inverts a predicate
+func Not[E any](predicate Predicate[E]) Predicate[E] {
+	return func(event E) bool {
+		return !predicate(event)
+	}
+}
+
+type busHandler[E any] struct {
+	pattern []string
+	filter  Predicate[E]
+	handle  func(event E)
+}
+
+// EventBus is a typed subject that routes events by topic. Topics are
+// dot-separated ("jobs.engineering.berlin"); subscription patterns may use
+// "*" to match exactly one segment and a trailing "#" to match any number of
+// remaining segments, including none. It gives the same concurrency and
+// delivery guarantees as JobPostings.
+type EventBus[E any] struct {
+	handlers subscribers[busHandler[E]]
+}
+
+func NewEventBus[E any]() *EventBus[E] {
+	return &EventBus[E]{}
+}
+
+// Subscribe delivers every event published on a topic matching pattern
+func (b *EventBus[E]) Subscribe(pattern string, handle func(event E)) *Subscription {
+	return b.SubscribeWhere(pattern, nil, handle)
+}
+
+// SubscribeWhere delivers events matching pattern for which filter returns
+// true. A nil filter accepts everything.
+func (b *EventBus[E]) SubscribeWhere(pattern string, filter Predicate[E], handle func(event E)) *Subscription {
+	return b.handlers.add(busHandler[E]{
+		pattern: strings.Split(pattern, "."),
+		filter:  filter,
+		handle:  handle,
+	})
+}
+
+// SubscribeContext is like SubscribeWhere, but the subscription is also
+// cancelled once ctx is done.
+func (b *EventBus[E]) SubscribeContext(ctx context.Context, pattern string, filter Predicate[E], handle func(event E)) *Subscription {
+	sub := b.SubscribeWhere(pattern, filter, handle)
+	sub.watch(ctx)
+	return sub
+}
+
+// Publish delivers event synchronously to every matching subscriber and
+// returns how many received it.
+func (b *EventBus[E]) Publish(topic string, event E) int {
+	segments := strings.Split(topic, ".")
+	delivered := 0
+	for _, entry := range b.handlers.snapshot() {
+		h := entry.value
+		if !topicMatches(h.pattern, segments) {
+			continue
+		}
+		if h.filter != nil && !h.filter(event) {
+			continue
+		}
+		h.handle(event)
+		delivered++
+	}
+	return delivered
+}
+
+// Count returns how many subscriptions are currently registered
+func (b *EventBus[E]) Count() int {
+	return len(b.handlers.snapshot())
+}
+
+func topicMatches(pattern, topic []string) bool {
+	for i, segment := range pattern {
+		if segment == "#" && i == len(pattern)-1 {
+			return true
+		}
+		if i >= len(topic) {
+			return false
+		}
+		if segment != "*" && segment != topic[i] {
+			return false
+		}
+	}
+	return len(pattern) == len(topic)
+}
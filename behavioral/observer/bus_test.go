@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestTopicMatches(t *testing.T) {
+	for _, tc := range []struct {
+		pattern, topic string
+		want           bool
+	}{
+		{"jobs.acme.berlin", "jobs.acme.berlin", true},
+		{"jobs.acme.berlin", "jobs.acme.paris", false},
+		{"jobs.acme", "jobs.acme.berlin", false},
+		{"jobs.acme.berlin", "jobs.acme", false},
+
+		// * matches exactly one segment
+		{"jobs.*.berlin", "jobs.acme.berlin", true},
+		{"jobs.*.berlin", "jobs.acme.paris", false},
+		{"jobs.*", "jobs.acme.berlin", false},
+		{"jobs.*.*", "jobs.acme", false},
+		{"*", "jobs", true},
+		{"*", "", true},
+
+		// A trailing # matches any number of segments, including none
+		{"jobs.#", "jobs", true},
+		{"jobs.#", "jobs.acme", true},
+		{"jobs.#", "jobs.acme.berlin", true},
+		{"jobs.#", "news.acme", false},
+		{"#", "jobs.acme.berlin", true},
+		{"jobs.*.#", "jobs", false},
+		{"jobs.*.#", "jobs.acme", true},
+
+		// # anywhere else is a literal segment
+		{"jobs.#.berlin", "jobs.acme.berlin", false},
+		{"jobs.#.berlin", "jobs.#.berlin", true},
+
+		// Empty segments are segments like any other
+		{"jobs..berlin", "jobs..berlin", true},
+		{"jobs.*.berlin", "jobs..berlin", true},
+		{"jobs", "jobs.", false},
+		{"jobs.*", "jobs.", true},
+		{"", "", true},
+		{"", "jobs", false},
+	} {
+		got := topicMatches(strings.Split(tc.pattern, "."), strings.Split(tc.topic, "."))
+		if got != tc.want {
+			t.Errorf("topicMatches(%q, %q) = %t, want %t", tc.pattern, tc.topic, got, tc.want)
+		}
+	}
+}
+
+func TestPredicates(t *testing.T) {
+	remoteGo := JobPosting{Location: "Remote", Salary: 120_000, Tags: []string{"Go"}}
+	berlinJava := JobPosting{Location: "Berlin", Salary: 90_000, Tags: []string{"java", "remote"}}
+	paris := JobPosting{Location: "Paris", Salary: 150_000}
+
+	for _, tc := range []struct {
+		name      string
+		predicate Predicate[JobPosting]
+		want      []bool // remoteGo, berlinJava, paris
+	}{
+		{"IsRemote", IsRemote, []bool{true, true, false}},
+		{"HasTag ignores case", HasTag("go"), []bool{true, false, false}},
+		{"MinSalary is inclusive", MinSalary(120_000), []bool{true, false, true}},
+		{"All", All(IsRemote, MinSalary(100_000)), []bool{true, false, false}},
+		{"All of nothing", All[JobPosting](), []bool{true, true, true}},
+		{"Any", Any(HasTag("java"), MinSalary(140_000)), []bool{false, true, true}},
+		{"Any of nothing", Any[JobPosting](), []bool{false, false, false}},
+		{"Not", Not(Predicate[JobPosting](IsRemote)), []bool{false, false, true}},
+	} {
+		for i, p := range []JobPosting{remoteGo, berlinJava, paris} {
+			if got := tc.predicate(p); got != tc.want[i] {
+				t.Errorf("%s(%v) = %t, want %t", tc.name, p, got, tc.want[i])
+			}
+		}
+	}
+}
+
+func TestEventBusDelivery(t *testing.T) {
+	bus := NewEventBus[JobPosting]()
+	received := make(map[string][]string)
+	receive := func(name string) func(JobPosting) {
+		return func(p JobPosting) { received[name] = append(received[name], p.Title) }
+	}
+
+	bus.Subscribe("jobs.#", receive("everything"))
+	bus.Subscribe("jobs.*.berlin", receive("berlin"))
+	bus.Subscribe("jobs.acme.*", receive("acme"))
+	bus.SubscribeWhere("jobs.#", All(IsRemote, HasTag("go")), receive("remote go"))
+	bus.SubscribeWhere("jobs.acme.*", Not(MinSalary(100_000)), receive("cheap acme"))
+	cancelled := bus.Subscribe("jobs.#", receive("cancelled"))
+	cancelled.Unsubscribe()
+
+	postings := []struct {
+		posting JobPosting
+		want    int
+	}{
+		{JobPosting{Title: "backend", Company: "Acme", Location: "Berlin", Salary: 110_000}, 3},
+		{JobPosting{Title: "mobile", Company: "Initech", Location: "Berlin", Salary: 80_000}, 2},
+		{JobPosting{Title: "platform", Company: "Acme", Location: "Remote", Salary: 95_000, Tags: []string{"go"}}, 4},
+		{JobPosting{Title: "data", Company: "Initech", Location: "Paris", Salary: 130_000, Tags: []string{"go"}}, 1},
+	}
+	for _, p := range postings {
+		if got := bus.Publish(p.posting.Topic(), p.posting); got != p.want {
+			t.Errorf("Publish(%s) delivered to %d subscribers, want %d", p.posting.Topic(), got, p.want)
+		}
+	}
+	if got := bus.Publish("news.acme.berlin", postings[0].posting); got != 0 {
+		t.Errorf("Publish on an unrelated topic delivered to %d subscribers", got)
+	}
+
+	for name, want := range map[string][]string{
+		"everything": {"backend", "mobile", "platform", "data"},
+		"berlin":     {"backend", "mobile"},
+		"acme":       {"backend", "platform"},
+		"remote go":  {"platform"},
+		"cheap acme": {"platform"},
+		"cancelled":  nil,
+	} {
+		if got := received[name]; !slices.Equal(got, want) {
+			t.Errorf("%s received %v, want %v", name, got, want)
+		}
+	}
+	if got := bus.Count(); got != 5 {
+		t.Errorf("Count() = %d, want 5", got)
+	}
+}
+
+func TestEventBusSubscribeContext(t *testing.T) {
+	bus := NewEventBus[string]()
+	ctx, cancel := context.WithCancel(context.Background())
+	var got []string
+	sub := bus.SubscribeContext(ctx, "alerts.*", func(s string) bool { return s != "noise" }, func(s string) {
+		got = append(got, s)
+	})
+
+	bus.Publish("alerts.disk", "full")
+	bus.Publish("alerts.disk", "noise")
+	cancel()
+	<-sub.Done()
+	bus.Publish("alerts.disk", "late")
+
+	if !slices.Equal(got, []string{"full"}) {
+		t.Errorf("received %v, want [full]", got)
+	}
+	if bus.Count() != 0 {
+		t.Errorf("Count() = %d after the context was cancelled, want 0", bus.Count())
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock is the time source used by the notification operators, so tests and
+// demos can drive them with a FakeClock instead of sleeping
+type Clock interface {
+	Now() time.Time
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is a pending call scheduled with Clock.AfterFunc
+type Timer interface {
+	Stop() bool
+}
+
+// RealClock is the wall clock
+type RealClock struct{}
+
+func (RealClock) Now() time.Time {
+	return time.Now()
+}
+
+func (RealClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+// FakeClock only moves when Advance is called. Timers that become due run
+// synchronously inside Advance, in deadline order.
+type FakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+func NewFakeClock(start time.Time) *FakeClock {
+	return &FakeClock{now: start}
+}
+
+func (fc *FakeClock) Now() time.Time {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return fc.now
+}
+
+func (fc *FakeClock) AfterFunc(d time.Duration, f func()) Timer {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	timer := &fakeTimer{clock: fc, deadline: fc.now.Add(d), f: f}
+	fc.timers = append(fc.timers, timer)
+	return timer
+}
+
+// Advance moves the clock forward by d, firing every timer due on the way
+func (fc *FakeClock) Advance(d time.Duration) {
+	fc.mu.Lock()
+	target := fc.now.Add(d)
+	fc.mu.Unlock()
+
+	for {
+		fc.mu.Lock()
+		sort.SliceStable(fc.timers, func(i, j int) bool {
+			return fc.timers[i].deadline.Before(fc.timers[j].deadline)
+		})
+		if len(fc.timers) == 0 || fc.timers[0].deadline.After(target) {
+			fc.now = target
+			fc.mu.Unlock()
+			return
+		}
+		timer := fc.timers[0]
+		fc.timers = fc.timers[1:]
+		fc.now = timer.deadline
+		fc.mu.Unlock()
+
+		// Run outside the lock; the callback may schedule new timers
+		timer.f()
+	}
+}
+
+type fakeTimer struct {
+	clock    *FakeClock
+	deadline time.Time
+	f        func()
+}
+
+func (ft *fakeTimer) Stop() bool {
+	ft.clock.mu.Lock()
+	defer ft.clock.mu.Unlock()
+	for i, timer := range ft.clock.timers {
+		if timer == ft {
+			ft.clock.timers = append(ft.clock.timers[:i], ft.clock.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -1,17 +1,18 @@
 package main
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+)
 
 // Observer interface
 type Observer interface {
 	Update(jobTitle string)
-	GetName() string
 }
 
 // Subject interface
 type Subject interface {
-	Attach(observer Observer)
-	Detach(observer Observer)
+	Attach(observer Observer) *Subscription
 	Notify(jobTitle string)
 }
 
@@ -33,33 +34,47 @@ func (js *JobSeeker) GetName() string {
 }
 
 // JobPostings - concrete subject
+//
+// JobPostings is safe for concurrent use. Attaching and unsubscribing copy the
+// subscription list and publish the new copy, so Notify iterates an immutable
+// snapshot without holding a lock. Delivery guarantee: every observer attached
+// before Notify starts receives the job exactly once; an observer attached
+// while a Notify is in flight only sees later jobs, and one unsubscribed
+// mid-flight may still receive the job being delivered. Observers may attach
+// or unsubscribe from inside Update.
 type JobPostings struct {
-	observers []Observer
+	observers subscribers[Observer]
 }
 
 func NewJobPostings() *JobPostings {
-	return &JobPostings{observers: make([]Observer, 0)}
+	return &JobPostings{}
 }
 
-func (jp *JobPostings) Attach(observer Observer) {
-	jp.observers = append(jp.observers, observer)
+// Attach subscribes observer and returns the handle that cancels it. The same
+// observer may be attached more than once; each handle is independent.
+func (jp *JobPostings) Attach(observer Observer) *Subscription {
+	return jp.observers.add(observer)
 }
 
-func (jp *JobPostings) Detach(observer Observer) {
-	for i, obs := range jp.observers {
-		if obs.GetName() == observer.GetName() {
-			jp.observers = append(jp.observers[:i], jp.observers[i+1:]...)
-			break
-		}
-	}
+// AttachContext is like Attach, but the subscription is also cancelled once
+// ctx is done.
+func (jp *JobPostings) AttachContext(ctx context.Context, observer Observer) *Subscription {
+	sub := jp.Attach(observer)
+	sub.watch(ctx)
+	return sub
 }
 
 func (jp *JobPostings) Notify(jobTitle string) {
-	for _, observer := range jp.observers {
-		observer.Update(jobTitle)
+	for _, entry := range jp.observers.snapshot() {
+		entry.value.Update(jobTitle)
 	}
 }
 
+// Count returns how many observers are currently attached
+func (jp *JobPostings) Count() int {
+	return len(jp.observers.snapshot())
+}
+
 func (jp *JobPostings) AddJob(jobTitle string) {
 	jp.Notify(jobTitle)
 }
@@ -74,8 +89,8 @@ func main() {
 	johnDoe := NewJobSeeker("John Doe")
 	janeDoe := NewJobSeeker("Jane Doe")
 	
-	// Subscribe job seekers
-	jobPostings.Attach(johnDoe)
+	// Subscribe job seekers, keeping the handle to unsubscribe later
+	johnSub := jobPostings.Attach(johnDoe)
 	jobPostings.Attach(janeDoe)
 	
 	// Post new job
@@ -87,10 +102,62 @@ func main() {
 	
 	// Unsubscribe one observer
 	fmt.Printf("\n%s unsubscribes...\n", johnDoe.GetName())
-	jobPostings.Detach(johnDoe)
+	johnSub.Unsubscribe()
+	johnSub.Unsubscribe() // cancelling twice is harmless
 	
 	fmt.Println("Posting job after John unsubscribed:")
 	jobPostings.AddJob("Product Manager")
 	
+	// Two seekers with the same name are still told apart by their handles
+	fmt.Println("\nTwo different seekers both named John Doe subscribe...")
+	firstJohn := jobPostings.Attach(NewJobSeeker("John Doe"))
+	ctx, cancel := context.WithCancel(context.Background())
+	secondJohn := jobPostings.AttachContext(ctx, NewJobSeeker("John Doe (until cancelled)"))
+	jobPostings.AddJob("DevOps Engineer")
+
+	fmt.Println("\nThe first John Doe unsubscribes, the second one's context is cancelled...")
+	firstJohn.Unsubscribe()
+	cancel()
+	<-secondJohn.Done() // the context watcher unsubscribes on its own goroutine
+	jobPostings.AddJob("QA Engineer")
+
+	// Typed events routed by topic and filtered per subscriber
+	fmt.Println("\nPublishing rich postings on an event bus:")
+	board := NewEventBus[JobPosting]()
+	gopher := NewJobSeeker("Gopher")
+	berliner := NewJobSeeker("Berliner")
+	board.SubscribeWhere("jobs.#", All(IsRemote, HasTag("go"), MinSalary(100_000)), gopher.Receive)
+	board.Subscribe("jobs.*.berlin", berliner.Receive)
+
+	postings := []JobPosting{
+		{Title: "Backend Engineer", Company: "Acme", Location: "Remote", Salary: 120_000, Tags: []string{"go", "postgres"}},
+		{Title: "Frontend Engineer", Company: "Acme", Location: "Remote", Salary: 130_000, Tags: []string{"typescript"}},
+		{Title: "Platform Engineer", Company: "Initech", Location: "Berlin", Salary: 95_000, Tags: []string{"go", "kubernetes"}},
+		{Title: "Junior Go Developer", Company: "Globex", Location: "Remote", Salary: 70_000, Tags: []string{"go"}},
+	}
+	for _, posting := range postings {
+		if board.Publish(posting.Topic(), posting) == 0 {
+			fmt.Printf("Nobody was interested in: %s\n", posting.Title)
+		}
+	}
+
+	fmt.Println("\nPosting a burst of jobs to asynchronous subscribers:")
+	demoAsyncDelivery()
+
+	fmt.Println("\nReplaying retained jobs for late and restarted subscribers:")
+	demoDurableReplay()
+
+	fmt.Println("\nPushing jobs to a webhook and an SSE stream:")
+	demoPushDelivery()
+
+	fmt.Println("\nBulk-posting 500 jobs through notification operators:")
+	demoNotificationOperators()
+
+	fmt.Println("\nObserving job postings as a reactive stream:")
+	demoReactiveStreams()
+
+	fmt.Println("\nPosting jobs while subscribers come and go concurrently:")
+	demoConcurrentSubscribers()
+
 	fmt.Println("\nObserver pattern enables loose coupling between subjects and observers!")
 }
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrSubscriberActive is returned when a durable subscriber ID is attached twice
+var ErrSubscriberActive = errors.New("durable subscriber already attached")
+
+type startKind int
+
+const (
+	startLatest startKind = iota
+	startBeginning
+	startOffset
+	startTime
+)
+
+// StartPosition says where a durable subscriber without a stored offset
+// begins reading
+type StartPosition struct {
+	kind   startKind
+	offset uint64
+	time   time.Time
+}
+
+// FromLatest only delivers jobs posted after attaching
+func FromLatest() StartPosition {
+	return StartPosition{kind: startLatest}
+}
+
+// FromBeginning replays every retained job
+func FromBeginning() StartPosition {
+	return StartPosition{kind: startBeginning}
+}
+
+// FromOffset replays starting at the given log offset
+func FromOffset(offset uint64) StartPosition {
+	return StartPosition{kind: startOffset, offset: offset}
+}
+
+// FromTime replays jobs posted at or after t
+func FromTime(t time.Time) StartPosition {
+	return StartPosition{kind: startTime, time: t}
+}
+
+// DurableJobPostings is a JobPostings that records every job in an EventLog.
+// Plain observers attached with Attach still get live, synchronous updates;
+// durable subscribers read from the log on their own goroutine, so they can
+// start in the past and resume after a restart.
+type DurableJobPostings struct {
+	*JobPostings
+	log     EventLog
+	offsets OffsetStore
+	now     func() time.Time
+
+	mu       sync.Mutex
+	appended chan struct{} // closed and replaced on every append
+	active   map[string]bool
+}
+
+func NewDurableJobPostings(log EventLog, offsets OffsetStore) *DurableJobPostings {
+	return &DurableJobPostings{
+		JobPostings: NewJobPostings(),
+		log:         log,
+		offsets:     offsets,
+		now:         time.Now,
+		appended:    make(chan struct{}),
+		active:      make(map[string]bool),
+	}
+}
+
+// PostJob records the job in the log, wakes durable subscribers and notifies
+// live observers. Unlike the embedded JobPostings.AddJob it reports the
+// logged event, or the error if the log could not record it.
+func (d *DurableJobPostings) PostJob(jobTitle string) (JobEvent, error) {
+	d.mu.Lock()
+	event, err := d.log.Append(jobTitle, d.now())
+	if err != nil {
+		d.mu.Unlock()
+		return JobEvent{}, err
+	}
+	close(d.appended)
+	d.appended = make(chan struct{})
+	d.mu.Unlock()
+
+	d.Notify(jobTitle)
+	return event, nil
+}
+
+// AddJob keeps the JobPostings signature so DurableJobPostings can stand in
+// for it, but still records the job. Use PostJob to see log errors.
+func (d *DurableJobPostings) AddJob(jobTitle string) {
+	d.PostJob(jobTitle)
+}
+
+func (d *DurableJobPostings) changed() <-chan struct{} {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.appended
+}
+
+// AttachDurable delivers jobs from the log to observer, acknowledging each
+// one in the offset store once Update returns. If subscriberID already has an
+// acknowledged offset, delivery resumes right after it and start is ignored.
+func (d *DurableJobPostings) AttachDurable(subscriberID string, observer Observer, start StartPosition) (*DurableSubscription, error) {
+	next, ok, err := d.offsets.Load(subscriberID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if next, err = d.resolve(start); err != nil {
+			return nil, err
+		}
+	}
+
+	d.mu.Lock()
+	if d.active[subscriberID] {
+		d.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrSubscriberActive, subscriberID)
+	}
+	d.active[subscriberID] = true
+	d.mu.Unlock()
+
+	ds := &DurableSubscription{
+		id:       subscriberID,
+		observer: observer,
+		subject:  d,
+		next:     next,
+		stop:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	ds.Subscription = newSubscription(func(*Subscription) {
+		d.mu.Lock()
+		delete(d.active, subscriberID)
+		d.mu.Unlock()
+	}, ds.halt)
+	go ds.run()
+	return ds, nil
+}
+
+// AttachDurableContext is like AttachDurable, but the subscription is also
+// cancelled once ctx is done.
+func (d *DurableJobPostings) AttachDurableContext(ctx context.Context, subscriberID string, observer Observer, start StartPosition) (*DurableSubscription, error) {
+	ds, err := d.AttachDurable(subscriberID, observer, start)
+	if err != nil {
+		return nil, err
+	}
+	ds.watch(ctx)
+	return ds, nil
+}
+
+func (d *DurableJobPostings) resolve(start StartPosition) (uint64, error) {
+	switch start.kind {
+	case startBeginning:
+		return d.log.Oldest(), nil
+	case startOffset:
+		return start.offset, nil
+	case startTime:
+		return offsetAt(d.log, start.time)
+	default:
+		return d.log.Next(), nil
+	}
+}
+
+// DurableSubscription is the handle for a subscriber attached with
+// AttachDurable
+type DurableSubscription struct {
+	*Subscription
+	id       string
+	observer Observer
+	subject  *DurableJobPostings
+	stop     chan struct{}
+	stopped  chan struct{}
+	once     sync.Once
+
+	mu      sync.Mutex
+	next    uint64
+	skipped uint64
+	err     error
+}
+
+const (
+	durableBatch      = 64
+	durableMinBackoff = 10 * time.Millisecond
+	durableMaxBackoff = 5 * time.Second
+)
+
+func (ds *DurableSubscription) run() {
+	defer close(ds.stopped)
+	log := ds.subject.log
+	backoff := durableMinBackoff
+	retrying := false
+	for {
+		// Grab the wake-up channel before reading so an append that lands
+		// in between is not missed
+		changed := ds.subject.changed()
+		events, err := log.Read(ds.Position(), durableBatch)
+		if err != nil {
+			// Appends may never come, so retry on a timer rather than
+			// waiting for one
+			ds.fail(err)
+			retrying = true
+			timer := time.NewTimer(backoff)
+			select {
+			case <-timer.C:
+				backoff = min(2*backoff, durableMaxBackoff)
+				continue
+			case <-ds.stop:
+				timer.Stop()
+				return
+			}
+		}
+		if retrying {
+			backoff, retrying = durableMinBackoff, false
+			ds.fail(nil)
+		}
+		if len(events) == 0 {
+			select {
+			case <-changed:
+				continue
+			case <-ds.stop:
+				return
+			}
+		}
+
+		for _, event := range events {
+			select {
+			case <-ds.stop:
+				return
+			default:
+			}
+			ds.deliver(event)
+		}
+	}
+}
+
+func (ds *DurableSubscription) deliver(event JobEvent) {
+	ds.mu.Lock()
+	if event.Offset > ds.next {
+		// Evicted from the log before this subscriber got to them
+		ds.skipped += event.Offset - ds.next
+	}
+	ds.mu.Unlock()
+
+	ds.observer.Update(event.JobTitle)
+
+	ds.mu.Lock()
+	ds.next = event.Offset + 1
+	ds.mu.Unlock()
+	if err := ds.subject.offsets.Save(ds.id, event.Offset+1); err != nil {
+		ds.fail(err)
+	}
+}
+
+func (ds *DurableSubscription) fail(err error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	ds.err = err
+}
+
+func (ds *DurableSubscription) halt() {
+	ds.once.Do(func() { close(ds.stop) })
+}
+
+// Position is the offset of the next job this subscriber will receive
+func (ds *DurableSubscription) Position() uint64 {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	return ds.next
+}
+
+// Skipped counts jobs evicted from the log before they could be delivered
+func (ds *DurableSubscription) Skipped() uint64 {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	return ds.skipped
+}
+
+// Err returns the most recent log or offset store error, if any. A failed
+// log read is retried with exponential backoff, and Err goes back to nil
+// once a retried read succeeds.
+func (ds *DurableSubscription) Err() error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	return ds.err
+}
+
+// Close unsubscribes and waits for the subscriber's goroutine to exit. It
+// must not be called from inside the observer's Update.
+func (ds *DurableSubscription) Close() {
+	ds.Unsubscribe()
+	<-ds.stopped
+}
+
+// WaitFor blocks until every job before offset has been delivered or ctx is
+// done.
+func (ds *DurableSubscription) WaitFor(ctx context.Context, offset uint64) error {
+	ticker := time.NewTicker(5 * time.Millisecond)
+	defer ticker.Stop()
+	for ds.Position() < offset {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ds.stopped:
+			return errors.New("durable subscription closed")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyLog fails the first failures reads, then behaves like its RingLog
+type flakyLog struct {
+	*RingLog
+	failures atomic.Int32
+}
+
+func (l *flakyLog) Read(from uint64, max int) ([]JobEvent, error) {
+	if l.failures.Add(-1) >= 0 {
+		return nil, errors.New("disk unavailable")
+	}
+	return l.RingLog.Read(from, max)
+}
+
+func TestDurableSubscriptionRetriesFailedReads(t *testing.T) {
+	log := &flakyLog{RingLog: NewRingLog(16)}
+	log.failures.Store(3)
+	board := NewDurableJobPostings(log, NewMemoryOffsetStore())
+
+	// Posted before attaching, so no append will wake the subscriber
+	if _, err := board.PostJob("Backend Engineer"); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := newRecorder()
+	sub, err := board.AttachDurable("retry", rec, FromBeginning())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := sub.WaitFor(ctx, 1); err != nil {
+		t.Fatalf("job not delivered after read errors: %v", err)
+	}
+	if got := rec.count("Backend Engineer"); got != 1 {
+		t.Errorf("delivered %d times, want 1", got)
+	}
+	if err := sub.Err(); err != nil {
+		t.Errorf("Err() = %v after a successful read, want nil", err)
+	}
+}
+
+func TestDurableAddJobIsLogged(t *testing.T) {
+	log := NewRingLog(16)
+	board := NewDurableJobPostings(log, NewMemoryOffsetStore())
+	board.AddJob("Data Engineer")
+	if log.Next() != 1 {
+		t.Fatalf("AddJob did not record the job, log next = %d", log.Next())
+	}
+}
+
+// waitFor fails the test unless sub delivers every job before offset soon
+func waitFor(t *testing.T, sub *DurableSubscription, offset uint64) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := sub.WaitFor(ctx, offset); err != nil {
+		t.Fatalf("waiting for offset %d: %v (at %d)", offset, err, sub.Position())
+	}
+}
+
+// titles reads every retained event from log
+func titles(t *testing.T, log EventLog, from uint64, max int) []string {
+	t.Helper()
+	events, err := log.Read(from, max)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result []string
+	for i, event := range events {
+		if i > 0 && event.Offset != events[i-1].Offset+1 {
+			t.Errorf("offsets %d and %d are not consecutive", events[i-1].Offset, event.Offset)
+		}
+		result = append(result, event.JobTitle)
+	}
+	return result
+}
+
+func TestSegmentLogReopen(t *testing.T) {
+	dir := t.TempDir()
+	log, err := OpenSegmentLog(dir, 3, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 7; i++ {
+		if _, err := log.Append(fmt.Sprintf("job %d", i), epoch); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reopened, err := OpenSegmentLog(dir, 3, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopened.Oldest() != 0 || reopened.Next() != 7 {
+		t.Errorf("reopened log spans [%d, %d), want [0, 7)", reopened.Oldest(), reopened.Next())
+	}
+	if got := titles(t, reopened, 4, 2); !slices.Equal(got, []string{"job 4", "job 5"}) {
+		t.Errorf("Read(4, 2) = %v", got)
+	}
+	event, err := reopened.Append("job 7", epoch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Offset != 7 {
+		t.Errorf("first append after reopening got offset %d, want 7", event.Offset)
+	}
+	if got := titles(t, reopened, 0, 100); len(got) != 8 || got[7] != "job 7" {
+		t.Errorf("Read(0, 100) = %v", got)
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "*"+segmentExt)); len(files) != 3 {
+		t.Errorf("%d segment files for 8 events of 3, want 3", len(files))
+	}
+}
+
+func TestSegmentLogRetention(t *testing.T) {
+	dir := t.TempDir()
+	log, err := OpenSegmentLog(dir, 2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 7; i++ {
+		if _, err := log.Append(fmt.Sprintf("job %d", i), epoch); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Segments start at 0, 2, 4 and 6; only the newest two are kept
+	if log.Oldest() != 4 || log.Next() != 7 {
+		t.Errorf("log spans [%d, %d), want [4, 7)", log.Oldest(), log.Next())
+	}
+	if got := titles(t, log, 0, 100); !slices.Equal(got, []string{"job 4", "job 5", "job 6"}) {
+		t.Errorf("Read(0, 100) = %v", got)
+	}
+	reopened, err := OpenSegmentLog(dir, 2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopened.Oldest() != 4 || reopened.Next() != 7 {
+		t.Errorf("reopened log spans [%d, %d), want [4, 7)", reopened.Oldest(), reopened.Next())
+	}
+}
+
+func TestDurableSubscriberResumesAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	open := func() *DurableJobPostings {
+		t.Helper()
+		log, err := OpenSegmentLog(filepath.Join(dir, "jobs"), 2, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		offsets, err := OpenFileOffsetStore(filepath.Join(dir, "offsets.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return NewDurableJobPostings(log, offsets)
+	}
+
+	board := open()
+	for _, job := range []string{"Backend", "Frontend", "Data"} {
+		if _, err := board.PostJob(job); err != nil {
+			t.Fatal(err)
+		}
+	}
+	first := &orderedRecorder{}
+	sub, err := board.AttachDurable("mailer", first, FromBeginning())
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, sub, 3)
+	sub.Close()
+
+	// The process restarts: jobs posted while the mailer was down are
+	// delivered, and nothing it acknowledged is delivered again. Its stored
+	// offset wins over the start position.
+	board = open()
+	for _, job := range []string{"Mobile", "SRE"} {
+		if _, err := board.PostJob(job); err != nil {
+			t.Fatal(err)
+		}
+	}
+	second := &orderedRecorder{}
+	sub, err = board.AttachDurable("mailer", second, FromLatest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+	waitFor(t, sub, 5)
+
+	if got := first.received(); !slices.Equal(got, []string{"Backend", "Frontend", "Data"}) {
+		t.Errorf("before the restart the mailer received %v", got)
+	}
+	if got := second.received(); !slices.Equal(got, []string{"Mobile", "SRE"}) {
+		t.Errorf("after the restart the mailer received %v, want [Mobile SRE]", got)
+	}
+	if next, ok, _ := board.offsets.Load("mailer"); !ok || next != 5 {
+		t.Errorf("stored offset = %d, %t, want 5", next, ok)
+	}
+}
+
+func TestDurableStartPositions(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		start StartPosition
+		want  []string
+	}{
+		{"beginning", FromBeginning(), []string{"a", "b", "c", "d", "e"}},
+		{"latest", FromLatest(), []string{"e"}},
+		{"offset", FromOffset(2), []string{"c", "d", "e"}},
+		{"time between jobs", FromTime(epoch.Add(90 * time.Second)), []string{"c", "d", "e"}},
+		{"time of a job", FromTime(epoch.Add(time.Minute)), []string{"b", "c", "d", "e"}},
+		{"time before every job", FromTime(epoch.Add(-time.Hour)), []string{"a", "b", "c", "d", "e"}},
+		{"time after every job", FromTime(epoch.Add(time.Hour)), []string{"e"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// a to d are posted a minute apart before attaching, e after
+			board := NewDurableJobPostings(NewRingLog(16), NewMemoryOffsetStore())
+			var clock atomic.Int64
+			board.now = func() time.Time { return epoch.Add(time.Duration(clock.Load())) }
+			for i, job := range []string{"a", "b", "c", "d"} {
+				clock.Store(int64(time.Duration(i) * time.Minute))
+				if _, err := board.PostJob(job); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			rec := &orderedRecorder{}
+			sub, err := board.AttachDurable("seeker", rec, tc.start)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer sub.Close()
+			clock.Store(int64(2 * time.Hour))
+			if _, err := board.PostJob("e"); err != nil {
+				t.Fatal(err)
+			}
+			waitFor(t, sub, 5)
+
+			if got := rec.received(); !slices.Equal(got, tc.want) {
+				t.Errorf("received %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDurableSubscriberSkipsEvictedJobs(t *testing.T) {
+	log := NewRingLog(3)
+	board := NewDurableJobPostings(log, NewMemoryOffsetStore())
+	if _, err := board.PostJob("job 0"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The subscriber is stuck on job 0 while five more are posted, and the
+	// ring only keeps the last three
+	observer := newGatedObserver()
+	sub, err := board.AttachDurable("slow", observer, FromBeginning())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+	<-observer.started
+	for i := 1; i <= 5; i++ {
+		if _, err := board.PostJob(fmt.Sprintf("job %d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if log.Oldest() != 3 || log.Next() != 6 {
+		t.Fatalf("ring spans [%d, %d), want [3, 6)", log.Oldest(), log.Next())
+	}
+	close(observer.gate)
+	waitFor(t, sub, 6)
+
+	if got := observer.received(); !slices.Equal(got, []string{"job 0", "job 3", "job 4", "job 5"}) {
+		t.Errorf("received %v", got)
+	}
+	if got := sub.Skipped(); got != 2 {
+		t.Errorf("Skipped() = %d, want 2", got)
+	}
+
+	// Starting at an evicted offset skips to the oldest retained job
+	late, err := board.AttachDurable("late", newRecorder(), FromOffset(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer late.Close()
+	waitFor(t, late, 6)
+	if got := late.Skipped(); got != 2 {
+		t.Errorf("Skipped() from an evicted offset = %d, want 2", got)
+	}
+}
+
+func TestDurableSubscriberIDIsExclusive(t *testing.T) {
+	board := NewDurableJobPostings(NewRingLog(4), NewMemoryOffsetStore())
+	sub, err := board.AttachDurable("mailer", newRecorder(), FromLatest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := board.AttachDurable("mailer", newRecorder(), FromLatest()); !errors.Is(err, ErrSubscriberActive) {
+		t.Errorf("attaching twice: err = %v, want %v", err, ErrSubscriberActive)
+	}
+	sub.Close()
+	again, err := board.AttachDurable("mailer", newRecorder(), FromLatest())
+	if err != nil {
+		t.Fatalf("attaching after Close: %v", err)
+	}
+	again.Close()
+}
+
+// orderedRecorder keeps the jobs it receives in order
+type orderedRecorder struct {
+	mu   sync.Mutex
+	jobs []string
+}
+
+func (r *orderedRecorder) Update(jobTitle string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.jobs = append(r.jobs, jobTitle)
+}
+
+func (r *orderedRecorder) received() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.jobs)
+}
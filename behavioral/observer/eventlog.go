@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JobEvent is a job posting as recorded in an EventLog
+type JobEvent struct {
+	Offset   uint64    `json:"offset"`
+	Time     time.Time `json:"time"`
+	JobTitle string    `json:"job"`
+}
+
+// EventLog retains posted jobs so late or restarted subscribers can replay
+// them. Offsets start at 0 and grow by one per append; old events may be
+// evicted, in which case Oldest moves forward.
+type EventLog interface {
+	Append(jobTitle string, at time.Time) (JobEvent, error)
+	// Read returns up to max retained events with Offset >= from, oldest first
+	Read(from uint64, max int) ([]JobEvent, error)
+	// Oldest is the offset of the first retained event
+	Oldest() uint64
+	// Next is the offset the next appended event will get
+	Next() uint64
+}
+
+// RingLog keeps the most recent events in memory
+type RingLog struct {
+	mu     sync.RWMutex
+	events []JobEvent
+	start  int // index of the oldest event in events
+	count  int
+	next   uint64
+}
+
+func NewRingLog(capacity int) *RingLog {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &RingLog{events: make([]JobEvent, capacity)}
+}
+
+func (rl *RingLog) Append(jobTitle string, at time.Time) (JobEvent, error) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	event := JobEvent{Offset: rl.next, Time: at, JobTitle: jobTitle}
+	rl.next++
+	if rl.count < len(rl.events) {
+		rl.events[(rl.start+rl.count)%len(rl.events)] = event
+		rl.count++
+	} else {
+		rl.events[rl.start] = event
+		rl.start = (rl.start + 1) % len(rl.events)
+	}
+	return event, nil
+}
+
+func (rl *RingLog) Read(from uint64, max int) ([]JobEvent, error) {
+	rl.mu.RLock()
+	defer rl.mu.RUnlock()
+
+	oldest := rl.next - uint64(rl.count)
+	if from < oldest {
+		from = oldest
+	}
+	var result []JobEvent
+	for offset := from; offset < rl.next && len(result) < max; offset++ {
+		index := (rl.start + int(offset-oldest)) % len(rl.events)
+		result = append(result, rl.events[index])
+	}
+	return result, nil
+}
+
+func (rl *RingLog) Oldest() uint64 {
+	rl.mu.RLock()
+	defer rl.mu.RUnlock()
+	return rl.next - uint64(rl.count)
+}
+
+func (rl *RingLog) Next() uint64 {
+	rl.mu.RLock()
+	defer rl.mu.RUnlock()
+	return rl.next
+}
+
+// SegmentLog persists events as JSON lines in a directory of segment files.
+// Each segment is named after the offset of its first event and holds at most
+// SegmentSize events; once more than MaxSegments exist the oldest is deleted.
+// Reopening a directory picks up where the previous process stopped.
+type SegmentLog struct {
+	dir         string
+	segmentSize int
+	maxSegments int
+
+	mu       sync.RWMutex
+	segments []uint64 // base offsets, ascending
+	inLast   int      // events in the newest segment
+	next     uint64
+}
+
+const segmentExt = ".log"
+
+// OpenSegmentLog opens or creates a segment log in dir. maxSegments of 0
+// keeps every segment.
+func OpenSegmentLog(dir string, segmentSize, maxSegments int) (*SegmentLog, error) {
+	if segmentSize < 1 {
+		return nil, errors.New("segment size must be at least 1")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	sl := &SegmentLog{dir: dir, segmentSize: segmentSize, maxSegments: maxSegments}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+		base, err := strconv.ParseUint(strings.TrimSuffix(name, segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		sl.segments = append(sl.segments, base)
+	}
+	sort.Slice(sl.segments, func(i, j int) bool { return sl.segments[i] < sl.segments[j] })
+
+	if len(sl.segments) > 0 {
+		last := sl.segments[len(sl.segments)-1]
+		events, err := sl.readSegment(last)
+		if err != nil {
+			return nil, err
+		}
+		sl.inLast = len(events)
+		sl.next = last + uint64(len(events))
+	}
+	return sl, nil
+}
+
+func (sl *SegmentLog) segmentPath(base uint64) string {
+	return filepath.Join(sl.dir, fmt.Sprintf("%020d%s", base, segmentExt))
+}
+
+func (sl *SegmentLog) Append(jobTitle string, at time.Time) (JobEvent, error) {
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+
+	if len(sl.segments) == 0 || sl.inLast >= sl.segmentSize {
+		sl.segments = append(sl.segments, sl.next)
+		sl.inLast = 0
+		if err := sl.enforceRetention(); err != nil {
+			return JobEvent{}, err
+		}
+	}
+
+	event := JobEvent{Offset: sl.next, Time: at, JobTitle: jobTitle}
+	line, err := json.Marshal(event)
+	if err != nil {
+		return JobEvent{}, err
+	}
+	file, err := os.OpenFile(sl.segmentPath(sl.segments[len(sl.segments)-1]), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return JobEvent{}, err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return JobEvent{}, err
+	}
+	if err := file.Close(); err != nil {
+		return JobEvent{}, err
+	}
+
+	sl.inLast++
+	sl.next++
+	return event, nil
+}
+
+func (sl *SegmentLog) enforceRetention() error {
+	for sl.maxSegments > 0 && len(sl.segments) > sl.maxSegments {
+		if err := os.Remove(sl.segmentPath(sl.segments[0])); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		sl.segments = sl.segments[1:]
+	}
+	return nil
+}
+
+func (sl *SegmentLog) Read(from uint64, max int) ([]JobEvent, error) {
+	sl.mu.RLock()
+	defer sl.mu.RUnlock()
+
+	var result []JobEvent
+	for i, base := range sl.segments {
+		if i+1 < len(sl.segments) && sl.segments[i+1] <= from {
+			continue // every event in this segment is before from
+		}
+		events, err := sl.readSegment(base)
+		if err != nil {
+			return nil, err
+		}
+		for _, event := range events {
+			if event.Offset < from {
+				continue
+			}
+			result = append(result, event)
+			if len(result) == max {
+				return result, nil
+			}
+		}
+	}
+	return result, nil
+}
+
+func (sl *SegmentLog) readSegment(base uint64) ([]JobEvent, error) {
+	file, err := os.Open(sl.segmentPath(base))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil // rolled but nothing written yet
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var events []JobEvent
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event JobEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return nil, fmt.Errorf("segment %d: %w", base, err)
+		}
+		events = append(events, event)
+	}
+	return events, scanner.Err()
+}
+
+func (sl *SegmentLog) Oldest() uint64 {
+	sl.mu.RLock()
+	defer sl.mu.RUnlock()
+	if len(sl.segments) == 0 {
+		return sl.next
+	}
+	return sl.segments[0]
+}
+
+func (sl *SegmentLog) Next() uint64 {
+	sl.mu.RLock()
+	defer sl.mu.RUnlock()
+	return sl.next
+}
+
+// offsetAt returns the offset of the first retained event posted at or after t
+func offsetAt(log EventLog, t time.Time) (uint64, error) {
+	const batch = 256
+	from := log.Oldest()
+	for {
+		events, err := log.Read(from, batch)
+		if err != nil {
+			return 0, err
+		}
+		for _, event := range events {
+			if !event.Time.Before(t) {
+				return event.Offset, nil
+			}
+		}
+		if len(events) < batch {
+			return log.Next(), nil
+		}
+		from = events[len(events)-1].Offset + 1
+	}
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// countingSeeker is a silent observer that only tallies deliveries
+type countingSeeker struct {
+	received atomic.Int64
+}
+
+func (cs *countingSeeker) Update(jobTitle string) {
+	cs.received.Add(1)
+}
+
+// demoConcurrentSubscribers hammers Attach/Unsubscribe from several goroutines
+// while jobs are being posted. observer_test.go does the same under
+// `go test -race` and checks every delivery.
+func demoConcurrentSubscribers() {
+	const (
+		posters  = 4
+		churners = 8
+		rounds   = 200
+	)
+
+	jobPostings := NewJobPostings()
+	steady := &countingSeeker{}
+	jobPostings.Attach(steady)
+
+	var wg sync.WaitGroup
+	for p := 0; p < posters; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				jobPostings.AddJob(fmt.Sprintf("Job %d-%d", p, i))
+			}
+		}(p)
+	}
+	for c := 0; c < churners; c++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			seeker := &countingSeeker{}
+			for i := 0; i < rounds; i++ {
+				jobPostings.Attach(seeker).Unsubscribe()
+			}
+		}()
+	}
+	wg.Wait()
+
+	fmt.Printf("Steady seeker received %d of %d jobs, %d observer(s) left attached\n",
+		steady.received.Load(), posters*rounds, jobPostings.Count())
+}
+
+// slowSeeker takes its time reading every posting
+type slowSeeker struct {
+	*JobSeeker
+	delay time.Duration
+}
+
+func (ss slowSeeker) Update(jobTitle string) {
+	time.Sleep(ss.delay)
+	ss.JobSeeker.Update(jobTitle)
+}
+
+// grumpySeeker panics on every posting
+type grumpySeeker struct{}
+
+func (grumpySeeker) Update(jobTitle string) {
+	panic("not another " + jobTitle)
+}
+
+// demoAsyncDelivery posts a burst of jobs to subscribers with different
+// speeds and overflow policies, then prints what each of them got.
+func demoAsyncDelivery() {
+	jobPostings := NewJobPostings()
+
+	fast := jobPostings.AttachAsync(NewJobSeeker("Fast Fiona"), AsyncOptions{BufferSize: 8})
+	dropping := jobPostings.AttachAsync(slowSeeker{NewJobSeeker("Slow Sam"), 20 * time.Millisecond},
+		AsyncOptions{BufferSize: 2, Overflow: DropOldest})
+	strict := jobPostings.AttachAsync(slowSeeker{NewJobSeeker("Strict Stan"), 20 * time.Millisecond},
+		AsyncOptions{BufferSize: 1, Overflow: Disconnect})
+	grumpy := jobPostings.AttachAsync(grumpySeeker{}, AsyncOptions{
+		BufferSize: 8,
+		OnPanic: func(recovered any) {
+			fmt.Printf("Recovered from a panicking observer: %v\n", recovered)
+		},
+	})
+
+	start := time.Now()
+	for i := 1; i <= 5; i++ {
+		jobPostings.AddJob(fmt.Sprintf("Burst job #%d", i))
+	}
+	fmt.Printf("Publisher finished posting in %s\n", time.Since(start).Round(time.Millisecond))
+
+	for _, sub := range []*AsyncSubscription{fast, dropping, strict, grumpy} {
+		sub.Close()
+	}
+	fmt.Printf("Fast Fiona:  %s\n", fast.Stats())
+	fmt.Printf("Slow Sam:    %s\n", dropping.Stats())
+	fmt.Printf("Strict Stan: %s\n", strict.Stats())
+	fmt.Printf("Grumpy:      %s\n", grumpy.Stats())
+}
+
+// demoDurableReplay shows late subscribers replaying the log and a restarted
+// subscriber resuming from its acknowledged offset, using a segment log on
+// disk.
+func demoDurableReplay() {
+	dir, err := os.MkdirTemp("", "job-postings")
+	if err != nil {
+		fmt.Printf("Cannot create log directory: %v\n", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	open := func() (*DurableJobPostings, error) {
+		log, err := OpenSegmentLog(filepath.Join(dir, "log"), 2, 0)
+		if err != nil {
+			return nil, err
+		}
+		offsets, err := OpenFileOffsetStore(filepath.Join(dir, "offsets.json"))
+		if err != nil {
+			return nil, err
+		}
+		return NewDurableJobPostings(log, offsets), nil
+	}
+
+	board, err := open()
+	if err != nil {
+		fmt.Printf("Cannot open job board: %v\n", err)
+		return
+	}
+	var last JobEvent
+	for _, title := range []string{"Software Engineer", "Data Scientist", "Product Manager"} {
+		if last, err = board.PostJob(title); err != nil {
+			fmt.Printf("Cannot post job: %v\n", err)
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	fmt.Println("Late Larry attaches from the beginning:")
+	larry, err := board.AttachDurable("larry", NewJobSeeker("Late Larry"), FromBeginning())
+	if err != nil {
+		fmt.Printf("Cannot attach: %v\n", err)
+		return
+	}
+	larry.WaitFor(ctx, last.Offset+1)
+
+	fmt.Println("Olivia attaches from offset 2:")
+	olivia, err := board.AttachDurable("olivia", NewJobSeeker("Olivia"), FromOffset(2))
+	if err != nil {
+		fmt.Printf("Cannot attach: %v\n", err)
+		return
+	}
+	olivia.WaitFor(ctx, last.Offset+1)
+	olivia.Close()
+	larry.Close()
+
+	fmt.Println("The process restarts; two more jobs are posted while Larry is away...")
+	board, err = open()
+	if err != nil {
+		fmt.Printf("Cannot reopen job board: %v\n", err)
+		return
+	}
+	for _, title := range []string{"Designer", "Support Engineer"} {
+		if last, err = board.PostJob(title); err != nil {
+			fmt.Printf("Cannot post job: %v\n", err)
+			return
+		}
+	}
+
+	larry, err = board.AttachDurable("larry", NewJobSeeker("Late Larry"), FromBeginning())
+	if err != nil {
+		fmt.Printf("Cannot attach: %v\n", err)
+		return
+	}
+	defer larry.Close()
+	larry.WaitFor(ctx, last.Offset+1)
+	fmt.Printf("Larry resumed and is now at offset %d\n", larry.Position())
+}
+
+// demoPushDelivery forwards jobs to a webhook receiver and streams them to an
+// SSE client, both served by httptest.
+func demoPushDelivery() {
+	secret := []byte("s3cret")
+	var calls atomic.Int64
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !VerifySignature(secret, body, r.Header.Get(SignatureHeader)) {
+			http.Error(w, "bad signature", http.StatusUnauthorized)
+			return
+		}
+		if calls.Add(1) == 1 {
+			http.Error(w, "warming up", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Printf("Webhook received: %s\n", body)
+	}))
+	defer receiver.Close()
+
+	noSleep := func(time.Duration) {}
+	jobPostings := NewJobPostings()
+	webhook := NewWebhookObserver(receiver.URL, WebhookOptions{Secret: secret, Sleep: noSleep})
+	broken := NewWebhookObserver(receiver.URL, WebhookOptions{Secret: []byte("wrong"), MaxAttempts: 2, Sleep: noSleep})
+	hooks := []*AsyncSubscription{
+		jobPostings.AttachAsync(webhook, AsyncOptions{BufferSize: 16}),
+		jobPostings.AttachAsync(broken, AsyncOptions{BufferSize: 16}),
+	}
+
+	sse := NewSSEHandler(jobPostings, 16)
+	stream := httptest.NewServer(sse)
+	defer stream.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	request, _ := http.NewRequestWithContext(ctx, http.MethodGet, stream.URL, nil)
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		fmt.Printf("Cannot connect to SSE stream: %v\n", err)
+		return
+	}
+	defer response.Body.Close()
+	for sse.Clients() < 1 {
+		time.Sleep(time.Millisecond) // wait for the browser's subscription
+	}
+
+	jobPostings.AddJob("Site Reliability Engineer")
+	jobPostings.AddJob("Security Engineer")
+
+	lines := bufio.NewScanner(response.Body)
+	for events := 0; events < 2 && lines.Scan(); {
+		if line := lines.Text(); strings.HasPrefix(line, "data: ") {
+			fmt.Printf("SSE client received: %s\n", strings.TrimPrefix(line, "data: "))
+			events++
+		}
+	}
+	cancel()
+
+	for _, hook := range hooks {
+		hook.Close()
+	}
+	for _, letter := range broken.DeadLetters() {
+		fmt.Printf("Dead letter after %d attempts: %s (%v)\n", letter.Attempts, letter.Payload.JobTitle, letter.Err)
+	}
+}
+
+// demoNotificationOperators bulk-posts jobs through debounce, throttle, batch
+// and dedupe operators driven by a fake clock.
+func demoNotificationOperators() {
+	clock := NewFakeClock(time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC))
+	jobPostings := NewJobPostings()
+
+	jobPostings.Attach(Debounce(NewJobSeeker("Debounced Dana"), time.Second, clock))
+	throttled := Throttle(NewJobSeeker("Throttled Theo"), time.Minute, clock)
+	jobPostings.Attach(throttled)
+	batched := Batch(NewJobSeeker("Batched Bea"), 200, 10*time.Second, clock)
+	jobPostings.Attach(batched)
+	jobPostings.Attach(Dedupe(Batch(Digest(NewJobSeeker("Unique Uma")), 0, 10*time.Second, clock), 0, clock))
+
+	// A recruiter bulk-posts 500 jobs, one every 10ms, most of them reposts
+	for i := 0; i < 500; i++ {
+		jobPostings.AddJob(fmt.Sprintf("Role %d", i%3))
+		clock.Advance(10 * time.Millisecond)
+	}
+	clock.Advance(time.Minute)
+	batched.Flush()
+
+	fmt.Printf("Throttled Theo was spared %d notifications\n", throttled.Dropped())
+}
+
+// demoReactiveStreams composes the hot job postings stream with cold streams
+// and operators.
+func demoReactiveStreams() {
+	jobPostings := NewJobPostings()
+	jobs := jobPostings.Observe()
+
+	onError := func(err error) { fmt.Printf("Stream failed: %v\n", err) }
+
+	engineering := Filter(jobs, func(title string) bool {
+		return strings.Contains(title, "Engineer")
+	})
+	Take(Map(engineering, strings.ToUpper), 2).Subscribe(
+		func(title string) { fmt.Printf("Engineering feed: %s\n", title) },
+		onError,
+		func() { fmt.Println("Engineering feed complete after 2 jobs") },
+	)
+
+	counter := Scan(DistinctUntilChanged(jobs), 0, func(count int, _ string) int { return count + 1 })
+	counting := counter.Subscribe(func(count int) {
+		fmt.Printf("Distinct postings so far: %d\n", count)
+	}, onError, nil)
+	defer counting.Unsubscribe()
+
+	for _, title := range []string{"Software Engineer", "Software Engineer", "Designer", "Data Engineer", "QA Engineer"} {
+		jobPostings.AddJob(title)
+	}
+
+	// A cold stream replays for every subscriber; a subject fails the merge
+	featured := NewStreamSubject[string]()
+	Merge(FromSlice("Archived: COBOL Developer"), featured.Observable()).Subscribe(
+		func(title string) { fmt.Printf("Merged feed: %s\n", title) },
+		onError,
+		func() { fmt.Println("Merged feed complete") },
+	)
+	featured.Next("Featured: Staff Engineer")
+	featured.Error(errors.New("featured feed went offline"))
+	featured.Next("Never delivered")
+}
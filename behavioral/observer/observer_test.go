@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// recorder counts every job it receives by title
+type recorder struct {
+	mu   sync.Mutex
+	jobs map[string]int
+}
+
+func newRecorder() *recorder {
+	return &recorder{jobs: make(map[string]int)}
+}
+
+func (r *recorder) Update(jobTitle string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.jobs[jobTitle]++
+}
+
+func (r *recorder) count(jobTitle string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.jobs[jobTitle]
+}
+
+func (r *recorder) total() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	total := 0
+	for _, n := range r.jobs {
+		total += n
+	}
+	return total
+}
+
+// Run with go test -race: posters notify while churners attach and
+// unsubscribe, and the steady observer must get every job exactly once
+func TestConcurrentAttachNotifyUnsubscribe(t *testing.T) {
+	const (
+		posters  = 4
+		churners = 8
+		rounds   = 300
+	)
+	jobPostings := NewJobPostings()
+	steady := newRecorder()
+	jobPostings.Attach(steady)
+
+	var wg sync.WaitGroup
+	for p := 0; p < posters; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				jobPostings.AddJob(fmt.Sprintf("job %d-%d", p, i))
+			}
+		}(p)
+	}
+	var churned atomic.Int64
+	for c := 0; c < churners; c++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				sub := jobPostings.Attach(newRecorder())
+				jobPostings.Count()
+				sub.Unsubscribe()
+				sub.Unsubscribe()
+				churned.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for p := 0; p < posters; p++ {
+		for i := 0; i < rounds; i++ {
+			if n := steady.count(fmt.Sprintf("job %d-%d", p, i)); n != 1 {
+				t.Fatalf("job %d-%d delivered %d times, want 1", p, i, n)
+			}
+		}
+	}
+	if got := jobPostings.Count(); got != 1 {
+		t.Errorf("Count() = %d after churning %d subscriptions, want 1", got, churned.Load())
+	}
+}
+
+// An observer attached before Notify starts receives the job; one attached
+// after it returns does not see it; an unsubscribed one sees nothing more
+func TestDeliveryGuarantee(t *testing.T) {
+	jobPostings := NewJobPostings()
+	early := newRecorder()
+	sub := jobPostings.Attach(early)
+	jobPostings.AddJob("first")
+
+	late := newRecorder()
+	jobPostings.Attach(late)
+	sub.Unsubscribe()
+	jobPostings.AddJob("second")
+
+	if early.count("first") != 1 || early.count("second") != 0 {
+		t.Errorf("early observer got %v, want only first", early.jobs)
+	}
+	if late.count("first") != 0 || late.count("second") != 1 {
+		t.Errorf("late observer got %v, want only second", late.jobs)
+	}
+}
+
+// selfRemoving unsubscribes itself and attaches a newcomer from inside Update
+type selfRemoving struct {
+	jobPostings *JobPostings
+	sub         *Subscription
+	newcomer    *recorder
+	received    atomic.Int64
+}
+
+func (s *selfRemoving) Update(jobTitle string) {
+	s.received.Add(1)
+	s.sub.Unsubscribe()
+	s.jobPostings.Attach(s.newcomer)
+}
+
+func TestAttachAndUnsubscribeFromUpdate(t *testing.T) {
+	jobPostings := NewJobPostings()
+	observer := &selfRemoving{jobPostings: jobPostings, newcomer: newRecorder()}
+	observer.sub = jobPostings.Attach(observer)
+
+	jobPostings.AddJob("first")
+	jobPostings.AddJob("second")
+
+	if got := observer.received.Load(); got != 1 {
+		t.Errorf("self-removing observer received %d jobs, want 1", got)
+	}
+	// The newcomer attached during the first Notify only sees the second job
+	if observer.newcomer.count("first") != 0 || observer.newcomer.count("second") != 1 {
+		t.Errorf("newcomer got %v, want only second", observer.newcomer.jobs)
+	}
+}
+
+func TestAttachContextUnsubscribesConcurrently(t *testing.T) {
+	jobPostings := NewJobPostings()
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				jobPostings.AddJob("tick")
+			}
+		}
+	}()
+
+	subs := make([]*Subscription, 50)
+	cancels := make([]context.CancelFunc, len(subs))
+	for i := range subs {
+		var ctx context.Context
+		ctx, cancels[i] = context.WithCancel(context.Background())
+		subs[i] = jobPostings.AttachContext(ctx, newRecorder())
+	}
+	for i := range subs {
+		cancels[i]()
+	}
+	for i, sub := range subs {
+		select {
+		case <-sub.Done():
+		case <-time.After(5 * time.Second):
+			t.Fatalf("subscription %d not removed after its context was cancelled", i)
+		}
+	}
+	close(stop)
+	wg.Wait()
+
+	if got := jobPostings.Count(); got != 0 {
+		t.Errorf("Count() = %d, want 0", got)
+	}
+}
+
+func TestObserverAttachedTwiceIsDeliveredTwice(t *testing.T) {
+	jobPostings := NewJobPostings()
+	r := newRecorder()
+	first := jobPostings.Attach(r)
+	jobPostings.Attach(r)
+	jobPostings.AddJob("job")
+	first.Unsubscribe()
+	jobPostings.AddJob("job")
+	if got := r.total(); got != 3 {
+		t.Errorf("received %d deliveries, want 3", got)
+	}
+}
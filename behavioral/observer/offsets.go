@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// OffsetStore remembers, per durable subscriber, the offset of the next
+// event it has not acknowledged yet.
+type OffsetStore interface {
+	Load(subscriberID string) (next uint64, ok bool, err error)
+	Save(subscriberID string, next uint64) error
+}
+
+// MemoryOffsetStore keeps acknowledged offsets for the lifetime of the process
+type MemoryOffsetStore struct {
+	mu      sync.Mutex
+	offsets map[string]uint64
+}
+
+func NewMemoryOffsetStore() *MemoryOffsetStore {
+	return &MemoryOffsetStore{offsets: make(map[string]uint64)}
+}
+
+func (ms *MemoryOffsetStore) Load(subscriberID string) (uint64, bool, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	next, ok := ms.offsets[subscriberID]
+	return next, ok, nil
+}
+
+func (ms *MemoryOffsetStore) Save(subscriberID string, next uint64) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.offsets[subscriberID] = next
+	return nil
+}
+
+// FileOffsetStore keeps acknowledged offsets in a JSON file, rewritten
+// atomically on every save so a crash never leaves it half written.
+type FileOffsetStore struct {
+	path string
+
+	mu      sync.Mutex
+	offsets map[string]uint64
+}
+
+func OpenFileOffsetStore(path string) (*FileOffsetStore, error) {
+	fs := &FileOffsetStore{path: path, offsets: make(map[string]uint64)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fs, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &fs.offsets); err != nil {
+		return nil, err
+	}
+	return fs, nil
+}
+
+func (fs *FileOffsetStore) Load(subscriberID string) (uint64, bool, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	next, ok := fs.offsets[subscriberID]
+	return next, ok, nil
+}
+
+func (fs *FileOffsetStore) Save(subscriberID string, next uint64) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	fs.offsets[subscriberID] = next
+	data, err := json.MarshalIndent(fs.offsets, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(fs.path), filepath.Base(fs.path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), fs.path)
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The operators below wrap an Observer and are Observers themselves, so they
+// can be attached directly or stacked:
+//
+//	jobPostings.Attach(Dedupe(Debounce(seeker, time.Second, RealClock{}), time.Hour, RealClock{}))
+//
+// All of them are safe for concurrent use. Operators that hold jobs back
+// deliver them from a timer goroutine.
+
+// Debouncer delivers a job only once no other job has arrived for the quiet
+// period, and then only the last one
+type Debouncer struct {
+	target Observer
+	quiet  time.Duration
+	clock  Clock
+
+	mu         sync.Mutex
+	pending    string
+	timer      Timer
+	generation int // tells a stale timer apart from the current one
+}
+
+func Debounce(target Observer, quiet time.Duration, clock Clock) *Debouncer {
+	return &Debouncer{target: target, quiet: quiet, clock: clock}
+}
+
+func (d *Debouncer) Update(jobTitle string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.pending = jobTitle
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	d.generation++
+	generation := d.generation
+	d.timer = d.clock.AfterFunc(d.quiet, func() { d.fire(generation) })
+}
+
+func (d *Debouncer) fire(generation int) {
+	d.mu.Lock()
+	if generation != d.generation || d.timer == nil {
+		// A newer job restarted the quiet period after this timer fired
+		d.mu.Unlock()
+		return
+	}
+	d.timer = nil
+	jobTitle := d.pending
+	d.mu.Unlock()
+
+	d.target.Update(jobTitle)
+}
+
+// Flush delivers the pending job now, if there is one
+func (d *Debouncer) Flush() {
+	d.mu.Lock()
+	if d.timer == nil {
+		d.mu.Unlock()
+		return
+	}
+	d.timer.Stop()
+	d.timer = nil
+	jobTitle := d.pending
+	d.mu.Unlock()
+
+	d.target.Update(jobTitle)
+}
+
+// Throttler delivers at most one job per interval: the first job goes through
+// immediately and the ones following it within the interval are dropped
+type Throttler struct {
+	target   Observer
+	interval time.Duration
+	clock    Clock
+
+	mu      sync.Mutex
+	last    time.Time
+	started bool
+	dropped int
+}
+
+func Throttle(target Observer, interval time.Duration, clock Clock) *Throttler {
+	return &Throttler{target: target, interval: interval, clock: clock}
+}
+
+func (t *Throttler) Update(jobTitle string) {
+	t.mu.Lock()
+	now := t.clock.Now()
+	if t.started && now.Sub(t.last) < t.interval {
+		t.dropped++
+		t.mu.Unlock()
+		return
+	}
+	t.started = true
+	t.last = now
+	t.mu.Unlock()
+
+	t.target.Update(jobTitle)
+}
+
+// Dropped counts jobs suppressed so far
+func (t *Throttler) Dropped() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.dropped
+}
+
+// DigestObserver receives several jobs in one notification
+type DigestObserver interface {
+	UpdateDigest(jobTitles []string)
+}
+
+// Digest adapts a plain Observer to DigestObserver by summarizing the batch
+// into a single Update
+func Digest(target Observer) DigestObserver {
+	return digestAdapter{target}
+}
+
+type digestAdapter struct {
+	target Observer
+}
+
+func (da digestAdapter) UpdateDigest(jobTitles []string) {
+	if len(jobTitles) == 1 {
+		da.target.Update(jobTitles[0])
+		return
+	}
+	da.target.Update(fmt.Sprintf("%d new jobs: %s", len(jobTitles), strings.Join(jobTitles, ", ")))
+}
+
+// UpdateDigest lets a JobSeeker receive batched jobs directly
+func (js *JobSeeker) UpdateDigest(jobTitles []string) {
+	const preview = 3
+	summary := strings.Join(jobTitles, ", ")
+	if len(jobTitles) > preview {
+		summary = fmt.Sprintf("%s and %d more", strings.Join(jobTitles[:preview], ", "), len(jobTitles)-preview)
+	}
+	fmt.Printf("Hi %s! %d new jobs posted: %s\n", js.name, len(jobTitles), summary)
+}
+
+// Batcher collects jobs and delivers them as one digest when maxSize jobs have
+// piled up or maxWait has passed since the first job of the batch, whichever
+// comes first. A maxSize or maxWait of 0 disables that trigger.
+type Batcher struct {
+	target  DigestObserver
+	maxSize int
+	maxWait time.Duration
+	clock   Clock
+
+	mu         sync.Mutex
+	batch      []string
+	timer      Timer
+	generation int // tells a stale timer apart from the current batch's
+}
+
+func Batch(target DigestObserver, maxSize int, maxWait time.Duration, clock Clock) *Batcher {
+	return &Batcher{target: target, maxSize: maxSize, maxWait: maxWait, clock: clock}
+}
+
+func (b *Batcher) Update(jobTitle string) {
+	b.mu.Lock()
+	b.batch = append(b.batch, jobTitle)
+	if len(b.batch) == 1 && b.maxWait > 0 {
+		generation := b.generation
+		b.timer = b.clock.AfterFunc(b.maxWait, func() { b.flush(generation) })
+	}
+	// Taken under the same lock as the append, so concurrent updates can
+	// never grow a batch past maxSize
+	var full []string
+	if b.maxSize > 0 && len(b.batch) >= b.maxSize {
+		full = b.take()
+	}
+	b.mu.Unlock()
+
+	if full != nil {
+		b.target.UpdateDigest(full)
+	}
+}
+
+// Flush delivers whatever is batched right now
+func (b *Batcher) Flush() {
+	b.mu.Lock()
+	generation := b.generation
+	b.mu.Unlock()
+	b.flush(generation)
+}
+
+func (b *Batcher) flush(generation int) {
+	b.mu.Lock()
+	if generation != b.generation {
+		// This batch was already delivered
+		b.mu.Unlock()
+		return
+	}
+	batch := b.take()
+	b.mu.Unlock()
+
+	if len(batch) > 0 {
+		b.target.UpdateDigest(batch)
+	}
+}
+
+// take ends the current batch and returns it; b.mu must be held
+func (b *Batcher) take() []string {
+	b.generation++
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	batch := b.batch
+	b.batch = nil
+	return batch
+}
+
+// Deduplicator drops a job whose title was already delivered within the
+// window. A window of 0 remembers every title forever.
+type Deduplicator struct {
+	target Observer
+	window time.Duration
+	clock  Clock
+
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+func Dedupe(target Observer, window time.Duration, clock Clock) *Deduplicator {
+	return &Deduplicator{target: target, window: window, clock: clock, seen: make(map[string]time.Time)}
+}
+
+func (d *Deduplicator) Update(jobTitle string) {
+	d.mu.Lock()
+	now := d.clock.Now()
+	if seenAt, ok := d.seen[jobTitle]; ok && (d.window == 0 || now.Sub(seenAt) < d.window) {
+		d.mu.Unlock()
+		return
+	}
+	d.seen[jobTitle] = now
+	if d.window > 0 {
+		for title, seenAt := range d.seen {
+			if now.Sub(seenAt) >= d.window {
+				delete(d.seen, title)
+			}
+		}
+	}
+	d.mu.Unlock()
+
+	d.target.Update(jobTitle)
+}
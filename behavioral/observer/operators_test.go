@@ -0,0 +1,259 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+var epoch = time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+
+// delivery is one job an operator passed on, and when
+type delivery struct {
+	jobs []string
+	at   time.Duration // since epoch
+}
+
+// timeline records what reaches the end of an operator chain. It is both an
+// Observer and a DigestObserver.
+type timeline struct {
+	clock Clock
+
+	mu         sync.Mutex
+	deliveries []delivery
+}
+
+func (tl *timeline) Update(jobTitle string) {
+	tl.UpdateDigest([]string{jobTitle})
+}
+
+func (tl *timeline) UpdateDigest(jobTitles []string) {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	tl.deliveries = append(tl.deliveries, delivery{
+		jobs: slices.Clone(jobTitles),
+		at:   tl.clock.Now().Sub(epoch),
+	})
+}
+
+func (tl *timeline) expect(t *testing.T, want ...delivery) {
+	t.Helper()
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	equal := slices.EqualFunc(tl.deliveries, want, func(a, b delivery) bool {
+		return a.at == b.at && slices.Equal(a.jobs, b.jobs)
+	})
+	if !equal {
+		t.Errorf("delivered %v, want %v", tl.deliveries, want)
+	}
+}
+
+func at(d time.Duration, jobs ...string) delivery {
+	return delivery{jobs: jobs, at: d}
+}
+
+func newTimeline() (*FakeClock, *timeline) {
+	clock := NewFakeClock(epoch)
+	return clock, &timeline{clock: clock}
+}
+
+func TestDebounceDeliversLastJobAfterQuietPeriod(t *testing.T) {
+	clock, tl := newTimeline()
+	d := Debounce(tl, time.Second, clock)
+
+	d.Update("a")
+	clock.Advance(500 * time.Millisecond)
+	d.Update("b") // restarts the quiet period
+	clock.Advance(999 * time.Millisecond)
+	tl.expect(t)
+
+	clock.Advance(time.Millisecond)
+	tl.expect(t, at(1500*time.Millisecond, "b"))
+
+	clock.Advance(time.Hour)
+	d.Update("c")
+	clock.Advance(time.Second)
+	tl.expect(t, at(1500*time.Millisecond, "b"), at(time.Hour+2500*time.Millisecond, "c"))
+}
+
+func TestDebounceFlush(t *testing.T) {
+	clock, tl := newTimeline()
+	d := Debounce(tl, time.Second, clock)
+
+	d.Flush() // nothing pending
+	d.Update("a")
+	clock.Advance(100 * time.Millisecond)
+	d.Flush()
+	clock.Advance(time.Second) // the stopped timer must not deliver again
+	d.Flush()
+	tl.expect(t, at(100*time.Millisecond, "a"))
+}
+
+func TestThrottleDropsWithinInterval(t *testing.T) {
+	clock, tl := newTimeline()
+	th := Throttle(tl, time.Second, clock)
+
+	th.Update("a")
+	clock.Advance(500 * time.Millisecond)
+	th.Update("b")
+	clock.Advance(499 * time.Millisecond)
+	th.Update("c")
+	clock.Advance(time.Millisecond)
+	th.Update("d") // a full interval after "a"
+	th.Update("e")
+	clock.Advance(2 * time.Second)
+	th.Update("f")
+
+	tl.expect(t, at(0, "a"), at(time.Second, "d"), at(3*time.Second, "f"))
+	if th.Dropped() != 3 {
+		t.Errorf("Dropped() = %d, want 3", th.Dropped())
+	}
+}
+
+func TestBatchFlushesOnSize(t *testing.T) {
+	clock, tl := newTimeline()
+	b := Batch(tl, 3, 0, clock)
+
+	for _, job := range []string{"a", "b", "c", "d"} {
+		b.Update(job)
+		clock.Advance(time.Second)
+	}
+	clock.Advance(time.Hour) // no maxWait, so "d" waits for more jobs
+	tl.expect(t, at(2*time.Second, "a", "b", "c"))
+
+	b.Flush()
+	tl.expect(t, at(2*time.Second, "a", "b", "c"), at(time.Hour+4*time.Second, "d"))
+}
+
+func TestBatchFlushesOnMaxWait(t *testing.T) {
+	clock, tl := newTimeline()
+	b := Batch(tl, 10, time.Minute, clock)
+
+	b.Update("a")
+	clock.Advance(30 * time.Second)
+	b.Update("b")
+	clock.Advance(30*time.Second - time.Nanosecond)
+	tl.expect(t)
+
+	// maxWait counts from the first job of the batch, not the last
+	clock.Advance(time.Nanosecond)
+	tl.expect(t, at(time.Minute, "a", "b"))
+
+	// The next batch gets its own timer
+	b.Update("c")
+	clock.Advance(time.Minute)
+	tl.expect(t, at(time.Minute, "a", "b"), at(2*time.Minute, "c"))
+}
+
+func TestBatchSizeFlushCancelsTimer(t *testing.T) {
+	clock, tl := newTimeline()
+	b := Batch(tl, 2, time.Minute, clock)
+
+	b.Update("a")
+	b.Update("b")
+	clock.Advance(30 * time.Second)
+	b.Update("c")
+	clock.Advance(59 * time.Second) // the first batch's timer must not flush "c" early
+	tl.expect(t, at(0, "a", "b"))
+
+	clock.Advance(time.Second)
+	tl.expect(t, at(0, "a", "b"), at(90*time.Second, "c"))
+}
+
+// Run with go test -race: concurrent updates must never grow a batch past
+// maxSize or lose a job
+func TestBatchConcurrentUpdates(t *testing.T) {
+	const (
+		maxSize = 5
+		writers = 8
+		jobs    = 200
+	)
+	clock, tl := newTimeline()
+	b := Batch(tl, maxSize, 0, clock)
+
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < jobs; i++ {
+				b.Update(fmt.Sprintf("%d-%d", w, i))
+			}
+		}(w)
+	}
+	wg.Wait()
+	b.Flush()
+
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	seen := make(map[string]bool)
+	for i, d := range tl.deliveries {
+		// Only the final Flush may deliver a short batch
+		if len(d.jobs) > maxSize || (len(d.jobs) != maxSize && i != len(tl.deliveries)-1) {
+			t.Errorf("batch %d has %d jobs, want %d", i, len(d.jobs), maxSize)
+		}
+		for _, job := range d.jobs {
+			if seen[job] {
+				t.Errorf("%s delivered twice", job)
+			}
+			seen[job] = true
+		}
+	}
+	if len(seen) != writers*jobs {
+		t.Errorf("delivered %d jobs, want %d", len(seen), writers*jobs)
+	}
+}
+
+func TestDigestSummarizesBatch(t *testing.T) {
+	clock, tl := newTimeline()
+	b := Batch(Digest(tl), 0, time.Second, clock)
+
+	b.Update("solo")
+	clock.Advance(time.Second)
+	b.Update("a")
+	b.Update("b")
+	b.Update("c")
+	clock.Advance(time.Second)
+
+	tl.expect(t, at(time.Second, "solo"), at(2*time.Second, "3 new jobs: a, b, c"))
+}
+
+func TestDedupeForever(t *testing.T) {
+	clock, tl := newTimeline()
+	d := Dedupe(tl, 0, clock)
+
+	d.Update("a")
+	d.Update("b")
+	clock.Advance(24 * time.Hour)
+	d.Update("a")
+	d.Update("c")
+
+	tl.expect(t, at(0, "a"), at(0, "b"), at(24*time.Hour, "c"))
+}
+
+func TestDedupeWindow(t *testing.T) {
+	clock, tl := newTimeline()
+	d := Dedupe(tl, time.Minute, clock)
+
+	d.Update("a")
+	clock.Advance(59 * time.Second)
+	d.Update("a") // within the window: dropped, and does not extend it
+	clock.Advance(time.Second)
+	d.Update("a")
+
+	tl.expect(t, at(0, "a"), at(time.Minute, "a"))
+}
+
+func TestStackedOperators(t *testing.T) {
+	clock, tl := newTimeline()
+	chain := Dedupe(Batch(Digest(tl), 0, time.Minute, clock), 0, clock)
+
+	for i := 0; i < 500; i++ {
+		chain.Update([]string{"Role 0", "Role 1", "Role 2"}[i%3])
+	}
+	clock.Advance(time.Minute)
+
+	tl.expect(t, at(time.Minute, "3 new jobs: Role 0, Role 1, Role 2"))
+}
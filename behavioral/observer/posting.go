@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// JobPosting is the event published on a job board bus
+type JobPosting struct {
+	Title    string
+	Company  string
+	Location string
+	Salary   int // yearly, in USD
+	Tags     []string
+}
+
+// Topic routes a posting as "jobs.<company>.<location>", e.g.
+// "jobs.acme.remote", so subscribers can use patterns like "jobs.*.berlin".
+func (p JobPosting) Topic() string {
+	return "jobs." + slug(p.Company) + "." + slug(p.Location)
+}
+
+func (p JobPosting) String() string {
+	return fmt.Sprintf("%s at %s (%s, $%d) %v", p.Title, p.Company, p.Location, p.Salary, p.Tags)
+}
+
+func slug(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return "unknown"
+	}
+	return strings.Join(strings.FieldsFunc(s, func(r rune) bool {
+		return r == ' ' || r == '.' || r == '-' || r == '_'
+	}), "-")
+}
+
+// IsRemote matches postings located "Remote" or tagged "remote"
+func IsRemote(p JobPosting) bool {
+	return strings.EqualFold(p.Location, "remote") || slices.ContainsFunc(p.Tags, func(tag string) bool {
+		return strings.EqualFold(tag, "remote")
+	})
+}
+
+// HasTag matches postings carrying tag, ignoring case
+func HasTag(tag string) Predicate[JobPosting] {
+	return func(p JobPosting) bool {
+		return slices.ContainsFunc(p.Tags, func(t string) bool {
+			return strings.EqualFold(t, tag)
+		})
+	}
+}
+
+// MinSalary matches postings paying at least amount
+func MinSalary(amount int) Predicate[JobPosting] {
+	return func(p JobPosting) bool {
+		return p.Salary >= amount
+	}
+}
+
+// Receive lets a JobSeeker subscribe to a JobPosting bus
+func (js *JobSeeker) Receive(posting JobPosting) {
+	fmt.Printf("Hi %s! New job posted: %s\n", js.name, posting)
+}
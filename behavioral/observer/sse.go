@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// sseHistory is how many recent jobs an SSEHandler keeps for clients that
+// reconnect with a Last-Event-ID
+const sseHistory = 256
+
+// SSEHandler streams every job posted on a JobPostings subject to browsers as
+// Server-Sent Events. Each connected client has its own bounded queue and
+// goroutine, so a slow browser only loses its own oldest events. Jobs get
+// increasing ids, and a client that reconnects with a Last-Event-ID header
+// first receives the retained jobs it missed.
+type SSEHandler struct {
+	bufferSize int
+	sub        *Subscription
+
+	mu      sync.Mutex
+	lastID  uint64
+	history []sseEvent // oldest first, at most sseHistory
+	clients subscribers[*mailbox[sseEvent]]
+}
+
+type sseEvent struct {
+	id   uint64
+	data []byte
+}
+
+func NewSSEHandler(subject *JobPostings, bufferSize int) *SSEHandler {
+	h := &SSEHandler{bufferSize: bufferSize}
+	h.sub = subject.Attach(ObserverFunc(h.record))
+	return h
+}
+
+// Close detaches the handler from its subject. Connected clients stay open
+// until their requests end but receive no more jobs.
+func (h *SSEHandler) Close() {
+	h.sub.Unsubscribe()
+}
+
+// Clients returns how many browsers are connected
+func (h *SSEHandler) Clients() int {
+	return len(h.clients.snapshot())
+}
+
+func (h *SSEHandler) record(jobTitle string) {
+	data, err := json.Marshal(PushPayload{JobTitle: jobTitle, Time: time.Now()})
+	if err != nil {
+		return
+	}
+
+	// Held while pushing so a connecting client sees either the history
+	// entry or the live push, never both or neither
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lastID++
+	event := sseEvent{id: h.lastID, data: data}
+	h.history = append(h.history, event)
+	if len(h.history) > sseHistory {
+		h.history = h.history[len(h.history)-sseHistory:]
+	}
+	for _, client := range h.clients.snapshot() {
+		client.value.push(event)
+	}
+}
+
+func (h *SSEHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	// A missing or malformed header means a fresh connection
+	lastSeen, _ := strconv.ParseUint(r.Header.Get("Last-Event-ID"), 10, 64)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	h.mu.Lock()
+	var missed []sseEvent
+	if lastSeen > 0 {
+		for _, event := range h.history {
+			if event.id > lastSeen {
+				missed = append(missed, event)
+			}
+		}
+	}
+	box := newMailbox(func(event sseEvent) {
+		fmt.Fprintf(w, "id: %d\nevent: job\ndata: %s\n\n", event.id, event.data)
+		flusher.Flush()
+	}, AsyncOptions{
+		BufferSize: max(h.bufferSize, len(missed)),
+		Overflow:   DropOldest,
+	})
+	for _, event := range missed {
+		box.push(event)
+	}
+	sub := h.clients.addWithCleanup(box, box.close)
+	h.mu.Unlock()
+
+	// The response must not be written to once ServeHTTP returns, so wait
+	// for the client's goroutine to finish
+	<-r.Context().Done()
+	sub.Unsubscribe()
+	<-box.stopped
+}
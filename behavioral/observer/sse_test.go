@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sseMessage is one parsed Server-Sent Event
+type sseMessage struct {
+	id    uint64
+	event string
+	job   string
+}
+
+// sseStream is a connected test client
+type sseStream struct {
+	cancel   context.CancelFunc
+	response *http.Response
+	lines    *bufio.Scanner
+}
+
+func connectSSE(t *testing.T, url, lastEventID string) *sseStream {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	request, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if lastEventID != "" {
+		request.Header.Set("Last-Event-ID", lastEventID)
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		cancel()
+		t.Fatal(err)
+	}
+	if got := response.Header.Get("Content-Type"); got != "text/event-stream" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	return &sseStream{cancel: cancel, response: response, lines: bufio.NewScanner(response.Body)}
+}
+
+func (s *sseStream) close() {
+	s.cancel()
+	s.response.Body.Close()
+}
+
+func (s *sseStream) next(t *testing.T) sseMessage {
+	t.Helper()
+	var msg sseMessage
+	for s.lines.Scan() {
+		line := s.lines.Text()
+		switch {
+		case line == "":
+			return msg
+		case strings.HasPrefix(line, "id: "):
+			msg.id, _ = strconv.ParseUint(strings.TrimPrefix(line, "id: "), 10, 64)
+		case strings.HasPrefix(line, "event: "):
+			msg.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			var payload PushPayload
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &payload); err != nil {
+				t.Fatalf("bad data line %q: %v", line, err)
+			}
+			msg.job = payload.JobTitle
+		}
+	}
+	t.Fatalf("stream ended: %v", s.lines.Err())
+	return msg
+}
+
+func waitForClients(t *testing.T, h *SSEHandler, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for h.Clients() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d clients connected, want %d", h.Clients(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSSEStreamsJobs(t *testing.T) {
+	jobPostings := NewJobPostings()
+	handler := NewSSEHandler(jobPostings, 16)
+	defer handler.Close()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	first := connectSSE(t, server.URL, "")
+	defer first.close()
+	second := connectSSE(t, server.URL, "")
+	defer second.close()
+	waitForClients(t, handler, 2)
+
+	jobPostings.AddJob("Frontend Engineer")
+	jobPostings.AddJob("Backend Engineer")
+
+	for _, client := range []*sseStream{first, second} {
+		for i, want := range []string{"Frontend Engineer", "Backend Engineer"} {
+			msg := client.next(t)
+			if msg.event != "job" || msg.job != want || msg.id != uint64(i+1) {
+				t.Errorf("got %+v, want job %q with id %d", msg, want, i+1)
+			}
+		}
+	}
+}
+
+func TestSSEReconnectReplaysMissedJobs(t *testing.T) {
+	jobPostings := NewJobPostings()
+	handler := NewSSEHandler(jobPostings, 16)
+	defer handler.Close()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	client := connectSSE(t, server.URL, "")
+	waitForClients(t, handler, 1)
+	jobPostings.AddJob("Job 1")
+	jobPostings.AddJob("Job 2")
+	client.next(t)
+	lastSeen := client.next(t).id
+	client.close()
+	waitForClients(t, handler, 0)
+
+	// Posted while the browser was disconnected
+	jobPostings.AddJob("Job 3")
+	jobPostings.AddJob("Job 4")
+
+	client = connectSSE(t, server.URL, strconv.FormatUint(lastSeen, 10))
+	defer client.close()
+	waitForClients(t, handler, 1)
+	jobPostings.AddJob("Job 5")
+
+	for _, want := range []string{"Job 3", "Job 4", "Job 5"} {
+		if msg := client.next(t); msg.job != want {
+			t.Errorf("got %+v, want %q", msg, want)
+		}
+	}
+
+	fresh := connectSSE(t, server.URL, "")
+	defer fresh.close()
+	waitForClients(t, handler, 2)
+	jobPostings.AddJob("Job 6")
+	if msg := fresh.next(t); msg.job != "Job 6" || msg.id != 6 {
+		t.Errorf("client without Last-Event-ID got %+v, want only Job 6", msg)
+	}
+}
+
+func TestSSEReplayIsBoundedByHistory(t *testing.T) {
+	jobPostings := NewJobPostings()
+	handler := NewSSEHandler(jobPostings, 4)
+	defer handler.Close()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	for i := 1; i <= sseHistory+10; i++ {
+		jobPostings.AddJob("Job " + strconv.Itoa(i))
+	}
+	client := connectSSE(t, server.URL, "1")
+	defer client.close()
+
+	// Jobs 2 to 11 were evicted; replay starts at the oldest retained one
+	if msg := client.next(t); msg.id != 11 {
+		t.Errorf("first replayed id = %d, want 11", msg.id)
+	}
+}
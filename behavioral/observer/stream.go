@@ -0,0 +1,334 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Emitter is handed to an Observable's producer. It delivers values to one
+// subscriber, serializes concurrent calls, and ignores everything after the
+// first Error or Complete or once the subscriber has unsubscribed.
+type Emitter[T any] struct {
+	onNext     func(T)
+	onError    func(error)
+	onComplete func()
+	sub        *Subscription
+
+	mu      sync.Mutex // serializes signals
+	done    bool
+	stopped atomic.Bool // set on unsubscribe
+
+	teardownMu sync.Mutex
+	teardown   func()
+	tornDown   bool
+}
+
+func (e *Emitter[T]) Next(value T) {
+	if e.stopped.Load() {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.done || e.stopped.Load() {
+		return
+	}
+	e.onNext(value)
+}
+
+// Error terminates the stream with err
+func (e *Emitter[T]) Error(err error) {
+	if e.terminate(func() { e.onError(err) }) {
+		e.sub.Unsubscribe()
+	}
+}
+
+// Complete terminates the stream successfully
+func (e *Emitter[T]) Complete() {
+	if e.terminate(e.onComplete) {
+		e.sub.Unsubscribe()
+	}
+}
+
+func (e *Emitter[T]) terminate(signal func()) bool {
+	if e.stopped.Load() {
+		return false
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.done || e.stopped.Load() {
+		return false
+	}
+	e.done = true
+	signal()
+	return true
+}
+
+// Done reports whether the subscriber no longer wants values, so producers
+// can stop early
+func (e *Emitter[T]) Done() bool {
+	if e.stopped.Load() {
+		return true
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.done
+}
+
+func (e *Emitter[T]) setTeardown(teardown func()) {
+	if teardown == nil {
+		return
+	}
+	e.teardownMu.Lock()
+	if e.tornDown {
+		e.teardownMu.Unlock()
+		teardown() // the stream ended while the producer was still starting
+		return
+	}
+	e.teardown = teardown
+	e.teardownMu.Unlock()
+}
+
+func (e *Emitter[T]) stop() {
+	e.stopped.Store(true)
+	e.teardownMu.Lock()
+	e.tornDown = true
+	teardown := e.teardown
+	e.teardown = nil
+	e.teardownMu.Unlock()
+
+	if teardown != nil {
+		teardown()
+	}
+}
+
+// Observable is a stream of values followed by at most one Error or Complete.
+// Cold observables (FromSlice, Create) run their producer again for every
+// subscriber; hot ones (StreamSubject, JobPostings.Observe) share one live source
+// and subscribers only see what happens after they subscribe.
+type Observable[T any] struct {
+	produce func(e *Emitter[T]) (teardown func())
+}
+
+// Create builds an observable from a producer. The producer runs on Subscribe
+// and returns a teardown that is called once the subscriber unsubscribes or
+// the stream terminates; it may return nil.
+func Create[T any](produce func(e *Emitter[T]) (teardown func())) Observable[T] {
+	return Observable[T]{produce: produce}
+}
+
+// Subscribe starts receiving values. Any callback may be nil.
+func (o Observable[T]) Subscribe(onNext func(T), onError func(error), onComplete func()) *Subscription {
+	if onNext == nil {
+		onNext = func(T) {}
+	}
+	if onError == nil {
+		onError = func(error) {}
+	}
+	if onComplete == nil {
+		onComplete = func() {}
+	}
+
+	e := newEmitter(onNext, onError, onComplete)
+	o.run(e)
+	return e.sub
+}
+
+func newEmitter[T any](onNext func(T), onError func(error), onComplete func()) *Emitter[T] {
+	e := &Emitter[T]{onNext: onNext, onError: onError, onComplete: onComplete}
+	e.sub = newSubscription(func(*Subscription) {}, e.stop)
+	return e
+}
+
+// run starts the producer for e. Operators that may have to unsubscribe
+// while a synchronous producer is still running create e first.
+func (o Observable[T]) run(e *Emitter[T]) {
+	e.setTeardown(o.produce(e))
+}
+
+// FromSlice is a cold observable emitting values and then completing
+func FromSlice[T any](values ...T) Observable[T] {
+	return Create(func(e *Emitter[T]) func() {
+		for _, value := range values {
+			if e.Done() {
+				return nil
+			}
+			e.Next(value)
+		}
+		e.Complete()
+		return nil
+	})
+}
+
+// Map transforms every value
+func Map[T, R any](source Observable[T], transform func(T) R) Observable[R] {
+	return Create(func(e *Emitter[R]) func() {
+		return source.Subscribe(func(value T) {
+			e.Next(transform(value))
+		}, e.Error, e.Complete).Unsubscribe
+	})
+}
+
+// Filter only passes values for which keep returns true
+func Filter[T any](source Observable[T], keep func(T) bool) Observable[T] {
+	return Create(func(e *Emitter[T]) func() {
+		return source.Subscribe(func(value T) {
+			if keep(value) {
+				e.Next(value)
+			}
+		}, e.Error, e.Complete).Unsubscribe
+	})
+}
+
+// Scan emits the running accumulation of values, starting from seed
+func Scan[T, R any](source Observable[T], seed R, accumulate func(R, T) R) Observable[R] {
+	return Create(func(e *Emitter[R]) func() {
+		acc := seed
+		return source.Subscribe(func(value T) {
+			acc = accumulate(acc, value)
+			e.Next(acc)
+		}, e.Error, e.Complete).Unsubscribe
+	})
+}
+
+// DistinctUntilChanged drops values equal to the one just before them
+func DistinctUntilChanged[T comparable](source Observable[T]) Observable[T] {
+	return Create(func(e *Emitter[T]) func() {
+		var last T
+		seen := false
+		return source.Subscribe(func(value T) {
+			if seen && value == last {
+				return
+			}
+			last, seen = value, true
+			e.Next(value)
+		}, e.Error, e.Complete).Unsubscribe
+	})
+}
+
+// Take emits the first n values and then completes, unsubscribing from source
+func Take[T any](source Observable[T], n int) Observable[T] {
+	return Create(func(e *Emitter[T]) func() {
+		if n <= 0 {
+			e.Complete()
+			return nil
+		}
+		taken := 0
+		var upstream *Emitter[T]
+		upstream = newEmitter(func(value T) {
+			taken++
+			e.Next(value)
+			if taken == n {
+				e.Complete()
+				// Also stops a source still producing inside run
+				upstream.sub.Unsubscribe()
+			}
+		}, e.Error, e.Complete)
+		source.run(upstream)
+		return upstream.sub.Unsubscribe
+	})
+}
+
+// Merge interleaves several sources. It completes once all of them have
+// completed and fails as soon as one of them fails.
+func Merge[T any](sources ...Observable[T]) Observable[T] {
+	return Create(func(e *Emitter[T]) func() {
+		if len(sources) == 0 {
+			e.Complete()
+			return nil
+		}
+		var remaining atomic.Int64
+		remaining.Store(int64(len(sources)))
+		subs := make([]*Subscription, 0, len(sources))
+		for _, source := range sources {
+			subs = append(subs, source.Subscribe(e.Next, e.Error, func() {
+				if remaining.Add(-1) == 0 {
+					e.Complete()
+				}
+			}))
+		}
+		return func() {
+			for _, sub := range subs {
+				sub.Unsubscribe()
+			}
+		}
+	})
+}
+
+// StreamSubject is a hot observable that multicasts whatever is pushed into it.
+// Subscribers arriving after Error or Complete get the terminal signal at once.
+type StreamSubject[T any] struct {
+	emitters subscribers[*Emitter[T]]
+
+	mu         sync.Mutex
+	terminated bool
+	err        error
+}
+
+func NewStreamSubject[T any]() *StreamSubject[T] {
+	return &StreamSubject[T]{}
+}
+
+func (s *StreamSubject[T]) Next(value T) {
+	for _, entry := range s.emitters.snapshot() {
+		entry.value.Next(value)
+	}
+}
+
+func (s *StreamSubject[T]) Error(err error) {
+	for _, entry := range s.terminate(err) {
+		entry.value.Error(err)
+	}
+}
+
+func (s *StreamSubject[T]) Complete() {
+	for _, entry := range s.terminate(nil) {
+		entry.value.Complete()
+	}
+}
+
+// terminate records the terminal signal and returns who still has to get it
+func (s *StreamSubject[T]) terminate(err error) []*subscriber[*Emitter[T]] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.terminated {
+		return nil
+	}
+	s.terminated, s.err = true, err
+	return s.emitters.snapshot()
+}
+
+// Observable exposes the subject for subscribing only
+func (s *StreamSubject[T]) Observable() Observable[T] {
+	return Create(func(e *Emitter[T]) func() {
+		s.mu.Lock()
+		if !s.terminated {
+			// Registered under the lock so a concurrent terminate cannot miss it
+			defer s.mu.Unlock()
+			return s.emitters.add(e).Unsubscribe
+		}
+		err := s.err
+		s.mu.Unlock()
+
+		if err != nil {
+			e.Error(err)
+		} else {
+			e.Complete()
+		}
+		return nil
+	})
+}
+
+// ObserverFunc adapts a plain function to the Observer interface
+type ObserverFunc func(jobTitle string)
+
+func (f ObserverFunc) Update(jobTitle string) {
+	f(jobTitle)
+}
+
+// Observe exposes the job postings as a hot observable: every subscriber
+// sees the jobs posted while it is subscribed
+func (jp *JobPostings) Observe() Observable[string] {
+	return Create(func(e *Emitter[string]) func() {
+		return jp.Attach(ObserverFunc(e.Next)).Unsubscribe
+	})
+}
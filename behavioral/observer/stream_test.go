@@ -0,0 +1,327 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// collector records every signal one subscriber receives
+type collector[T any] struct {
+	mu        sync.Mutex
+	values    []T
+	errs      []error
+	completed int
+}
+
+func collect[T any](source Observable[T]) (*collector[T], *Subscription) {
+	c := &collector[T]{}
+	sub := source.Subscribe(func(value T) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.values = append(c.values, value)
+	}, func(err error) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.errs = append(c.errs, err)
+	}, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.completed++
+	})
+	return c, sub
+}
+
+// expect checks the values received and how the stream ended: with wantErr,
+// with a completion, or not yet when neither is asked for
+func (c *collector[T]) expect(t *testing.T, values []T, wantErr error, completed bool, equal func(a, b T) bool) {
+	t.Helper()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !slices.EqualFunc(c.values, values, equal) {
+		t.Errorf("received %v, want %v", c.values, values)
+	}
+	switch {
+	case wantErr != nil && (len(c.errs) != 1 || !errors.Is(c.errs[0], wantErr)):
+		t.Errorf("errors %v, want exactly %v", c.errs, wantErr)
+	case wantErr == nil && len(c.errs) > 0:
+		t.Errorf("unexpected errors %v", c.errs)
+	}
+	if want := map[bool]int{true: 1}[completed]; c.completed != want {
+		t.Errorf("completed %d times, want %d", c.completed, want)
+	}
+}
+
+func equal[T comparable](a, b T) bool { return a == b }
+
+func (s *StreamSubject[T]) subscribers() int {
+	return len(s.emitters.snapshot())
+}
+
+func TestOperators(t *testing.T) {
+	numbers := FromSlice(1, 2, 2, 3, 3, 3, 1, 4)
+	for _, tc := range []struct {
+		name   string
+		stream Observable[int]
+		want   []int
+	}{
+		{"FromSlice", numbers, []int{1, 2, 2, 3, 3, 3, 1, 4}},
+		{"Map", Map(numbers, func(n int) int { return n * 10 }), []int{10, 20, 20, 30, 30, 30, 10, 40}},
+		{"Filter", Filter(numbers, func(n int) bool { return n%2 == 1 }), []int{1, 3, 3, 3, 1}},
+		{"Scan", Scan(numbers, 100, func(acc, n int) int { return acc + n }), []int{101, 103, 105, 108, 111, 114, 115, 119}},
+		{"DistinctUntilChanged", DistinctUntilChanged(numbers), []int{1, 2, 3, 1, 4}},
+		{"Take", Take(numbers, 3), []int{1, 2, 2}},
+		{"Take more than there is", Take(numbers, 20), []int{1, 2, 2, 3, 3, 3, 1, 4}},
+		{"Take none", Take(numbers, 0), nil},
+		{"chained", Take(DistinctUntilChanged(Filter(numbers, func(n int) bool { return n > 1 })), 2), []int{2, 3}},
+		{"Merge of cold sources", Merge(FromSlice(1, 2), FromSlice(3)), []int{1, 2, 3}},
+		{"Merge of nothing", Merge[int](), nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c, _ := collect(tc.stream)
+			c.expect(t, tc.want, nil, true, equal[int])
+		})
+	}
+
+	lengths, _ := collect(Map(FromSlice("go", "rust"), func(s string) int { return len(s) }))
+	lengths.expect(t, []int{2, 4}, nil, true, equal[int])
+}
+
+func TestOperatorsPassErrorsOn(t *testing.T) {
+	errBroken := errors.New("broken")
+	failing := Create(func(e *Emitter[int]) func() {
+		e.Next(1)
+		e.Error(errBroken)
+		return nil
+	})
+	for name, stream := range map[string]Observable[int]{
+		"Map":                  Map(failing, func(n int) int { return n }),
+		"Filter":               Filter(failing, func(int) bool { return true }),
+		"Scan":                 Scan(failing, 0, func(acc, n int) int { return acc + n }),
+		"DistinctUntilChanged": DistinctUntilChanged(failing),
+		"Take":                 Take(failing, 5),
+	} {
+		t.Run(name, func(t *testing.T) {
+			c, _ := collect(stream)
+			c.expect(t, []int{1}, errBroken, false, equal[int])
+		})
+	}
+}
+
+func TestTakeUnsubscribesUpstream(t *testing.T) {
+	subject := NewStreamSubject[int]()
+	c, _ := collect(Take(subject.Observable(), 2))
+	if subject.subscribers() != 1 {
+		t.Fatalf("%d subscribers to the subject, want 1", subject.subscribers())
+	}
+
+	subject.Next(1)
+	subject.Next(2)
+	subject.Next(3)
+	c.expect(t, []int{1, 2}, nil, true, equal[int])
+	if subject.subscribers() != 0 {
+		t.Errorf("Take left %d subscribers on the subject after completing", subject.subscribers())
+	}
+
+	// A cold producer is torn down once, and stops early
+	produced, teardowns := 0, 0
+	counting := Create(func(e *Emitter[int]) func() {
+		for i := 0; i < 100 && !e.Done(); i++ {
+			produced++
+			e.Next(i)
+		}
+		e.Complete()
+		return func() { teardowns++ }
+	})
+	c, _ = collect(Take(counting, 3))
+	c.expect(t, []int{0, 1, 2}, nil, true, equal[int])
+	if produced != 3 {
+		t.Errorf("the producer made %d values for Take(3)", produced)
+	}
+	if teardowns != 1 {
+		t.Errorf("the producer was torn down %d times, want 1", teardowns)
+	}
+}
+
+func TestMergeCompletesAfterAllSources(t *testing.T) {
+	a, b := NewStreamSubject[string](), NewStreamSubject[string]()
+	c, _ := collect(Merge(a.Observable(), b.Observable()))
+
+	a.Next("a1")
+	b.Next("b1")
+	a.Complete()
+	c.expect(t, []string{"a1", "b1"}, nil, false, equal[string])
+
+	b.Next("b2")
+	b.Complete()
+	c.expect(t, []string{"a1", "b1", "b2"}, nil, true, equal[string])
+}
+
+func TestMergeFailsOnFirstError(t *testing.T) {
+	errFirst, errSecond := errors.New("first"), errors.New("second")
+	a, b := NewStreamSubject[string](), NewStreamSubject[string]()
+	c, _ := collect(Merge(a.Observable(), b.Observable()))
+
+	a.Next("a1")
+	a.Error(errFirst)
+	if a.subscribers() != 0 || b.subscribers() != 0 {
+		t.Errorf("subscribers left after the error: %d and %d", a.subscribers(), b.subscribers())
+	}
+	b.Next("b1")
+	b.Error(errSecond)
+	b.Complete()
+	c.expect(t, []string{"a1"}, errFirst, false, equal[string])
+}
+
+func TestMergeUnsubscribesEverySource(t *testing.T) {
+	a, b := NewStreamSubject[int](), NewStreamSubject[int]()
+	c, sub := collect(Merge(a.Observable(), b.Observable()))
+	a.Next(1)
+	sub.Unsubscribe()
+	a.Next(2)
+	b.Next(3)
+	c.expect(t, []int{1}, nil, false, equal[int])
+	if a.subscribers() != 0 || b.subscribers() != 0 {
+		t.Errorf("subscribers left after unsubscribing: %d and %d", a.subscribers(), b.subscribers())
+	}
+}
+
+func TestColdObservableReplaysForEachSubscriber(t *testing.T) {
+	runs := 0
+	cold := Create(func(e *Emitter[string]) func() {
+		runs++
+		e.Next("first")
+		e.Next("second")
+		e.Complete()
+		return nil
+	})
+	for i := 0; i < 2; i++ {
+		c, _ := collect(cold)
+		c.expect(t, []string{"first", "second"}, nil, true, equal[string])
+	}
+	if runs != 2 {
+		t.Errorf("the producer ran %d times for two subscribers, want 2", runs)
+	}
+
+	words := FromSlice("a", "b")
+	first, _ := collect(words)
+	second, _ := collect(words)
+	first.expect(t, []string{"a", "b"}, nil, true, equal[string])
+	second.expect(t, []string{"a", "b"}, nil, true, equal[string])
+}
+
+func TestHotSubjectSharesOneSource(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		err  error
+	}{
+		{"complete", nil},
+		{"error", errors.New("feed closed")},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			subject := NewStreamSubject[int]()
+			early, _ := collect(subject.Observable())
+			subject.Next(1)
+			late, _ := collect(subject.Observable())
+			subject.Next(2)
+			if tc.err != nil {
+				subject.Error(tc.err)
+			} else {
+				subject.Complete()
+			}
+			// Subscribing after the end only gets the terminal signal
+			after, _ := collect(subject.Observable())
+			subject.Next(3)
+			subject.Complete()
+			subject.Error(errors.New("again"))
+
+			completed := tc.err == nil
+			early.expect(t, []int{1, 2}, tc.err, completed, equal[int])
+			late.expect(t, []int{2}, tc.err, completed, equal[int])
+			after.expect(t, nil, tc.err, completed, equal[int])
+		})
+	}
+}
+
+func TestNothingIsEmittedAfterTheEnd(t *testing.T) {
+	errFirst := errors.New("first")
+	for _, tc := range []struct {
+		name      string
+		produce   func(e *Emitter[int])
+		want      []int
+		err       error
+		completed bool
+	}{
+		{"after Complete", func(e *Emitter[int]) {
+			e.Next(1)
+			e.Complete()
+			e.Next(2)
+			e.Error(errFirst)
+			e.Complete()
+		}, []int{1}, nil, true},
+		{"after Error", func(e *Emitter[int]) {
+			e.Next(1)
+			e.Error(errFirst)
+			e.Next(2)
+			e.Error(errors.New("second"))
+			e.Complete()
+		}, []int{1}, errFirst, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			done := false
+			teardowns := 0
+			c, sub := collect(Create(func(e *Emitter[int]) func() {
+				tc.produce(e)
+				done = e.Done()
+				return func() { teardowns++ }
+			}))
+			c.expect(t, tc.want, tc.err, tc.completed, equal[int])
+			if !done {
+				t.Error("Done() = false after the end")
+			}
+			if sub.Active() {
+				t.Error("the subscription is still active after the end")
+			}
+			if teardowns != 1 {
+				t.Errorf("torn down %d times, want 1", teardowns)
+			}
+		})
+	}
+}
+
+func TestUnsubscribeStopsEmitter(t *testing.T) {
+	var emitter *Emitter[int]
+	teardowns := 0
+	c, sub := collect(Create(func(e *Emitter[int]) func() {
+		emitter = e
+		return func() { teardowns++ }
+	}))
+	emitter.Next(1)
+	sub.Unsubscribe()
+	sub.Unsubscribe()
+	emitter.Next(2)
+	emitter.Complete()
+
+	c.expect(t, []int{1}, nil, false, equal[int])
+	if !emitter.Done() {
+		t.Error("Done() = false after unsubscribing")
+	}
+	if teardowns != 1 {
+		t.Errorf("torn down %d times, want 1", teardowns)
+	}
+}
+
+func TestJobPostingsObserve(t *testing.T) {
+	jobPostings := NewJobPostings()
+	c, sub := collect(Map(jobPostings.Observe(), strings.ToUpper))
+	jobPostings.AddJob("Go Developer")
+	sub.Unsubscribe()
+	jobPostings.AddJob("Rust Developer")
+
+	c.expect(t, []string{"GO DEVELOPER"}, nil, false, equal[string])
+	if jobPostings.Count() != 0 {
+		t.Errorf("Count() = %d after unsubscribing", jobPostings.Count())
+	}
+}
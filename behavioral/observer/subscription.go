@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// Subscription is the handle returned when attaching to a subject. It
+// identifies one attachment, so two observers that look alike are never
+// confused with each other.
+type Subscription struct {
+	remove  func(*Subscription)
+	cleanup func() // optional, runs once after removal
+
+	mu        sync.Mutex
+	cancelled bool
+	done      chan struct{}
+	stopWatch func() bool // stops the context watcher set up by watch
+}
+
+func newSubscription(remove func(*Subscription), cleanup func()) *Subscription {
+	return &Subscription{remove: remove, cleanup: cleanup, done: make(chan struct{})}
+}
+
+// watch cancels the subscription once ctx is done
+func (s *Subscription) watch(ctx context.Context) {
+	stop := context.AfterFunc(ctx, s.Unsubscribe)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cancelled {
+		stop()
+		return
+	}
+	s.stopWatch = stop
+}
+
+// Unsubscribe removes the observer from the subject. Calling it more than once
+// has no further effect.
+func (s *Subscription) Unsubscribe() {
+	s.mu.Lock()
+	if s.cancelled {
+		s.mu.Unlock()
+		return
+	}
+	s.cancelled = true
+	stopWatch := s.stopWatch
+	s.mu.Unlock()
+
+	if stopWatch != nil {
+		stopWatch()
+	}
+	s.remove(s)
+	if s.cleanup != nil {
+		s.cleanup()
+	}
+	close(s.done)
+}
+
+// Done returns a channel that is closed once the subscription has been
+// cancelled and removed from the subject.
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+// Active reports whether the subscription has not been cancelled yet
+func (s *Subscription) Active() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.cancelled
+}
+
+// subscriber pairs whatever a subject delivers to with its handle
+type subscriber[T any] struct {
+	value T
+	sub   *Subscription
+}
+
+// subscribers is the copy-on-write list shared by every subject in this
+// package. Writers copy the slice under a mutex and publish the new copy;
+// readers take a snapshot and iterate it without locking.
+type subscribers[T any] struct {
+	mu   sync.Mutex // serializes writers
+	list atomic.Pointer[[]*subscriber[T]]
+}
+
+func (s *subscribers[T]) add(value T) *Subscription {
+	return s.addWithCleanup(value, nil)
+}
+
+// addWithCleanup is like add, but cleanup runs once the subscription has been
+// cancelled and removed.
+func (s *subscribers[T]) addWithCleanup(value T, cleanup func()) *Subscription {
+	sub := newSubscription(s.remove, cleanup)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current := s.snapshot()
+	next := make([]*subscriber[T], len(current), len(current)+1)
+	copy(next, current)
+	next = append(next, &subscriber[T]{value: value, sub: sub})
+	s.list.Store(&next)
+	return sub
+}
+
+func (s *subscribers[T]) remove(sub *Subscription) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current := s.snapshot()
+	for i, entry := range current {
+		if entry.sub == sub {
+			next := make([]*subscriber[T], 0, len(current)-1)
+			next = append(next, current[:i]...)
+			next = append(next, current[i+1:]...)
+			s.list.Store(&next)
+			return
+		}
+	}
+}
+
+func (s *subscribers[T]) snapshot() []*subscriber[T] {
+	if list := s.list.Load(); list != nil {
+		return *list
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// SignatureHeader carries the hex HMAC-SHA256 of the request body, prefixed
+// with "sha256=", when a WebhookObserver has a secret
+const SignatureHeader = "X-Signature"
+
+// PushPayload is the JSON body pushed for every job, by webhook or SSE
+type PushPayload struct {
+	JobTitle string    `json:"job"`
+	Time     time.Time `json:"time"`
+}
+
+// DeadLetter is a job that could not be delivered: it exhausted its retries
+// or the endpoint rejected it with a permanent 4xx
+type DeadLetter struct {
+	Payload  PushPayload
+	Attempts int
+	Err      error
+}
+
+// WebhookOptions configures a WebhookObserver; zero values pick defaults
+type WebhookOptions struct {
+	Secret      []byte       // signs payloads when set
+	Client      *http.Client // defaults to a client with a 5s timeout
+	MaxAttempts int          // defaults to 3
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// Sleep waits between attempts; tests can replace it to skip the delays
+	Sleep func(time.Duration)
+}
+
+// WebhookObserver forwards every job to an HTTP endpoint. Update blocks while
+// it retries, so attach it with AttachAsync to keep the publisher unaffected.
+type WebhookObserver struct {
+	url     string
+	options WebhookOptions
+	now     func() time.Time
+
+	mu          sync.Mutex
+	deadLetters []DeadLetter
+}
+
+func NewWebhookObserver(url string, options WebhookOptions) *WebhookObserver {
+	if options.Client == nil {
+		options.Client = &http.Client{Timeout: 5 * time.Second}
+	}
+	if options.MaxAttempts < 1 {
+		options.MaxAttempts = 3
+	}
+	if options.BaseBackoff <= 0 {
+		options.BaseBackoff = 100 * time.Millisecond
+	}
+	if options.MaxBackoff <= 0 {
+		options.MaxBackoff = 5 * time.Second
+	}
+	if options.Sleep == nil {
+		options.Sleep = time.Sleep
+	}
+	return &WebhookObserver{url: url, options: options, now: time.Now}
+}
+
+func (wo *WebhookObserver) Update(jobTitle string) {
+	payload := PushPayload{JobTitle: jobTitle, Time: wo.now()}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		wo.deadLetter(payload, 0, err)
+		return
+	}
+
+	backoff := wo.options.BaseBackoff
+	for attempt := 1; ; attempt++ {
+		err = wo.post(body)
+		if err == nil {
+			return
+		}
+		if attempt == wo.options.MaxAttempts || permanent(err) {
+			wo.deadLetter(payload, attempt, err)
+			return
+		}
+		wo.options.Sleep(backoff)
+		backoff *= 2
+		if backoff > wo.options.MaxBackoff {
+			backoff = wo.options.MaxBackoff
+		}
+	}
+}
+
+func (wo *WebhookObserver) post(body []byte) error {
+	request, err := http.NewRequest(http.MethodPost, wo.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if len(wo.options.Secret) > 0 {
+		request.Header.Set(SignatureHeader, "sha256="+Sign(wo.options.Secret, body))
+	}
+
+	response, err := wo.options.Client.Do(request)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return &StatusError{Code: response.StatusCode, Status: response.Status}
+	}
+	return nil
+}
+
+// StatusError is a non-2xx webhook response
+type StatusError struct {
+	Code   int
+	Status string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("webhook responded %s", e.Status)
+}
+
+// permanent reports whether retrying cannot help: the endpoint rejected the
+// request itself. 408 and 429 ask the sender to come back later, so they are
+// retried like 5xx and transport errors.
+func permanent(err error) bool {
+	var status *StatusError
+	if !errors.As(err, &status) {
+		return false
+	}
+	switch status.Code {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return false
+	}
+	return status.Code >= 400 && status.Code < 500
+}
+
+func (wo *WebhookObserver) deadLetter(payload PushPayload, attempts int, err error) {
+	wo.mu.Lock()
+	defer wo.mu.Unlock()
+	wo.deadLetters = append(wo.deadLetters, DeadLetter{Payload: payload, Attempts: attempts, Err: err})
+}
+
+// DeadLetters returns the jobs that exhausted their retries
+func (wo *WebhookObserver) DeadLetters() []DeadLetter {
+	wo.mu.Lock()
+	defer wo.mu.Unlock()
+	return append([]DeadLetter(nil), wo.deadLetters...)
+}
+
+// Sign returns the hex HMAC-SHA256 of body under secret
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature checks a SignatureHeader value against body, so receivers
+// can reject payloads that were not sent by us
+func VerifySignature(secret, body []byte, header string) bool {
+	const prefix = "sha256="
+	if len(header) <= len(prefix) || header[:len(prefix)] != prefix {
+		return false
+	}
+	expected, err := hex.DecodeString(header[len(prefix):])
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
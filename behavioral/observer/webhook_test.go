@@ -0,0 +1,197 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// receiver is a webhook endpoint that answers with the queued status codes,
+// then 200, and records each request's body and signature header
+type receiver struct {
+	mu         sync.Mutex
+	statuses   []int
+	bodies     [][]byte
+	signatures []string
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rc.mu.Lock()
+	rc.bodies = append(rc.bodies, body)
+	rc.signatures = append(rc.signatures, r.Header.Get(SignatureHeader))
+	status := http.StatusOK
+	if len(rc.statuses) > 0 {
+		status, rc.statuses = rc.statuses[0], rc.statuses[1:]
+	}
+	rc.mu.Unlock()
+	w.WriteHeader(status)
+}
+
+func (rc *receiver) requests() int {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return len(rc.bodies)
+}
+
+// recordSleeps replaces WebhookOptions.Sleep and remembers each backoff
+func recordSleeps(sleeps *[]time.Duration) func(time.Duration) {
+	return func(d time.Duration) { *sleeps = append(*sleeps, d) }
+}
+
+func TestWebhookSignsPayload(t *testing.T) {
+	secret := []byte("s3cret")
+	rc := &receiver{}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	webhook := NewWebhookObserver(server.URL, WebhookOptions{Secret: secret})
+	webhook.Update("Platform Engineer")
+
+	if rc.requests() != 1 {
+		t.Fatalf("got %d requests, want 1", rc.requests())
+	}
+	if !VerifySignature(secret, rc.bodies[0], rc.signatures[0]) {
+		t.Errorf("signature %q does not verify body %s", rc.signatures[0], rc.bodies[0])
+	}
+	if VerifySignature([]byte("other"), rc.bodies[0], rc.signatures[0]) {
+		t.Error("signature verified under the wrong secret")
+	}
+	tampered := append([]byte(nil), rc.bodies[0]...)
+	tampered[len(tampered)-2] ^= 1
+	if VerifySignature(secret, tampered, rc.signatures[0]) {
+		t.Error("signature verified a tampered body")
+	}
+	if len(webhook.DeadLetters()) != 0 {
+		t.Errorf("unexpected dead letters: %v", webhook.DeadLetters())
+	}
+}
+
+func TestWebhookUnsignedWithoutSecret(t *testing.T) {
+	rc := &receiver{}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	NewWebhookObserver(server.URL, WebhookOptions{}).Update("Platform Engineer")
+	if rc.signatures[0] != "" {
+		t.Errorf("got signature %q without a secret", rc.signatures[0])
+	}
+}
+
+func TestWebhookRetriesWithBackoff(t *testing.T) {
+	for _, status := range []int{
+		http.StatusInternalServerError,
+		http.StatusServiceUnavailable,
+		http.StatusRequestTimeout,
+		http.StatusTooManyRequests,
+	} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			rc := &receiver{statuses: []int{status, status, status}}
+			server := httptest.NewServer(rc)
+			defer server.Close()
+
+			var sleeps []time.Duration
+			webhook := NewWebhookObserver(server.URL, WebhookOptions{
+				MaxAttempts: 5,
+				BaseBackoff: 10 * time.Millisecond,
+				MaxBackoff:  25 * time.Millisecond,
+				Sleep:       recordSleeps(&sleeps),
+			})
+			webhook.Update("Platform Engineer")
+
+			if rc.requests() != 4 {
+				t.Errorf("got %d requests, want 4", rc.requests())
+			}
+			want := []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 25 * time.Millisecond}
+			if len(sleeps) != len(want) {
+				t.Fatalf("slept %v, want %v", sleeps, want)
+			}
+			for i := range want {
+				if sleeps[i] != want[i] {
+					t.Fatalf("slept %v, want %v", sleeps, want)
+				}
+			}
+			if len(webhook.DeadLetters()) != 0 {
+				t.Errorf("unexpected dead letters: %v", webhook.DeadLetters())
+			}
+		})
+	}
+}
+
+func TestWebhookDeadLettersAfterMaxAttempts(t *testing.T) {
+	rc := &receiver{statuses: []int{502, 502, 502, 502}}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	var sleeps []time.Duration
+	webhook := NewWebhookObserver(server.URL, WebhookOptions{MaxAttempts: 3, Sleep: recordSleeps(&sleeps)})
+	webhook.Update("Platform Engineer")
+
+	if rc.requests() != 3 || len(sleeps) != 2 {
+		t.Errorf("got %d requests and %d sleeps, want 3 and 2", rc.requests(), len(sleeps))
+	}
+	letters := webhook.DeadLetters()
+	if len(letters) != 1 {
+		t.Fatalf("got %d dead letters, want 1", len(letters))
+	}
+	if letters[0].Attempts != 3 || letters[0].Payload.JobTitle != "Platform Engineer" {
+		t.Errorf("dead letter = %+v", letters[0])
+	}
+}
+
+func TestWebhookDeadLettersPermanentFailures(t *testing.T) {
+	for _, status := range []int{
+		http.StatusBadRequest,
+		http.StatusUnauthorized,
+		http.StatusNotFound,
+		http.StatusGone,
+		http.StatusUnprocessableEntity,
+	} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			rc := &receiver{statuses: []int{status}}
+			server := httptest.NewServer(rc)
+			defer server.Close()
+
+			var sleeps []time.Duration
+			webhook := NewWebhookObserver(server.URL, WebhookOptions{MaxAttempts: 5, Sleep: recordSleeps(&sleeps)})
+			webhook.Update("Platform Engineer")
+
+			if rc.requests() != 1 || len(sleeps) != 0 {
+				t.Errorf("got %d requests and %d sleeps, want 1 and 0", rc.requests(), len(sleeps))
+			}
+			letters := webhook.DeadLetters()
+			if len(letters) != 1 || letters[0].Attempts != 1 {
+				t.Fatalf("dead letters = %+v, want one after 1 attempt", letters)
+			}
+			if err, ok := letters[0].Err.(*StatusError); !ok || err.Code != status {
+				t.Errorf("dead letter error = %v, want status %d", letters[0].Err, status)
+			}
+		})
+	}
+}
+
+func TestWebhookRetriesTransportErrors(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			// Drop the connection without a response
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+		}
+	}))
+	defer server.Close()
+
+	webhook := NewWebhookObserver(server.URL, WebhookOptions{Sleep: func(time.Duration) {}})
+	webhook.Update("Platform Engineer")
+
+	if calls.Load() != 2 {
+		t.Errorf("got %d requests, want 2", calls.Load())
+	}
+	if len(webhook.DeadLetters()) != 0 {
+		t.Errorf("unexpected dead letters: %v", webhook.DeadLetters())
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+// Key is a keystroke: a printable character, or one of the special keys below
+type Key rune
+
+// Special keys are negative so they never collide with a character
+const (
+	KeyEscape Key = -(iota + 1)
+	KeyBackspace
+	KeyDelete
+	KeyLeft
+	KeyRight
+	KeyHome
+	KeyEnd
+	KeyInsert
+	KeyCapsLock
+	KeyEnter Key = '\n'
+)
+
+// printable reports whether key types a character
+func (k Key) printable() bool {
+	return k >= 0
+}
+
+// Buffer is the editor's text with a cursor sitting between two characters
+type Buffer struct {
+	text   []rune
+	cursor int
+}
+
+// Insert puts r at the cursor and moves past it
+func (b *Buffer) Insert(r rune) {
+	b.text = append(b.text, 0)
+	copy(b.text[b.cursor+1:], b.text[b.cursor:])
+	b.text[b.cursor] = r
+	b.cursor++
+}
+
+// Overwrite replaces the character after the cursor with r, or appends it at
+// the end of a line
+func (b *Buffer) Overwrite(r rune) {
+	if b.cursor == len(b.text) || b.text[b.cursor] == '\n' || r == '\n' {
+		b.Insert(r)
+		return
+	}
+	b.text[b.cursor] = r
+	b.cursor++
+}
+
+// Backspace removes the character before the cursor
+func (b *Buffer) Backspace() {
+	if b.cursor == 0 {
+		return
+	}
+	b.cursor--
+	b.Delete()
+}
+
+// Delete removes the character after the cursor
+func (b *Buffer) Delete() {
+	if b.cursor == len(b.text) {
+		return
+	}
+	b.text = append(b.text[:b.cursor], b.text[b.cursor+1:]...)
+}
+
+// Move shifts the cursor by n characters, stopping at either end
+func (b *Buffer) Move(n int) {
+	b.cursor = max(0, min(len(b.text), b.cursor+n))
+}
+
+// Home moves the cursor to the start of its line
+func (b *Buffer) Home() {
+	b.cursor = b.lineStart()
+}
+
+// End moves the cursor to the end of its line
+func (b *Buffer) End() {
+	for b.cursor < len(b.text) && b.text[b.cursor] != '\n' {
+		b.cursor++
+	}
+}
+
+// Previous returns the character before the cursor, or 0 at the start
+func (b *Buffer) Previous() rune {
+	if b.cursor == 0 {
+		return 0
+	}
+	return b.text[b.cursor-1]
+}
+
+// Line returns the line the cursor is on
+func (b *Buffer) Line() string {
+	end := b.cursor
+	for end < len(b.text) && b.text[end] != '\n' {
+		end++
+	}
+	return string(b.text[b.lineStart():end])
+}
+
+func (b *Buffer) lineStart() int {
+	start := b.cursor
+	for start > 0 && b.text[start-1] != '\n' {
+		start--
+	}
+	return start
+}
+
+func (b *Buffer) Cursor() int {
+	return b.cursor
+}
+
+func (b *Buffer) String() string {
+	return string(b.text)
+}
+
+// Display renders the line the cursor is on with a | marking the cursor
+func (b *Buffer) Display() string {
+	line := []rune(b.Line())
+	column := b.cursor - b.lineStart()
+	return string(line[:column]) + "|" + string(line[column:])
+}
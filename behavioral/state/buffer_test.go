@@ -0,0 +1,107 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// bufferAt makes a buffer from text with a | marking the cursor
+func bufferAt(marked string) *Buffer {
+	cursor := strings.IndexRune(marked, '|')
+	return &Buffer{
+		text:   []rune(strings.Replace(marked, "|", "", 1)),
+		cursor: len([]rune(marked[:cursor])),
+	}
+}
+
+// marked is the whole buffer with a | marking the cursor
+func (b *Buffer) marked() string {
+	return string(b.text[:b.cursor]) + "|" + string(b.text[b.cursor:])
+}
+
+func TestBufferEditing(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		start string
+		edit  func(b *Buffer)
+		want  string
+	}{
+		{"insert into nothing", "|", func(b *Buffer) { b.Insert('a') }, "a|"},
+		{"insert at the start", "|bc", func(b *Buffer) { b.Insert('a') }, "a|bc"},
+		{"insert in the middle", "a|c", func(b *Buffer) { b.Insert('b') }, "ab|c"},
+		{"insert at the end", "ab|", func(b *Buffer) { b.Insert('c') }, "abc|"},
+		{"insert a newline", "ab|cd", func(b *Buffer) { b.Insert('\n') }, "ab\n|cd"},
+		{"insert non-ASCII", "a|c", func(b *Buffer) { b.Insert('ß') }, "aß|c"},
+
+		{"overwrite at the start", "|abc", func(b *Buffer) { b.Overwrite('x') }, "x|bc"},
+		{"overwrite the last character", "ab|c", func(b *Buffer) { b.Overwrite('x') }, "abx|"},
+		{"overwrite at the end appends", "abc|", func(b *Buffer) { b.Overwrite('d') }, "abcd|"},
+		{"overwrite keeps the line break", "ab|\ncd", func(b *Buffer) { b.Overwrite('x') }, "abx|\ncd"},
+		{"overwrite with a newline inserts it", "a|bc", func(b *Buffer) { b.Overwrite('\n') }, "a\n|bc"},
+
+		{"backspace at the start", "|abc", (*Buffer).Backspace, "|abc"},
+		{"backspace in the middle", "ab|c", (*Buffer).Backspace, "a|c"},
+		{"backspace at the end", "abc|", (*Buffer).Backspace, "ab|"},
+		{"backspace joins lines", "ab\n|cd", (*Buffer).Backspace, "ab|cd"},
+		{"backspace in nothing", "|", (*Buffer).Backspace, "|"},
+
+		{"delete at the start", "|abc", (*Buffer).Delete, "|bc"},
+		{"delete in the middle", "a|bc", (*Buffer).Delete, "a|c"},
+		{"delete at the end", "abc|", (*Buffer).Delete, "abc|"},
+		{"delete joins lines", "ab|\ncd", (*Buffer).Delete, "ab|cd"},
+		{"delete in nothing", "|", (*Buffer).Delete, "|"},
+
+		{"move right", "a|bc", func(b *Buffer) { b.Move(1) }, "ab|c"},
+		{"move left", "a|bc", func(b *Buffer) { b.Move(-1) }, "|abc"},
+		{"move stops at the start", "a|bc", func(b *Buffer) { b.Move(-5) }, "|abc"},
+		{"move stops at the end", "a|bc", func(b *Buffer) { b.Move(5) }, "abc|"},
+		{"move crosses lines", "ab|\ncd", func(b *Buffer) { b.Move(2) }, "ab\nc|d"},
+		{"move in nothing", "|", func(b *Buffer) { b.Move(1) }, "|"},
+
+		{"home", "ab|c", (*Buffer).Home, "|abc"},
+		{"home of a later line", "ab\ncd|ef", (*Buffer).Home, "ab\n|cdef"},
+		{"home at the start of a line", "ab\n|cd", (*Buffer).Home, "ab\n|cd"},
+		{"end", "a|bc", (*Buffer).End, "abc|"},
+		{"end of an earlier line", "a|b\ncd", (*Buffer).End, "ab|\ncd"},
+		{"end at the end of a line", "ab|\ncd", (*Buffer).End, "ab|\ncd"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			b := bufferAt(tc.start)
+			tc.edit(b)
+			if got := b.marked(); got != tc.want {
+				t.Errorf("%q became %q, want %q", tc.start, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBufferReading(t *testing.T) {
+	for _, tc := range []struct {
+		buffer   string
+		previous rune
+		line     string
+		display  string
+	}{
+		{"|", 0, "", "|"},
+		{"|abc", 0, "abc", "|abc"},
+		{"ab|c", 'b', "abc", "ab|c"},
+		{"ab\nc|d\nef", 'c', "cd", "c|d"},
+		{"ab\n|cd", '\n', "cd", "|cd"},
+		{"ab|\ncd", 'b', "ab", "ab|"},
+		{"ab\n|", '\n', "", "|"},
+	} {
+		b := bufferAt(tc.buffer)
+		if got := b.Previous(); got != tc.previous {
+			t.Errorf("%q: Previous() = %q, want %q", tc.buffer, got, tc.previous)
+		}
+		if got := b.Line(); got != tc.line {
+			t.Errorf("%q: Line() = %q, want %q", tc.buffer, got, tc.line)
+		}
+		if got := b.Display(); got != tc.display {
+			t.Errorf("%q: Display() = %q, want %q", tc.buffer, got, tc.display)
+		}
+		if got, want := b.String(), strings.Replace(tc.buffer, "|", "", 1); got != want {
+			t.Errorf("%q: String() = %q, want %q", tc.buffer, got, want)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+const usage = `usage: state [command]
+
+Without a command the demo runs. Commands:
+  diagram [-format dot|mermaid] [-o file]   write the TextEditor state machine diagram`
+
+// runCommand dispatches the subcommands of the state demo
+func runCommand(args []string) error {
+	switch args[0] {
+	case "diagram":
+		return runDiagram(args[1:])
+	case "help", "-h", "-help", "--help":
+		fmt.Println(usage)
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q\n%s", args[0], usage)
+	}
+}
+
+func runDiagram(args []string) error {
+	flags := flag.NewFlagSet("diagram", flag.ContinueOnError)
+	format := flags.String("format", "mermaid", "diagram format: dot or mermaid")
+	output := flags.String("o", "", "write to this file instead of stdout")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	definition := NewTextEditor(true).Definition()
+	var diagram string
+	switch *format {
+	case "dot":
+		diagram = definition.DOT("TextEditor")
+	case "mermaid":
+		diagram = definition.Mermaid()
+	default:
+		return fmt.Errorf("unknown diagram format %q, want dot or mermaid", *format)
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	_, err := io.WriteString(w, diagram)
+	return err
+}
@@ -1,66 +1,433 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
-	"strings"
+	"os"
+	"unicode"
+
+	"go-design-patterns/behavioral/state/fsm"
 )
 
 // State interface
+//
+// WritingState shapes each character typed into the buffer. previous is the
+// character before the cursor, or 0 at the start of the buffer.
 type WritingState interface {
-	Write(words string) string
+	Write(previous, r rune) rune
 }
 
 // Concrete states
 type UpperCase struct{}
 
-func (u UpperCase) Write(words string) string {
-	return strings.ToUpper(words)
+func (u UpperCase) Write(previous, r rune) rune {
+	return unicode.ToUpper(r)
 }
 
 type LowerCase struct{}
 
-func (l LowerCase) Write(words string) string {
-	return strings.ToLower(words)
+func (l LowerCase) Write(previous, r rune) rune {
+	return unicode.ToLower(r)
 }
 
 type DefaultText struct{}
 
-func (d DefaultText) Write(words string) string {
-	return words
+func (d DefaultText) Write(previous, r rune) rune {
+	return r
+}
+
+// TitleCase capitalizes the first letter of every word
+type TitleCase struct{}
+
+func (t TitleCase) Write(previous, r rune) rune {
+	if previous == 0 || unicode.IsSpace(previous) {
+		return unicode.ToUpper(r)
+	}
+	return unicode.ToLower(r)
+}
+
+// Editor states and the events that switch between them
+//
+//	editing (parallel, deep history)
+//	├── case:  default | upper | lower | title
+//	└── input: insert | replace | normal
+//	readonly
+//	menu
+const (
+	StateEditing  fsm.State = "editing"
+	StateCase     fsm.State = "case"
+	StateDefault  fsm.State = "default"
+	StateUpper    fsm.State = "upper"
+	StateLower    fsm.State = "lower"
+	StateTitle    fsm.State = "title"
+	StateInput    fsm.State = "input"
+	StateInsert   fsm.State = "insert"
+	StateReplace  fsm.State = "replace"
+	StateNormal   fsm.State = "normal"
+	StateReadOnly fsm.State = "readonly"
+	StateMenu     fsm.State = "menu"
+
+	EventCapsLock    fsm.Event = "caps_lock"
+	EventLowerCase   fsm.Event = "lower_case"
+	EventTitleCase   fsm.Event = "title_case"
+	EventReset       fsm.Event = "reset"
+	EventInsertKey   fsm.Event = "insert_key"
+	EventEscape      fsm.Event = "escape"
+	EventInsertMode  fsm.Event = "insert_mode"
+	EventReplaceMode fsm.Event = "replace_mode"
+	EventLock        fsm.Event = "lock"
+	EventUnlock      fsm.Event = "unlock"
+	EventOpenMenu    fsm.Event = "open_menu"
+	EventCloseMenu   fsm.Event = "close_menu"
+)
+
+// editorVersion is bumped whenever the editor's states change shape.
+// Version 1 had only the default, upper and lower states, at the top level.
+const editorVersion = 2
+
+// writingStates is the state registry for the editor's case behaviour;
+// inputModes holds its input behaviour. Composite states have none of their
+// own.
+var writingStates = map[fsm.State]WritingState{
+	StateDefault: DefaultText{},
+	StateUpper:   UpperCase{},
+	StateLower:   LowerCase{},
+	StateTitle:   TitleCase{},
 }
 
+var errShoutingDisabled = errors.New("shouting is disabled for this editor")
+
 // Context
+//
+// TextEditor no longer accepts an arbitrary state: its WritingState and
+// InputMode follow a state machine, so only the declared transitions can
+// change them.
 type TextEditor struct {
-	state WritingState
+	state         WritingState
+	mode          InputMode
+	buffer        Buffer
+	machine       *fsm.Machine
+	allowShouting bool
+}
+
+func NewTextEditor(allowShouting bool) *TextEditor {
+	te := &TextEditor{allowShouting: allowShouting}
+	te.machine = te.definition().NewMachine()
+	return te
+}
+
+// definition declares the editor's state machine, bound to te's guards and
+// entry actions
+func (te *TextEditor) definition() *fsm.Definition {
+	enter := func(state fsm.State) fsm.Action {
+		return func(fsm.Step) { te.use(state) }
+	}
+	definition, err := fsm.NewDefinition(fsm.Spec{
+		Name:       "text-editor",
+		Version:    editorVersion,
+		Migrations: map[int]fsm.Migration{1: migrateFlatEditor},
+		Initial:    StateEditing,
+		States: []fsm.StateSpec{
+			{Name: StateEditing, Parallel: true, History: fsm.DeepHistory},
+			{Name: StateCase, Parent: StateEditing, Initial: StateDefault},
+			{Name: StateDefault, Parent: StateCase, OnEnter: enter(StateDefault), OnEnterName: "use DefaultText"},
+			{Name: StateUpper, Parent: StateCase, OnEnter: enter(StateUpper), OnEnterName: "use UpperCase"},
+			{Name: StateLower, Parent: StateCase, OnEnter: enter(StateLower), OnEnterName: "use LowerCase"},
+			{Name: StateTitle, Parent: StateCase, OnEnter: enter(StateTitle), OnEnterName: "use TitleCase"},
+			{Name: StateInput, Parent: StateEditing, Initial: StateInsert},
+			{Name: StateInsert, Parent: StateInput, OnEnter: enter(StateInsert), OnEnterName: "use InsertMode"},
+			{Name: StateReplace, Parent: StateInput, OnEnter: enter(StateReplace), OnEnterName: "use ReplaceMode"},
+			{Name: StateNormal, Parent: StateInput, OnEnter: enter(StateNormal), OnEnterName: "use NormalMode"},
+			{Name: StateReadOnly, OnEnter: enter(StateReadOnly), OnEnterName: "use ReadOnly"},
+			{Name: StateMenu, OnEnter: enter(StateMenu), OnEnterName: "use MenuOpen"},
+		},
+		Transitions: []fsm.Transition{
+			{From: StateDefault, Event: EventCapsLock, To: StateUpper, Guard: te.canShout},
+			{From: StateLower, Event: EventCapsLock, To: StateUpper, Guard: te.canShout},
+			{From: StateTitle, Event: EventCapsLock, To: StateUpper, Guard: te.canShout},
+			{From: StateUpper, Event: EventCapsLock, To: StateDefault},
+			{From: StateDefault, Event: EventLowerCase, To: StateLower},
+			{From: StateUpper, Event: EventLowerCase, To: StateLower},
+			{From: StateTitle, Event: EventLowerCase, To: StateLower},
+			{From: StateTitle, Event: EventTitleCase, To: StateDefault},
+			// Declared once on the composite; every case sub-state inherits
+			// them unless it handles the event itself, as title does
+			{From: StateCase, Event: EventTitleCase, To: StateTitle},
+			{From: StateCase, Event: EventReset, To: StateDefault},
+			{From: StateInsert, Event: EventInsertKey, To: StateReplace},
+			{From: StateReplace, Event: EventInsertKey, To: StateInsert},
+			{From: StateInsert, Event: EventEscape, To: StateNormal},
+			{From: StateReplace, Event: EventEscape, To: StateNormal},
+			{From: StateNormal, Event: EventInsertMode, To: StateInsert},
+			{From: StateNormal, Event: EventReplaceMode, To: StateReplace},
+			{From: StateEditing, Event: EventLock, To: StateReadOnly},
+			{From: StateReadOnly, Event: EventUnlock, To: StateEditing},
+			{From: StateEditing, Event: EventOpenMenu, To: StateMenu},
+			{From: StateMenu, Event: EventCloseMenu, To: StateEditing},
+		},
+	})
+	if err != nil {
+		panic(err) // the definition above is static; an error is a programming bug
+	}
+	return definition
+}
+
+// use looks up the behaviour state gives the editor in the state registries
+func (te *TextEditor) use(state fsm.State) {
+	if writing, ok := writingStates[state]; ok {
+		te.state = writing
+	}
+	if mode, ok := inputModes[state]; ok {
+		te.mode = mode
+	}
+}
+
+// migrateFlatEditor moves a version 1 save, whose only states were default,
+// upper and lower, into the case region; the new input region starts in insert
+func migrateFlatEditor(s fsm.Snapshot) (fsm.Snapshot, error) {
+	if len(s.Active) != 1 {
+		return s, fmt.Errorf("version 1 editor has one active state, got %v", s.Active)
+	}
+	s.Active = []fsm.State{s.Active[0], StateInsert}
+	return s, nil
+}
+
+// editorData is the editor context saved next to its machine
+type editorData struct {
+	AllowShouting bool   `json:"allow_shouting"`
+	Text          string `json:"text,omitempty"`
+	Cursor        int    `json:"cursor,omitempty"`
+}
+
+// MarshalJSON saves the editor's modes, history, settings and buffer
+func (te *TextEditor) MarshalJSON() ([]byte, error) {
+	snapshot := te.machine.Snapshot()
+	data, err := json.Marshal(editorData{
+		AllowShouting: te.allowShouting,
+		Text:          te.buffer.String(),
+		Cursor:        te.buffer.Cursor(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	snapshot.Data = data
+	return json.Marshal(snapshot)
+}
+
+// RestoreTextEditor rebuilds an editor saved with MarshalJSON. Saves from
+// older editor versions are migrated; ones that cannot be are rejected with
+// an *fsm.VersionError.
+func RestoreTextEditor(saved []byte) (*TextEditor, error) {
+	snapshot, err := fsm.ParseSnapshot(saved)
+	if err != nil {
+		return nil, fmt.Errorf("reading saved editor: %w", err)
+	}
+
+	te := &TextEditor{}
+	definition := te.definition()
+	if snapshot, err = definition.Migrate(snapshot); err != nil {
+		return nil, err
+	}
+	var data editorData
+	if len(snapshot.Data) > 0 {
+		if err := json.Unmarshal(snapshot.Data, &data); err != nil {
+			return nil, fmt.Errorf("reading saved editor data: %w", err)
+		}
+	}
+	te.allowShouting = data.AllowShouting
+	te.buffer = Buffer{text: []rune(data.Text)}
+	te.buffer.Move(data.Cursor)
+	if te.machine, err = definition.Restore(snapshot); err != nil {
+		return nil, err
+	}
+
+	// Restoring runs no entry actions, so look the behaviour up instead
+	for _, state := range te.machine.Active() {
+		te.use(state)
+	}
+	return te, nil
 }
 
-func NewTextEditor(state WritingState) *TextEditor {
-	return &TextEditor{state: state}
+func (te *TextEditor) canShout(fsm.Step) error {
+	if !te.allowShouting {
+		return errShoutingDisabled
+	}
+	return nil
 }
 
-func (te *TextEditor) SetState(state WritingState) {
-	te.state = state
+// Handle fires an editor event, returning an error if the current state does
+// not accept it
+func (te *TextEditor) Handle(event fsm.Event) error {
+	return te.machine.Fire(event)
 }
 
-func (te *TextEditor) Type(words string) string {
-	return te.state.Write(words)
+// Mode returns the innermost state of the editor's first region
+func (te *TextEditor) Mode() fsm.State {
+	return te.machine.Current()
+}
+
+// Modes returns the innermost state of every active region
+func (te *TextEditor) Modes() []fsm.State {
+	return te.machine.Leaves()
+}
+
+// Press hands one keystroke to the current input mode, which decides what
+// it does to the buffer
+func (te *TextEditor) Press(key Key) error {
+	return te.mode.Press(te, key)
+}
+
+// Type presses the key for every character of words, stopping at the first
+// keystroke the editor refuses
+func (te *TextEditor) Type(words string) error {
+	for _, r := range words {
+		if err := te.Press(Key(r)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Text returns the whole buffer
+func (te *TextEditor) Text() string {
+	return te.buffer.String()
+}
+
+// Line returns the line the cursor is on, with a | marking the cursor
+func (te *TextEditor) Line() string {
+	return te.buffer.Display()
+}
+
+// Machine returns the state machine the editor runs, for registering
+// transition listeners. Restoring an editor creates a new machine.
+func (te *TextEditor) Machine() *fsm.Machine {
+	return te.machine
+}
+
+// Definition returns the state machine definition the editor runs
+func (te *TextEditor) Definition() *fsm.Definition {
+	return te.machine.Definition()
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	fmt.Println("=== State Pattern Demo ===")
-	
-	editor := NewTextEditor(DefaultText{})
-	
+
+	editor := NewTextEditor(true)
+	audit := fsm.NewTransitionLog(8)
+	editor.Machine().OnTransition(audit.Record)
+	editor.Machine().OnState(StateReadOnly, func(c fsm.Change) {
+		fmt.Printf("(listener: %s -(%s)-> %s)\n", c.From, c.Event, c.To)
+	})
+
 	fmt.Println("Default state:")
-	fmt.Println(editor.Type("First line"))
-	
-	editor.SetState(UpperCase{})
-	fmt.Println("\nUpper case state:")
-	fmt.Println(editor.Type("Second line"))
-	
-	editor.SetState(LowerCase{})
+	editor.Type("First line")
+	fmt.Println(editor.Line())
+
+	editor.Press(KeyCapsLock)
+	fmt.Println("\nUpper case state (caps lock key):")
+	editor.Type("\nSecond line")
+	fmt.Println(editor.Line())
+
+	editor.Handle(EventLowerCase)
 	fmt.Println("\nLower case state:")
-	fmt.Println(editor.Type("Third line"))
-	
+	editor.Type("\nThird line")
+	fmt.Println(editor.Line())
+
+	editor.Handle(EventTitleCase)
+	fmt.Println("\nTitle case state:")
+	editor.Type("\nfourth line of text")
+	fmt.Println(editor.Line())
+
+	// Undeclared transitions are rejected with a typed error
+	fmt.Println("\nInvalid and vetoed transitions:")
+	var invalid *fsm.InvalidTransitionError
+	if err := editor.Handle(EventUnlock); errors.As(err, &invalid) {
+		fmt.Printf("Rejected: %v\n", err)
+	}
+	quiet := NewTextEditor(false)
+	if err := quiet.Press(KeyCapsLock); errors.Is(err, errShoutingDisabled) {
+		fmt.Printf("Vetoed: %v\n", err)
+	}
+	quiet.Type("Fifth line")
+	fmt.Printf("Quiet editor is still in %q mode: %s\n", quiet.Mode(), quiet.Line())
+
+	// Nested states: replace mode types over the line, the menu returns to
+	// exactly the sub-states that were active before, and reset bubbles up
+	// to the case region
+	fmt.Println("\nNested modes with history:")
+	editor.Type("\ndraft line")
+	editor.Handle(EventCapsLock)
+	editor.Press(KeyInsert)
+	editor.Press(KeyHome)
+	editor.Type("sixth")
+	fmt.Printf("Modes: %v -> %s\n", editor.Modes(), editor.Line())
+	editor.Handle(EventOpenMenu)
+	editor.Type("ignored")
+	fmt.Printf("Modes: %v -> %s\n", editor.Modes(), editor.Line())
+	editor.Press(KeyEscape) // closes the menu
+	editor.Handle(EventReset)
+	fmt.Printf("Modes: %v -> %s\n", editor.Modes(), editor.Line())
+
+	// Vim-like normal mode: keys are commands, not text
+	fmt.Println("\nNormal and insert modes:")
+	editor.Press(KeyEscape)
+	editor.Type("0xxxxx")
+	fmt.Printf("Modes: %v -> %s\n", editor.Modes(), editor.Line())
+	editor.Type("iSeventh")
+	fmt.Printf("Modes: %v -> %s\n", editor.Modes(), editor.Line())
+
+	// Read-only: the cursor still moves, but the buffer cannot change
+	fmt.Println("\nRead-only mode:")
+	editor.Handle(EventLock)
+	if err := editor.Type("!"); errors.Is(err, errReadOnly) {
+		fmt.Printf("Refused: %v\n", err)
+	}
+	editor.Press(KeyEnd)
+	editor.Handle(EventUnlock)
+	fmt.Printf("Modes: %v -> %s\n", editor.Modes(), editor.Line())
+
+	// The audit log keeps the last transitions for debugging
+	fmt.Println("\nTransition log:")
+	audit.Dump(os.Stdout)
+	locks := audit.Find(fsm.Query{State: StateReadOnly})
+	if len(locks) > 0 {
+		fmt.Printf("%d logged transitions touched read-only mode, the first on %q\n", len(locks), locks[0].Event)
+	} else {
+		fmt.Println("No logged transition touched read-only mode")
+	}
+
+	// Editors survive a restart: modes, history, settings and the buffer
+	// round-trip through JSON, and saves from older editor versions are
+	// migrated
+	fmt.Println("\nSaving and restoring:")
+	editor.Handle(EventLowerCase)
+	saved, _ := json.Marshal(editor)
+	fmt.Printf("Saved: %s\n", saved)
+	restored, err := RestoreTextEditor(saved)
+	if err != nil {
+		fmt.Printf("Restore failed: %v\n", err)
+		return
+	}
+	restored.Type(" and eighth")
+	fmt.Printf("Restored modes: %v -> %s\n", restored.Modes(), restored.Line())
+	old, err := RestoreTextEditor([]byte(`{"machine":"text-editor","version":1,"active":["upper"],"data":{"allow_shouting":true}}`))
+	if err == nil {
+		old.Type("Ninth line")
+		fmt.Printf("Migrated version 1 save: %v -> %s\n", old.Modes(), old.Line())
+	}
+	var versionErr *fsm.VersionError
+	if _, err := RestoreTextEditor([]byte(`{"machine":"text-editor","version":3,"active":["upper","insert"]}`)); errors.As(err, &versionErr) {
+		fmt.Printf("Rejected: %v\n", err)
+	}
+
 	fmt.Println("\nState pattern allows object behavior to change based on internal state!")
 }
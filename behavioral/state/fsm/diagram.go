@@ -0,0 +1,290 @@
+package fsm
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// DOT renders the definition as a Graphviz digraph. Composite states become
+// clusters, parallel regions are drawn dashed, and transition labels read
+// "event [guard] / action".
+func (d *Definition) DOT(name string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %s {\n", dotID(name))
+	b.WriteString("  compound=true;\n  rankdir=LR;\n  node [shape=box, style=rounded];\n")
+	b.WriteString("  \"__start\" [shape=point, width=0.15];\n")
+	if d.compound(d.initial) {
+		fmt.Fprintf(&b, "  \"__start\" -> %s [lhead=%s];\n", d.dotEndpoint(d.initial), dotQuote(clusterID(d.initial)))
+	} else {
+		fmt.Fprintf(&b, "  \"__start\" -> %s;\n", d.dotEndpoint(d.initial))
+	}
+	for _, state := range d.children[root] {
+		d.writeDOTState(&b, state, "  ")
+	}
+	for _, t := range d.declared {
+		attrs := []string{"label=" + dotQuote(d.transitionLabel(t))}
+		tail, head := d.dotLeaf(t.From), d.dotLeaf(t.To)
+		// Clipping only makes sense when the other end lies outside the
+		// cluster; Graphviz rejects an edge clipped at a cluster holding both
+		if d.compound(t.From) && !d.nestedIn(head, t.From) {
+			attrs = append(attrs, "ltail="+dotQuote(clusterID(t.From)))
+		}
+		if d.compound(t.To) && !d.nestedIn(tail, t.To) {
+			attrs = append(attrs, "lhead="+dotQuote(clusterID(t.To)))
+		}
+		fmt.Fprintf(&b, "  %s -> %s [%s];\n", dotQuote(string(tail)), dotQuote(string(head)), strings.Join(attrs, ", "))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func (d *Definition) writeDOTState(b *strings.Builder, state State, indent string) {
+	if !d.compound(state) {
+		fmt.Fprintf(b, "%s%s [label=%s];\n", indent, dotQuote(string(state)), dotQuote(d.stateLabel(state, "\n")))
+		return
+	}
+
+	spec := d.states[state]
+	fmt.Fprintf(b, "%ssubgraph %s {\n", indent, dotQuote(clusterID(state)))
+	fmt.Fprintf(b, "%s  label=%s;\n", indent, dotQuote(d.stateLabel(state, "\n")))
+	if spec.Parallel {
+		fmt.Fprintf(b, "%s  style=dashed;\n", indent)
+	} else {
+		fmt.Fprintf(b, "%s  style=rounded;\n", indent)
+		start := dotQuote("__start_" + string(state))
+		fmt.Fprintf(b, "%s  %s [shape=point, width=0.1];\n", indent, start)
+		fmt.Fprintf(b, "%s  %s -> %s;\n", indent, start, d.dotEndpoint(d.initialChild(state)))
+	}
+	for _, child := range d.children[state] {
+		d.writeDOTState(b, child, indent+"  ")
+	}
+	fmt.Fprintf(b, "%s}\n", indent)
+}
+
+// dotEndpoint picks the node edges attach to: composites are clusters, which
+// Graphviz cannot connect, so edges go to their innermost default leaf and
+// are clipped at the cluster border with ltail/lhead
+func (d *Definition) dotEndpoint(state State) string {
+	return dotQuote(string(d.dotLeaf(state)))
+}
+
+func (d *Definition) dotLeaf(state State) State {
+	for d.compound(state) {
+		state = d.children[state][0]
+		if !d.parallel(d.parent(state)) {
+			state = d.initialChild(d.parent(state))
+		}
+	}
+	return state
+}
+
+// Mermaid renders the definition as a Mermaid stateDiagram-v2. Each
+// transition is written inside the innermost composite containing both of its
+// ends, as Mermaid requires.
+func (d *Definition) Mermaid() string {
+	m := mermaidWriter{Definition: d, ids: d.mermaidIDs()}
+	m.WriteString("stateDiagram-v2\n")
+	m.writeBody(root, "  ")
+	return m.String()
+}
+
+// mermaidIDs gives every state an identifier Mermaid accepts. Names that
+// already are identifiers keep them; the others are sanitised and, if that
+// collides with another state's ID, numbered ("a-b" next to "a_b" becomes
+// "a_b_2").
+func (d *Definition) mermaidIDs() map[State]string {
+	ids := make(map[State]string, len(d.order))
+	taken := make(map[string]bool, len(d.order))
+	for _, state := range d.order {
+		if id := string(state); !nonIdentifier.MatchString(id) && id != "" {
+			ids[state] = id
+			taken[id] = true
+		}
+	}
+	for _, state := range d.order {
+		if _, ok := ids[state]; ok {
+			continue
+		}
+		base := nonIdentifier.ReplaceAllString(string(state), "_")
+		id := base
+		for n := 2; taken[id] || id == ""; n++ {
+			id = fmt.Sprintf("%s_%d", base, n)
+		}
+		ids[state] = id
+		taken[id] = true
+	}
+	return ids
+}
+
+// mermaidWriter renders one Mermaid diagram with a fixed set of state IDs
+type mermaidWriter struct {
+	*Definition
+	strings.Builder
+	ids map[State]string
+}
+
+func (m *mermaidWriter) id(state State) string {
+	return m.ids[state]
+}
+
+func (m *mermaidWriter) writeBody(parent State, indent string) {
+	d, b := m.Definition, &m.Builder
+	if parent == root {
+		fmt.Fprintf(b, "%s[*] --> %s\n", indent, m.id(d.initial))
+	} else if !d.parallel(parent) {
+		fmt.Fprintf(b, "%s[*] --> %s\n", indent, m.id(d.initialChild(parent)))
+	}
+
+	for i, state := range d.children[parent] {
+		if i > 0 && parent != root && d.parallel(parent) {
+			fmt.Fprintf(b, "%s--\n", indent)
+		}
+		if state != State(m.id(state)) {
+			fmt.Fprintf(b, "%sstate %q as %s\n", indent, state, m.id(state))
+		}
+		if d.compound(state) {
+			fmt.Fprintf(b, "%sstate %s {\n", indent, m.id(state))
+			m.writeBody(state, indent+"  ")
+			fmt.Fprintf(b, "%s}\n", indent)
+		}
+		notes := d.stateNotes(state)
+		switch d.states[state].History {
+		case ShallowHistory:
+			notes = append([]string{"shallow history [H]"}, notes...)
+		case DeepHistory:
+			notes = append([]string{"deep history [H*]"}, notes...)
+		}
+		for _, line := range notes {
+			fmt.Fprintf(b, "%s%s : %s\n", indent, m.id(state), line)
+		}
+		if parent != root && d.parallel(parent) {
+			// Transitions within one region belong to that region's section
+			m.writeTransitions(parent, state, indent)
+		}
+	}
+	if parent == root || !d.parallel(parent) {
+		m.writeTransitions(parent, root, indent)
+	}
+}
+
+func (m *mermaidWriter) writeTransitions(scope, region State, indent string) {
+	for _, t := range m.declared {
+		if s, r := m.diagramScope(t); s == scope && r == region {
+			fmt.Fprintf(&m.Builder, "%s%s --> %s : %s\n", indent, m.id(t.From), m.id(t.To), m.transitionLabel(t))
+		}
+	}
+}
+
+// diagramScope returns the innermost composite containing both ends of t,
+// or the root. When that composite is parallel, region is the region holding
+// both ends.
+func (d *Definition) diagramScope(t Transition) (scope, region State) {
+	scope = d.parent(t.From)
+	for scope != root {
+		if d.nestedIn(t.To, scope) {
+			if !d.parallel(scope) {
+				return scope, root
+			}
+			if from, to := d.path(scope, t.From)[0], d.path(scope, t.To)[0]; from == to {
+				return scope, from
+			}
+		}
+		scope = d.parent(scope)
+	}
+	return root, root
+}
+
+func (d *Definition) stateLabel(state State, sep string) string {
+	spec := d.states[state]
+	label := string(state)
+	switch spec.History {
+	case ShallowHistory:
+		label += " [H]"
+	case DeepHistory:
+		label += " [H*]"
+	}
+	if spec.Parallel {
+		label += " (parallel)"
+	}
+	for _, note := range d.stateNotes(state) {
+		label += sep + note
+	}
+	return label
+}
+
+func (d *Definition) stateNotes(state State) []string {
+	spec := d.states[state]
+	var notes []string
+	if name := actionName(spec.OnEnterName, spec.OnEnter); name != "" {
+		notes = append(notes, "entry / "+name)
+	}
+	if name := actionName(spec.OnExitName, spec.OnExit); name != "" {
+		notes = append(notes, "exit / "+name)
+	}
+	return notes
+}
+
+func (d *Definition) transitionLabel(t Transition) string {
+	label := string(t.Event)
+	if t.Guard != nil {
+		name := funcName(t.GuardName, t.Guard)
+		if name == "" {
+			name = "guard"
+		}
+		label += " [" + name + "]"
+	}
+	if name := actionName(t.ActionName, t.Action); name != "" {
+		label += " / " + name
+	}
+	return label
+}
+
+func actionName(explicit string, action Action) string {
+	if action == nil {
+		return explicit
+	}
+	if name := funcName(explicit, action); name != "" {
+		return name
+	}
+	return "action"
+}
+
+var anonymousFunc = regexp.MustCompile(`^func\d+$`)
+
+// funcName prefers the explicit label, then the declared name of f; it
+// returns "" for closures, whose generated names mean nothing to a reader
+func funcName(explicit string, f any) string {
+	if explicit != "" {
+		return explicit
+	}
+	fn := runtime.FuncForPC(reflect.ValueOf(f).Pointer())
+	if fn == nil {
+		return ""
+	}
+	name := strings.TrimSuffix(fn.Name(), "-fm")
+	name = name[strings.LastIndex(name, ".")+1:]
+	if anonymousFunc.MatchString(name) {
+		return ""
+	}
+	return name
+}
+
+var nonIdentifier = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+func clusterID(state State) string {
+	return "cluster_" + string(state)
+}
+
+func dotID(name string) string {
+	if name == "" {
+		return "fsm"
+	}
+	return dotQuote(name)
+}
+
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
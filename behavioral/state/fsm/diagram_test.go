@@ -0,0 +1,81 @@
+package fsm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDOTClipsOnlyEdgesLeavingClusters(t *testing.T) {
+	def, err := NewDefinition(Spec{
+		Initial: "idle",
+		States: []StateSpec{
+			{Name: "idle"},
+			{Name: "busy"},
+			{Name: "loading", Parent: "busy"},
+			{Name: "saving", Parent: "busy"},
+		},
+		Transitions: []Transition{
+			{From: "idle", Event: "start", To: "busy"},
+			{From: "busy", Event: "cancel", To: "idle"},
+			{From: "busy", Event: "restart", To: "busy"},
+			{From: "busy", Event: "save", To: "saving"},
+			{From: "saving", Event: "reload", To: "busy"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dot := def.DOT("test")
+
+	for _, want := range []string{
+		`"idle" -> "loading" [label="start", lhead="cluster_busy"];`,
+		`"loading" -> "idle" [label="cancel", ltail="cluster_busy"];`,
+		`"loading" -> "loading" [label="restart"];`,
+		`"loading" -> "saving" [label="save"];`,
+		`"saving" -> "loading" [label="reload"];`,
+	} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("DOT output lacks %s:\n%s", want, dot)
+		}
+	}
+}
+
+func TestMermaidIDsAreUnique(t *testing.T) {
+	def, err := NewDefinition(Spec{
+		Initial: "a-b",
+		States: []StateSpec{
+			{Name: "a-b"},
+			{Name: "a_b"},
+			{Name: "a b"},
+			{Name: "a_b_2"},
+		},
+		Transitions: []Transition{
+			{From: "a-b", Event: "go", To: "a_b"},
+			{From: "a_b", Event: "go", To: "a b"},
+			{From: "a b", Event: "go", To: "a_b_2"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := def.mermaidIDs()
+	want := map[State]string{"a_b": "a_b", "a_b_2": "a_b_2", "a-b": "a_b_3", "a b": "a_b_4"}
+	for state, id := range want {
+		if ids[state] != id {
+			t.Errorf("ID of %q = %q, want %q", state, ids[state], id)
+		}
+	}
+
+	mermaid := def.Mermaid()
+	for _, want := range []string{
+		`state "a-b" as a_b_3`,
+		`state "a b" as a_b_4`,
+		"a_b_3 --> a_b : go",
+		"a_b --> a_b_4 : go",
+		"a_b_4 --> a_b_2 : go",
+	} {
+		if !strings.Contains(mermaid, want) {
+			t.Errorf("Mermaid output lacks %q:\n%s", want, mermaid)
+		}
+	}
+}
@@ -0,0 +1,611 @@
+// Package fsm is a small finite state machine engine. States and the events
+// that move between them are declared up front in a Definition; a Machine is
+// one running instance of it. Guards can veto a transition, entry and exit
+// actions run as states change, and firing an event the current state does
+// not handle returns a typed error instead of silently doing nothing.
+//
+// States may be nested. A composite state is active together with one of its
+// children (or, for a parallel state, with all of them, each child being an
+// orthogonal region). Events the active leaf does not handle bubble up to its
+// ancestors, and history lets a composite state resume the sub-state it was
+// in when it was last left.
+package fsm
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// State names a state of the machine
+type State string
+
+// Event names something that can happen to the machine
+type Event string
+
+// root is the implicit parent of every top-level state
+const root State = ""
+
+// Step describes the transition being taken; it is handed to guards and
+// actions
+type Step struct {
+	From  State `json:"from"`
+	Event Event `json:"event"`
+	To    State `json:"to"`
+}
+
+// Guard vetoes a transition by returning an error explaining why
+type Guard func(step Step) error
+
+// Action runs as part of a transition
+type Action func(step Step)
+
+// History decides which child a composite state enters when it is re-entered
+type History int
+
+const (
+	// NoHistory always enters the declared Initial child
+	NoHistory History = iota
+	// ShallowHistory re-enters the child that was active when the state was
+	// last exited; that child itself starts from its own initial sub-state
+	ShallowHistory
+	// DeepHistory restores the whole nested configuration below the state
+	DeepHistory
+)
+
+// StateSpec declares a state and what happens when entering or leaving it
+type StateSpec struct {
+	Name State
+	// Parent nests this state inside another; empty means top level
+	Parent State
+	// Initial is the child a composite state enters by default; it defaults
+	// to the first declared child
+	Initial State
+	History History
+	// Parallel makes every child an orthogonal region, all active at once
+	Parallel bool
+	OnEnter  Action
+	OnExit   Action
+	// OnEnterName and OnExitName label the actions in diagrams; when empty
+	// the function name is used if it has one
+	OnEnterName string
+	OnExitName  string
+}
+
+// Transition declares that Event moves the machine from From to To. From may
+// be a composite state, in which case the transition applies to all of its
+// descendants that do not handle Event themselves. When several transitions
+// share From and Event, the first one whose Guard allows it is taken. Action
+// runs after the exited states' OnExit and before the entered states' OnEnter.
+type Transition struct {
+	From   State
+	Event  Event
+	To     State
+	Guard  Guard
+	Action Action
+	// GuardName and ActionName label the guard and action in diagrams; when
+	// empty the function name is used if it has one
+	GuardName  string
+	ActionName string
+}
+
+// Definition is the validated, immutable description of a machine
+type Definition struct {
+	name        string
+	version     int
+	migrations  map[int]Migration
+	initial     State
+	states      map[State]StateSpec
+	order       []State
+	children    map[State][]State
+	transitions map[State]map[Event][]Transition
+	declared    []Transition
+}
+
+// Spec is the input to NewDefinition
+type Spec struct {
+	// Name and Version identify the definition in snapshots. Bump Version
+	// whenever states are renamed, moved or removed.
+	Name    string
+	Version int
+	// Migrations upgrade snapshots taken with older versions: Migrations[v]
+	// turns a version v snapshot into a version v+1 one
+	Migrations  map[int]Migration
+	Initial     State
+	States      []StateSpec
+	Transitions []Transition
+}
+
+// NewDefinition checks spec for unknown or duplicate states, broken nesting
+// and bad initial children, and returns the definition machines are created
+// from
+func NewDefinition(spec Spec) (*Definition, error) {
+	def := &Definition{
+		name:        spec.Name,
+		version:     spec.Version,
+		migrations:  spec.Migrations,
+		initial:     spec.Initial,
+		states:      make(map[State]StateSpec),
+		children:    make(map[State][]State),
+		transitions: make(map[State]map[Event][]Transition),
+	}
+
+	var errs []error
+	for _, state := range spec.States {
+		if state.Name == root {
+			errs = append(errs, errors.New("state with empty name"))
+			continue
+		}
+		if _, ok := def.states[state.Name]; ok {
+			errs = append(errs, fmt.Errorf("state %q declared twice", state.Name))
+			continue
+		}
+		def.states[state.Name] = state
+		def.order = append(def.order, state.Name)
+	}
+	for _, name := range def.order {
+		parent := def.states[name].Parent
+		if _, ok := def.states[parent]; parent != root && !ok {
+			errs = append(errs, fmt.Errorf("state %q has undeclared parent %q", name, parent))
+			continue
+		}
+		def.children[parent] = append(def.children[parent], name)
+	}
+	for _, name := range def.order {
+		if def.nestedIn(name, name) {
+			errs = append(errs, fmt.Errorf("state %q is nested inside itself", name))
+		}
+	}
+	for _, name := range def.order {
+		spec := def.states[name]
+		if len(def.children[name]) == 0 {
+			if spec.Initial != root || spec.History != NoHistory || spec.Parallel {
+				errs = append(errs, fmt.Errorf("state %q has no children but declares Initial, History or Parallel", name))
+			}
+			continue
+		}
+		if spec.Parallel && spec.Initial != root {
+			errs = append(errs, fmt.Errorf("parallel state %q cannot declare Initial", name))
+		}
+		if spec.Initial != root && def.parent(spec.Initial) != name {
+			errs = append(errs, fmt.Errorf("initial state %q of %q is not its child", spec.Initial, name))
+		}
+	}
+	if _, ok := def.states[spec.Initial]; !ok {
+		errs = append(errs, fmt.Errorf("initial state %q is not declared", spec.Initial))
+	}
+	for _, t := range spec.Transitions {
+		if _, ok := def.states[t.From]; !ok {
+			errs = append(errs, fmt.Errorf("transition %q from undeclared state %q", t.Event, t.From))
+			continue
+		}
+		if _, ok := def.states[t.To]; !ok {
+			errs = append(errs, fmt.Errorf("transition %q to undeclared state %q", t.Event, t.To))
+			continue
+		}
+		if def.transitions[t.From] == nil {
+			def.transitions[t.From] = make(map[Event][]Transition)
+		}
+		def.transitions[t.From][t.Event] = append(def.transitions[t.From][t.Event], t)
+		def.declared = append(def.declared, t)
+	}
+	for from := range spec.Migrations {
+		if from < 0 || from >= spec.Version {
+			errs = append(errs, fmt.Errorf("migration from version %d is not older than version %d", from, spec.Version))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return def, nil
+}
+
+// Name returns the name snapshots of the definition's machines carry
+func (d *Definition) Name() string {
+	return d.name
+}
+
+// Version returns the version snapshots of the definition's machines carry
+func (d *Definition) Version() int {
+	return d.version
+}
+
+// Initial returns the state new machines start in
+func (d *Definition) Initial() State {
+	return d.initial
+}
+
+// States returns the declared states in declaration order
+func (d *Definition) States() []State {
+	return append([]State(nil), d.order...)
+}
+
+// Events returns the events state handles itself, not counting its ancestors
+func (d *Definition) Events(state State) []Event {
+	var events []Event
+	for event := range d.transitions[state] {
+		events = append(events, event)
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i] < events[j] })
+	return events
+}
+
+// Transitions returns every transition in declaration order
+func (d *Definition) Transitions() []Transition {
+	return append([]Transition(nil), d.declared...)
+}
+
+// Spec returns how state was declared
+func (d *Definition) Spec(state State) (StateSpec, bool) {
+	spec, ok := d.states[state]
+	return spec, ok
+}
+
+// Parent returns the state state is nested in, or "" for top-level states
+func (d *Definition) Parent(state State) State {
+	return d.parent(state)
+}
+
+// Children returns the states nested directly inside state; pass "" for the
+// top-level states
+func (d *Definition) Children(state State) []State {
+	return append([]State(nil), d.children[state]...)
+}
+
+func (d *Definition) parent(state State) State {
+	return d.states[state].Parent
+}
+
+func (d *Definition) compound(state State) bool {
+	return len(d.children[state]) > 0
+}
+
+func (d *Definition) parallel(state State) bool {
+	return d.states[state].Parallel
+}
+
+func (d *Definition) initialChild(state State) State {
+	if initial := d.states[state].Initial; initial != root {
+		return initial
+	}
+	return d.children[state][0]
+}
+
+// nestedIn reports whether state is a proper descendant of ancestor
+func (d *Definition) nestedIn(state, ancestor State) bool {
+	seen := map[State]bool{}
+	for s := d.parent(state); ; s = d.parent(s) {
+		if s == ancestor {
+			return true
+		}
+		if s == root || seen[s] {
+			return false
+		}
+		seen[s] = true
+	}
+}
+
+// path returns the states from below ancestor down to and including state
+func (d *Definition) path(ancestor, state State) []State {
+	var path []State
+	for s := state; s != ancestor; s = d.parent(s) {
+		path = append([]State{s}, path...)
+	}
+	return path
+}
+
+// InvalidTransitionError is returned when neither the active states nor their
+// ancestors have a transition for the event
+type InvalidTransitionError struct {
+	State State
+	Event Event
+}
+
+func (e *InvalidTransitionError) Error() string {
+	return fmt.Sprintf("fsm: event %q is not allowed in state %q", e.Event, e.State)
+}
+
+// GuardRejectedError is returned when every candidate transition was vetoed
+type GuardRejectedError struct {
+	Step   Step
+	Reason error
+}
+
+func (e *GuardRejectedError) Error() string {
+	return fmt.Sprintf("fsm: transition %s -(%s)-> %s rejected: %v", e.Step.From, e.Step.Event, e.Step.To, e.Reason)
+}
+
+func (e *GuardRejectedError) Unwrap() error {
+	return e.Reason
+}
+
+// Machine is a running instance of a Definition. It is safe for concurrent
+// use; guards and actions run while the machine is locked, so they must not
+// call Fire on the same machine.
+type Machine struct {
+	def *Definition
+
+	mu          sync.Mutex
+	active      map[State]bool
+	activeChild map[State]State // for the root and non-parallel composites
+	lastChild   map[State]State // history: child active when last exited
+	change      *Change         // the transition being taken, if any
+
+	listenersMu sync.Mutex
+	listeners   []*listener
+}
+
+// NewMachine starts a machine in the definition's initial state, entering
+// its ancestors and default sub-states and running their entry actions
+func (d *Definition) NewMachine() *Machine {
+	m := &Machine{
+		def:         d,
+		active:      make(map[State]bool),
+		activeChild: make(map[State]State),
+		lastChild:   make(map[State]State),
+	}
+	m.enterPath(root, d.initial, Step{To: d.initial})
+	return m
+}
+
+// Definition returns the definition the machine runs
+func (m *Machine) Definition() *Definition {
+	return m.def
+}
+
+// Current returns the innermost active state. With parallel regions it is
+// the leaf of the first region; use Active for the full configuration.
+func (m *Machine) Current() State {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.leaves()[0]
+}
+
+// Active returns every active state, parents before their children
+func (m *Machine) Active() []State {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var states []State
+	m.walk(root, func(s State) { states = append(states, s) })
+	return states
+}
+
+// Leaves returns the innermost active state of every active region
+func (m *Machine) Leaves() []State {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.leaves()
+}
+
+// In reports whether state is active, either as a leaf or as an ancestor of one
+func (m *Machine) In(state State) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.active[state]
+}
+
+// Can reports whether event would currently trigger a transition
+func (m *Machine) Can(event Event) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	selected, _ := m.selectTransitions(event)
+	return len(selected) > 0
+}
+
+// Fire handles event. Every active leaf looks for a transition on itself and
+// then on its ancestors; each source found handles the event once. For each
+// transition the machine exits up to the closest common ancestor of source
+// and target, runs the transition action, and enters down to the target. If
+// nothing handles the event the machine stays where it was. Listeners are
+// told about the transitions taken once Fire has finished them.
+func (m *Machine) Fire(event Event) error {
+	changes, err := m.fire(event)
+	m.notify(changes)
+	return err
+}
+
+func (m *Machine) fire(event Event) ([]Change, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	selected, err := m.selectTransitions(event)
+	if len(selected) == 0 {
+		return nil, err
+	}
+	var changes []Change
+	for _, s := range selected {
+		if !m.active[s.source] {
+			continue // exited by an earlier transition of the same event
+		}
+		changes = append(changes, m.take(s.source, s.transition, event))
+	}
+	return changes, nil
+}
+
+type selection struct {
+	source     State
+	transition Transition
+}
+
+func (m *Machine) selectTransitions(event Event) ([]selection, error) {
+	leaves := m.leaves()
+	var selected []selection
+	var rejected *GuardRejectedError
+	handled := map[State]bool{}
+	for _, leaf := range leaves {
+		for s := leaf; s != root; s = m.def.parent(s) {
+			t, err := m.pick(s, event)
+			if err == nil {
+				if !handled[s] {
+					handled[s] = true
+					selected = append(selected, selection{source: s, transition: t})
+				}
+				break
+			}
+			var guardErr *GuardRejectedError
+			if errors.As(err, &guardErr) && rejected == nil {
+				rejected = guardErr
+			}
+		}
+	}
+	if len(selected) > 0 {
+		return selected, nil
+	}
+	if rejected != nil {
+		return nil, rejected
+	}
+	return nil, &InvalidTransitionError{State: leaves[0], Event: event}
+}
+
+func (m *Machine) pick(source State, event Event) (Transition, error) {
+	candidates := m.def.transitions[source][event]
+	if len(candidates) == 0 {
+		return Transition{}, &InvalidTransitionError{State: source, Event: event}
+	}
+
+	var rejected *GuardRejectedError
+	for _, t := range candidates {
+		if t.Guard == nil {
+			return t, nil
+		}
+		step := Step{From: source, Event: event, To: t.To}
+		if err := t.Guard(step); err != nil {
+			if rejected == nil {
+				rejected = &GuardRejectedError{Step: step, Reason: err}
+			}
+			continue
+		}
+		return t, nil
+	}
+	return Transition{}, rejected
+}
+
+func (m *Machine) take(source State, t Transition, event Event) Change {
+	step := Step{From: source, Event: event, To: t.To}
+	change := Change{Step: step, Time: time.Now()}
+	m.change = &change
+	defer func() { m.change = nil }()
+
+	// The transition's domain is the closest state that strictly contains
+	// both source and target. A parallel state only qualifies if both sit in
+	// the same region; crossing regions leaves the parallel state altogether.
+	domain := m.def.parent(source)
+	for domain != root {
+		if m.def.nestedIn(t.To, domain) &&
+			(!m.def.parallel(domain) || m.def.path(domain, source)[0] == m.def.path(domain, t.To)[0]) {
+			break
+		}
+		domain = m.def.parent(domain)
+	}
+
+	m.exit(m.def.path(domain, source)[0], step)
+	if t.Action != nil {
+		t.Action(step)
+	}
+	m.enterPath(domain, t.To, step)
+	return change
+}
+
+// enterPath enters every state from below domain down to target, then the
+// target's default sub-states. Sibling regions of parallel states on the way
+// are entered by default too.
+func (m *Machine) enterPath(domain, target State, step Step) {
+	path := m.def.path(domain, target)
+	for i, s := range path {
+		m.enter(s, step)
+		if i+1 < len(path) && m.def.parallel(s) {
+			for _, region := range m.def.children[s] {
+				if region != path[i+1] {
+					m.enter(region, step)
+					m.enterDefault(region, false, step)
+				}
+			}
+		}
+	}
+	m.enterDefault(target, false, step)
+}
+
+// enterDefault enters the sub-states of an already entered state, honouring
+// history. deep is set while restoring a deep history configuration.
+func (m *Machine) enterDefault(state State, deep bool, step Step) {
+	if !m.def.compound(state) {
+		return
+	}
+	spec := m.def.states[state]
+	deep = deep || spec.History == DeepHistory
+	if spec.Parallel {
+		for _, region := range m.def.children[state] {
+			m.enter(region, step)
+			m.enterDefault(region, deep, step)
+		}
+		return
+	}
+
+	child := m.def.initialChild(state)
+	if last, ok := m.lastChild[state]; ok && (deep || spec.History == ShallowHistory) {
+		child = last
+	}
+	m.enter(child, step)
+	m.enterDefault(child, deep, step)
+}
+
+func (m *Machine) enter(state State, step Step) {
+	m.active[state] = true
+	if m.change != nil {
+		m.change.Entered = append(m.change.Entered, state)
+	}
+	if parent := m.def.parent(state); parent == root || !m.def.parallel(parent) {
+		m.activeChild[parent] = state
+	}
+	if enter := m.def.states[state].OnEnter; enter != nil {
+		enter(step)
+	}
+}
+
+// exit leaves state and its active descendants, innermost first, recording
+// history on the way out
+func (m *Machine) exit(state State, step Step) {
+	if m.def.parallel(state) {
+		regions := m.def.children[state]
+		for i := len(regions) - 1; i >= 0; i-- {
+			if m.active[regions[i]] {
+				m.exit(regions[i], step)
+			}
+		}
+	} else if child, ok := m.activeChild[state]; ok {
+		m.exit(child, step)
+		m.lastChild[state] = child
+		delete(m.activeChild, state)
+	}
+
+	if exit := m.def.states[state].OnExit; exit != nil {
+		exit(step)
+	}
+	delete(m.active, state)
+	if m.change != nil {
+		m.change.Exited = append(m.change.Exited, state)
+	}
+}
+
+// walk visits the active states below parent, parents first, regions in
+// declaration order
+func (m *Machine) walk(parent State, visit func(State)) {
+	for _, child := range m.def.children[parent] {
+		if m.active[child] {
+			visit(child)
+			m.walk(child, visit)
+		}
+	}
+}
+
+func (m *Machine) leaves() []State {
+	var leaves []State
+	m.walk(root, func(s State) {
+		if !m.def.compound(s) {
+			leaves = append(leaves, s)
+		}
+	})
+	return leaves
+}
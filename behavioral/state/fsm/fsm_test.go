@@ -0,0 +1,553 @@
+package fsm
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// tracer records entry actions, exit actions and transition actions in the
+// order they run
+type tracer struct {
+	calls []string
+}
+
+// trace fills in the actions of every state and transition of spec
+func (tr *tracer) trace(spec Spec) Spec {
+	spec.States = slices.Clone(spec.States)
+	for i, s := range spec.States {
+		name := s.Name
+		spec.States[i].OnEnter = func(Step) { tr.calls = append(tr.calls, "enter "+string(name)) }
+		spec.States[i].OnExit = func(Step) { tr.calls = append(tr.calls, "exit "+string(name)) }
+	}
+	spec.Transitions = slices.Clone(spec.Transitions)
+	for i := range spec.Transitions {
+		spec.Transitions[i].Action = func(step Step) {
+			tr.calls = append(tr.calls, fmt.Sprintf("action %s->%s", step.From, step.To))
+		}
+	}
+	return spec
+}
+
+// take returns the calls recorded since the last take
+func (tr *tracer) take() []string {
+	calls := tr.calls
+	tr.calls = nil
+	return calls
+}
+
+func mustDefine(t *testing.T, spec Spec) *Definition {
+	t.Helper()
+	def, err := NewDefinition(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return def
+}
+
+var errNoKey = errors.New("no key")
+
+// door is closed, open or locked; locking needs the key
+func door(hasKey *bool) Spec {
+	return Spec{
+		Name:    "door",
+		Initial: "closed",
+		States:  []StateSpec{{Name: "closed"}, {Name: "open"}, {Name: "locked"}},
+		Transitions: []Transition{
+			{From: "closed", Event: "open", To: "open"},
+			{From: "open", Event: "close", To: "closed"},
+			{From: "closed", Event: "lock", To: "locked", Guard: func(Step) error {
+				if !*hasKey {
+					return errNoKey
+				}
+				return nil
+			}},
+			{From: "locked", Event: "unlock", To: "closed"},
+			{From: "closed", Event: "knock", To: "closed"},
+		},
+	}
+}
+
+func TestFire(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		hasKey  bool
+		events  []Event
+		want    State
+		wantErr any // nil, *InvalidTransitionError or *GuardRejectedError
+	}{
+		{"open", false, []Event{"open"}, "open", nil},
+		{"open and close", false, []Event{"open", "close"}, "closed", nil},
+		{"lock with the key", true, []Event{"lock"}, "locked", nil},
+		{"lock and unlock", true, []Event{"lock", "unlock"}, "closed", nil},
+		{"lock without the key", false, []Event{"lock"}, "closed", &GuardRejectedError{}},
+		{"open twice", false, []Event{"open", "open"}, "open", &InvalidTransitionError{}},
+		{"lock an open door", true, []Event{"open", "lock"}, "open", &InvalidTransitionError{}},
+		{"open a locked door", true, []Event{"lock", "open"}, "locked", &InvalidTransitionError{}},
+		{"undeclared event", false, []Event{"kick"}, "closed", &InvalidTransitionError{}},
+		{"self transition", false, []Event{"knock"}, "closed", nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			hasKey := tc.hasKey
+			machine := mustDefine(t, door(&hasKey)).NewMachine()
+			var err error
+			for _, event := range tc.events {
+				if err = machine.Fire(event); err != nil {
+					break
+				}
+			}
+
+			if got := machine.Current(); got != tc.want {
+				t.Errorf("Current() = %q, want %q", got, tc.want)
+			}
+			switch tc.wantErr.(type) {
+			case nil:
+				if err != nil {
+					t.Errorf("Fire: %v", err)
+				}
+			case *InvalidTransitionError:
+				var invalid *InvalidTransitionError
+				if !errors.As(err, &invalid) {
+					t.Errorf("Fire: err = %v, want an *InvalidTransitionError", err)
+				}
+			case *GuardRejectedError:
+				var rejected *GuardRejectedError
+				if !errors.As(err, &rejected) {
+					t.Errorf("Fire: err = %v, want a *GuardRejectedError", err)
+				}
+			}
+		})
+	}
+}
+
+func TestErrorsDescribeTheRefusal(t *testing.T) {
+	hasKey := false
+	machine := mustDefine(t, door(&hasKey)).NewMachine()
+
+	err := machine.Fire("unlock")
+	var invalid *InvalidTransitionError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("Fire(unlock) = %v, want an *InvalidTransitionError", err)
+	}
+	if invalid.State != "closed" || invalid.Event != "unlock" {
+		t.Errorf("InvalidTransitionError = %+v", invalid)
+	}
+	if want := `fsm: event "unlock" is not allowed in state "closed"`; err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err, want)
+	}
+
+	err = machine.Fire("lock")
+	var rejected *GuardRejectedError
+	if !errors.As(err, &rejected) {
+		t.Fatalf("Fire(lock) = %v, want a *GuardRejectedError", err)
+	}
+	if want := (Step{From: "closed", Event: "lock", To: "locked"}); rejected.Step != want {
+		t.Errorf("rejected step = %+v, want %+v", rejected.Step, want)
+	}
+	if !errors.Is(err, errNoKey) {
+		t.Errorf("errors.Is(%v, errNoKey) = false", err)
+	}
+	if want := "fsm: transition closed -(lock)-> locked rejected: no key"; err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err, want)
+	}
+}
+
+func TestFirstAllowedGuardWins(t *testing.T) {
+	errTooSmall, errTooBig := errors.New("too small"), errors.New("too big")
+	amount := 0
+	def := mustDefine(t, Spec{
+		Initial: "pending",
+		States:  []StateSpec{{Name: "pending"}, {Name: "review"}, {Name: "approved"}},
+		Transitions: []Transition{
+			{From: "pending", Event: "submit", To: "approved", Guard: func(Step) error {
+				if amount > 100 {
+					return errTooBig
+				}
+				if amount < 10 {
+					return errTooSmall
+				}
+				return nil
+			}},
+			{From: "pending", Event: "submit", To: "review", Guard: func(Step) error {
+				if amount < 10 {
+					return errTooSmall
+				}
+				return nil
+			}},
+		},
+	})
+
+	for _, tc := range []struct {
+		amount  int
+		want    State
+		wantErr error
+	}{
+		{50, "approved", nil},
+		{500, "review", nil},
+		{5, "pending", errTooSmall},
+	} {
+		amount = tc.amount
+		machine := def.NewMachine()
+		if got := machine.Can("submit"); got != (tc.wantErr == nil) {
+			t.Errorf("amount %d: Can(submit) = %t", tc.amount, got)
+		}
+		err := machine.Fire("submit")
+		if !errors.Is(err, tc.wantErr) || (tc.wantErr == nil) != (err == nil) {
+			t.Errorf("amount %d: Fire(submit) = %v, want %v", tc.amount, err, tc.wantErr)
+		}
+		// Every candidate was vetoed: the first rejection is reported
+		var rejected *GuardRejectedError
+		if errors.As(err, &rejected) && rejected.Step.To != "approved" {
+			t.Errorf("amount %d: reported the rejection to %q, want the first candidate's", tc.amount, rejected.Step.To)
+		}
+		if got := machine.Current(); got != tc.want {
+			t.Errorf("amount %d: Current() = %q, want %q", tc.amount, got, tc.want)
+		}
+	}
+}
+
+func TestActionOrder(t *testing.T) {
+	hasKey := true
+	tr := &tracer{}
+	machine := mustDefine(t, tr.trace(door(&hasKey))).NewMachine()
+	if got, want := tr.take(), []string{"enter closed"}; !slices.Equal(got, want) {
+		t.Errorf("NewMachine ran %v, want %v", got, want)
+	}
+
+	for _, tc := range []struct {
+		event Event
+		want  []string
+	}{
+		{"open", []string{"exit closed", "action closed->open", "enter open"}},
+		{"open", nil}, // refused: nothing runs
+		{"close", []string{"exit open", "action open->closed", "enter closed"}},
+		{"knock", []string{"exit closed", "action closed->closed", "enter closed"}},
+		{"lock", []string{"exit closed", "action closed->locked", "enter locked"}},
+	} {
+		machine.Fire(tc.event)
+		if got := tr.take(); !slices.Equal(got, tc.want) {
+			t.Errorf("Fire(%s) ran %v, want %v", tc.event, got, tc.want)
+		}
+	}
+
+	// A vetoed transition runs nothing either
+	hasKey = false
+	machine = mustDefine(t, tr.trace(door(&hasKey))).NewMachine()
+	tr.take()
+	machine.Fire("lock")
+	if got := tr.take(); len(got) > 0 {
+		t.Errorf("a vetoed transition ran %v", got)
+	}
+}
+
+func TestActionsSeeTheStep(t *testing.T) {
+	var steps []Step
+	record := func(step Step) { steps = append(steps, step) }
+	def := mustDefine(t, Spec{
+		Initial: "a",
+		States:  []StateSpec{{Name: "a", OnExit: record}, {Name: "b", OnEnter: record}},
+		Transitions: []Transition{
+			{From: "a", Event: "go", To: "b", Guard: func(step Step) error { record(step); return nil }, Action: record},
+		},
+	})
+	def.NewMachine().Fire("go")
+
+	want := Step{From: "a", Event: "go", To: "b"}
+	if len(steps) != 4 {
+		t.Fatalf("recorded %d steps, want guard, exit, action and entry", len(steps))
+	}
+	for _, step := range steps {
+		if step != want {
+			t.Errorf("step = %+v, want %+v", step, want)
+		}
+	}
+}
+
+func TestNewDefinitionRejectsBadSpecs(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		spec Spec
+		want string
+	}{
+		{"undeclared initial", Spec{Initial: "nowhere", States: []StateSpec{{Name: "a"}}},
+			`initial state "nowhere" is not declared`},
+		{"empty state name", Spec{Initial: "a", States: []StateSpec{{Name: "a"}, {}}},
+			"state with empty name"},
+		{"duplicate state", Spec{Initial: "a", States: []StateSpec{{Name: "a"}, {Name: "a"}}},
+			`state "a" declared twice`},
+		{"undeclared parent", Spec{Initial: "a", States: []StateSpec{{Name: "a", Parent: "p"}}},
+			`state "a" has undeclared parent "p"`},
+		{"nested in itself", Spec{Initial: "a", States: []StateSpec{{Name: "a", Parent: "b"}, {Name: "b", Parent: "a"}}},
+			`state "a" is nested inside itself`},
+		{"history on a leaf", Spec{Initial: "a", States: []StateSpec{{Name: "a", History: DeepHistory}}},
+			`state "a" has no children but declares Initial, History or Parallel`},
+		{"initial of a parallel state", Spec{Initial: "p", States: []StateSpec{
+			{Name: "p", Parallel: true, Initial: "r"}, {Name: "r", Parent: "p"}}},
+			`parallel state "p" cannot declare Initial`},
+		{"initial not a child", Spec{Initial: "p", States: []StateSpec{
+			{Name: "p", Initial: "a"}, {Name: "a"}, {Name: "c", Parent: "p"}}},
+			`initial state "a" of "p" is not its child`},
+		{"transition from undeclared", Spec{Initial: "a", States: []StateSpec{{Name: "a"}},
+			Transitions: []Transition{{From: "x", Event: "go", To: "a"}}},
+			`transition "go" from undeclared state "x"`},
+		{"transition to undeclared", Spec{Initial: "a", States: []StateSpec{{Name: "a"}},
+			Transitions: []Transition{{From: "a", Event: "go", To: "x"}}},
+			`transition "go" to undeclared state "x"`},
+		{"migration not older", Spec{Initial: "a", Version: 2, States: []StateSpec{{Name: "a"}},
+			Migrations: map[int]Migration{2: nil}},
+			"migration from version 2 is not older than version 2"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewDefinition(tc.spec)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("NewDefinition: err = %v, want one containing %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestDefinitionAccessors(t *testing.T) {
+	hasKey := false
+	def := mustDefine(t, door(&hasKey))
+	if got := def.States(); !slices.Equal(got, []State{"closed", "open", "locked"}) {
+		t.Errorf("States() = %v", got)
+	}
+	if got := def.Events("closed"); !slices.Equal(got, []Event{"knock", "lock", "open"}) {
+		t.Errorf("Events(closed) = %v", got)
+	}
+	if got := len(def.Transitions()); got != 5 {
+		t.Errorf("len(Transitions()) = %d, want 5", got)
+	}
+	if def.Name() != "door" || def.Initial() != "closed" {
+		t.Errorf("Name() = %q, Initial() = %q", def.Name(), def.Initial())
+	}
+}
+
+// expectFire fires event and checks the active configuration and the
+// actions run on the way
+func expectFire(t *testing.T, m *Machine, tr *tracer, event Event, active []State, calls ...string) {
+	t.Helper()
+	if err := m.Fire(event); err != nil {
+		t.Errorf("Fire(%s): %v", event, err)
+	}
+	if got := m.Active(); !slices.Equal(got, active) {
+		t.Errorf("after %s: Active() = %v, want %v", event, got, active)
+	}
+	if got := tr.take(); !slices.Equal(got, calls) {
+		t.Errorf("after %s: ran\n\t%v\nwant\n\t%v", event, got, calls)
+	}
+}
+
+// player nests a playing state with two speeds inside on
+func player(history History) Spec {
+	return Spec{
+		Name:    "player",
+		Initial: "off",
+		States: []StateSpec{
+			{Name: "off"},
+			{Name: "on", History: history},
+			{Name: "stopped", Parent: "on"},
+			{Name: "playing", Parent: "on"},
+			{Name: "normal", Parent: "playing"},
+			{Name: "fast", Parent: "playing"},
+		},
+		Transitions: []Transition{
+			{From: "off", Event: "power", To: "on"},
+			{From: "on", Event: "power", To: "off"},
+			{From: "stopped", Event: "play", To: "playing"},
+			{From: "playing", Event: "stop", To: "stopped"},
+			{From: "normal", Event: "ff", To: "fast"},
+			{From: "fast", Event: "ff", To: "normal"},
+			// playing handles menu itself; elsewhere in on it turns off
+			{From: "playing", Event: "menu", To: "stopped"},
+			{From: "on", Event: "menu", To: "off"},
+		},
+	}
+}
+
+func TestNestedStatesAndBubbling(t *testing.T) {
+	tr := &tracer{}
+	m := mustDefine(t, tr.trace(player(NoHistory))).NewMachine()
+	tr.take()
+
+	expectFire(t, m, tr, "power", []State{"on", "stopped"},
+		"exit off", "action off->on", "enter on", "enter stopped")
+	expectFire(t, m, tr, "play", []State{"on", "playing", "normal"},
+		"exit stopped", "action stopped->playing", "enter playing", "enter normal")
+	// Within playing, only the leaf changes
+	expectFire(t, m, tr, "ff", []State{"on", "playing", "fast"},
+		"exit normal", "action normal->fast", "enter fast")
+	// fast does not handle menu; playing, the closest ancestor that does, wins over on
+	expectFire(t, m, tr, "menu", []State{"on", "stopped"},
+		"exit fast", "exit playing", "action playing->stopped", "enter stopped")
+	expectFire(t, m, tr, "menu", []State{"off"},
+		"exit stopped", "exit on", "action on->off", "enter off")
+
+	m.Fire("power")
+	m.Fire("play")
+	tr.take()
+	// Leaving from deep inside exits innermost first
+	expectFire(t, m, tr, "power", []State{"off"},
+		"exit normal", "exit playing", "exit on", "action on->off", "enter off")
+
+	if m.In("on") || !m.In("off") {
+		t.Errorf("In(on) = %t, In(off) = %t", m.In("on"), m.In("off"))
+	}
+	err := m.Fire("ff")
+	var invalid *InvalidTransitionError
+	if !errors.As(err, &invalid) || invalid.State != "off" {
+		t.Errorf("Fire(ff) while off = %v, want an *InvalidTransitionError in off", err)
+	}
+}
+
+func TestInitialCompositeState(t *testing.T) {
+	spec := player(NoHistory)
+	spec.Initial = "playing"
+	tr := &tracer{}
+	m := mustDefine(t, tr.trace(spec)).NewMachine()
+
+	if got := m.Active(); !slices.Equal(got, []State{"on", "playing", "normal"}) {
+		t.Errorf("Active() = %v", got)
+	}
+	if got, want := tr.take(), []string{"enter on", "enter playing", "enter normal"}; !slices.Equal(got, want) {
+		t.Errorf("NewMachine ran %v, want %v", got, want)
+	}
+	if m.Current() != "normal" {
+		t.Errorf("Current() = %q, want normal", m.Current())
+	}
+}
+
+func TestHistory(t *testing.T) {
+	for _, tc := range []struct {
+		history History
+		want    []State
+	}{
+		{NoHistory, []State{"on", "stopped"}},
+		// on resumes playing, but playing starts over at normal
+		{ShallowHistory, []State{"on", "playing", "normal"}},
+		{DeepHistory, []State{"on", "playing", "fast"}},
+	} {
+		t.Run(fmt.Sprint(tc.history), func(t *testing.T) {
+			m := mustDefine(t, player(tc.history)).NewMachine()
+			for _, event := range []Event{"power", "play", "ff", "power", "power"} {
+				if err := m.Fire(event); err != nil {
+					t.Fatalf("Fire(%s): %v", event, err)
+				}
+			}
+			if got := m.Active(); !slices.Equal(got, tc.want) {
+				t.Errorf("Active() after turning back on = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+// editor has two orthogonal regions, bold and italic, inside editing
+func editor(history History) Spec {
+	return Spec{
+		Name:    "editor",
+		Initial: "idle",
+		States: []StateSpec{
+			{Name: "idle"},
+			{Name: "editing", Parallel: true, History: history},
+			{Name: "bold", Parent: "editing"},
+			{Name: "boldOff", Parent: "bold"},
+			{Name: "boldOn", Parent: "bold"},
+			{Name: "italic", Parent: "editing"},
+			{Name: "italicOff", Parent: "italic"},
+			{Name: "italicOn", Parent: "italic"},
+		},
+		Transitions: []Transition{
+			{From: "idle", Event: "edit", To: "editing"},
+			{From: "idle", Event: "editItalic", To: "italicOn"},
+			{From: "editing", Event: "done", To: "idle"},
+			{From: "boldOff", Event: "b", To: "boldOn"},
+			{From: "boldOn", Event: "b", To: "boldOff"},
+			{From: "italicOff", Event: "i", To: "italicOn"},
+			{From: "italicOn", Event: "i", To: "italicOff"},
+			{From: "boldOn", Event: "reset", To: "boldOff"},
+			{From: "italicOn", Event: "reset", To: "italicOff"},
+			{From: "boldOn", Event: "swap", To: "italicOn"},
+		},
+	}
+}
+
+func TestParallelRegions(t *testing.T) {
+	tr := &tracer{}
+	m := mustDefine(t, tr.trace(editor(NoHistory))).NewMachine()
+	tr.take()
+
+	plain := []State{"editing", "bold", "boldOff", "italic", "italicOff"}
+	expectFire(t, m, tr, "edit", plain,
+		"exit idle", "action idle->editing",
+		"enter editing", "enter bold", "enter boldOff", "enter italic", "enter italicOff")
+	if got := m.Leaves(); !slices.Equal(got, []State{"boldOff", "italicOff"}) {
+		t.Errorf("Leaves() = %v", got)
+	}
+
+	// Each region handles its own events
+	expectFire(t, m, tr, "b", []State{"editing", "bold", "boldOn", "italic", "italicOff"},
+		"exit boldOff", "action boldOff->boldOn", "enter boldOn")
+	expectFire(t, m, tr, "i", []State{"editing", "bold", "boldOn", "italic", "italicOn"},
+		"exit italicOff", "action italicOff->italicOn", "enter italicOn")
+
+	// An event both regions handle moves both, in region order
+	expectFire(t, m, tr, "reset", plain,
+		"exit boldOn", "action boldOn->boldOff", "enter boldOff",
+		"exit italicOn", "action italicOn->italicOff", "enter italicOff")
+	var invalid *InvalidTransitionError
+	if err := m.Fire("reset"); !errors.As(err, &invalid) || invalid.State != "boldOff" {
+		t.Errorf("Fire(reset) with nothing set = %v, want an *InvalidTransitionError in boldOff", err)
+	}
+
+	// An ancestor both leaves bubble up to handles the event once, exiting
+	// the regions last to first
+	expectFire(t, m, tr, "done", []State{"idle"},
+		"exit italicOff", "exit italic", "exit boldOff", "exit bold", "exit editing",
+		"action editing->idle", "enter idle")
+
+	// Entering one region directly enters the others by default
+	expectFire(t, m, tr, "editItalic", []State{"editing", "bold", "boldOff", "italic", "italicOn"},
+		"exit idle", "action idle->italicOn",
+		"enter editing", "enter bold", "enter boldOff", "enter italic", "enter italicOn")
+}
+
+func TestCrossRegionTransitionLeavesTheParallelState(t *testing.T) {
+	tr := &tracer{}
+	m := mustDefine(t, tr.trace(editor(NoHistory))).NewMachine()
+	m.Fire("edit")
+	m.Fire("b")
+	m.Fire("i")
+	m.Fire("i")
+	tr.take()
+
+	// The regions share no ancestor below editing, so editing is exited and
+	// entered again, with bold back at its default
+	expectFire(t, m, tr, "swap", []State{"editing", "bold", "boldOff", "italic", "italicOn"},
+		"exit italicOff", "exit italic", "exit boldOn", "exit bold", "exit editing",
+		"action boldOn->italicOn",
+		"enter editing", "enter bold", "enter boldOff", "enter italic", "enter italicOn")
+}
+
+func TestDeepHistoryOfParallelState(t *testing.T) {
+	for _, tc := range []struct {
+		history History
+		want    []State
+	}{
+		{NoHistory, []State{"editing", "bold", "boldOff", "italic", "italicOff"}},
+		{DeepHistory, []State{"editing", "bold", "boldOn", "italic", "italicOff"}},
+	} {
+		t.Run(fmt.Sprint(tc.history), func(t *testing.T) {
+			m := mustDefine(t, editor(tc.history)).NewMachine()
+			for _, event := range []Event{"edit", "b", "done", "edit"} {
+				if err := m.Fire(event); err != nil {
+					t.Fatalf("Fire(%s): %v", event, err)
+				}
+			}
+			if got := m.Active(); !slices.Equal(got, tc.want) {
+				t.Errorf("Active() after editing again = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package fsm
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Change records a transition the machine took: its source, event and
+// target, when it happened, and every state exited and entered on the way,
+// innermost first for exits and outermost first for entries
+type Change struct {
+	Step
+	Time    time.Time `json:"time"`
+	Exited  []State   `json:"exited"`
+	Entered []State   `json:"entered"`
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s %s -(%s)-> %s exited %v entered %v",
+		c.Time.Format("15:04:05.000"), c.From, c.Event, c.To, c.Exited, c.Entered)
+}
+
+// Touches reports whether the change exited or entered state
+func (c Change) Touches(state State) bool {
+	return slices.Contains(c.Exited, state) || slices.Contains(c.Entered, state)
+}
+
+// Listener is told about a transition after it has been taken. Listeners run
+// outside the machine lock, so they may query the machine or fire events on
+// it; the changes of concurrent Fire calls may reach them interleaved.
+type Listener func(c Change)
+
+type listener struct {
+	state State // root listens to every transition
+	fn    Listener
+}
+
+// OnTransition registers l for every transition. The returned function
+// removes it again.
+func (m *Machine) OnTransition(l Listener) (remove func()) {
+	return m.listen(root, l)
+}
+
+// OnState registers l for the transitions that exit or enter state,
+// including the ones passing through it on the way to or from its
+// sub-states. The returned function removes it again.
+func (m *Machine) OnState(state State, l Listener) (remove func()) {
+	return m.listen(state, l)
+}
+
+func (m *Machine) listen(state State, fn Listener) func() {
+	l := &listener{state: state, fn: fn}
+	m.listenersMu.Lock()
+	m.listeners = append(m.listeners, l)
+	m.listenersMu.Unlock()
+
+	return func() {
+		m.listenersMu.Lock()
+		defer m.listenersMu.Unlock()
+		m.listeners = slices.DeleteFunc(m.listeners, func(other *listener) bool { return other == l })
+	}
+}
+
+func (m *Machine) notify(changes []Change) {
+	if len(changes) == 0 {
+		return
+	}
+	m.listenersMu.Lock()
+	listeners := slices.Clone(m.listeners)
+	m.listenersMu.Unlock()
+
+	for _, c := range changes {
+		for _, l := range listeners {
+			if l.state == root || c.Touches(l.state) {
+				l.fn(c)
+			}
+		}
+	}
+}
+
+// TransitionLog keeps the most recent transitions of the machines it is
+// registered with, for debugging how a machine got where it is:
+//
+//	log := fsm.NewTransitionLog(100)
+//	machine.OnTransition(log.Record)
+type TransitionLog struct {
+	mu      sync.Mutex
+	entries []Change // ring buffer
+	next    int
+	full    bool
+	dropped int
+}
+
+// NewTransitionLog returns a log holding at most capacity changes; older ones
+// are dropped first
+func NewTransitionLog(capacity int) *TransitionLog {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &TransitionLog{entries: make([]Change, capacity)}
+}
+
+// Record adds c to the log; it is a Listener
+func (l *TransitionLog) Record(c Change) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.full {
+		l.dropped++
+	}
+	l.entries[l.next] = c
+	l.next = (l.next + 1) % len(l.entries)
+	l.full = l.full || l.next == 0
+}
+
+// Entries returns the logged changes, oldest first
+func (l *TransitionLog) Entries() []Change {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.full {
+		return slices.Clone(l.entries[:l.next])
+	}
+	return append(slices.Clone(l.entries[l.next:]), l.entries[:l.next]...)
+}
+
+// Dropped counts the changes pushed out of the log by newer ones
+func (l *TransitionLog) Dropped() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.dropped
+}
+
+// Query selects logged changes; zero fields match everything
+type Query struct {
+	// State matches changes that exited or entered it
+	State State
+	Event Event
+	Since time.Time
+	Until time.Time
+}
+
+func (q Query) matches(c Change) bool {
+	return (q.State == root || c.Touches(q.State)) &&
+		(q.Event == "" || c.Event == q.Event) &&
+		(q.Since.IsZero() || !c.Time.Before(q.Since)) &&
+		(q.Until.IsZero() || c.Time.Before(q.Until))
+}
+
+// Find returns the logged changes matching q, oldest first
+func (l *TransitionLog) Find(q Query) []Change {
+	var found []Change
+	for _, c := range l.Entries() {
+		if q.matches(c) {
+			found = append(found, c)
+		}
+	}
+	return found
+}
+
+// Dump writes the log one change per line, noting how many were dropped
+func (l *TransitionLog) Dump(w io.Writer) error {
+	var b strings.Builder
+	if dropped := l.Dropped(); dropped > 0 {
+		fmt.Fprintf(&b, "(%d older transitions dropped)\n", dropped)
+	}
+	for _, c := range l.Entries() {
+		b.WriteString(c.String())
+		b.WriteByte('\n')
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
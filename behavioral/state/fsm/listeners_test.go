@@ -0,0 +1,236 @@
+package fsm
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+// changes collects what a listener is told, as "from -(event)-> to"
+type changes []string
+
+func (c *changes) listen(change Change) {
+	*c = append(*c, fmt.Sprintf("%s -(%s)-> %s", change.From, change.Event, change.To))
+}
+
+func TestListenersSeeEveryStateTheyTouch(t *testing.T) {
+	m := mustDefine(t, player(NoHistory)).NewMachine()
+	var all []Change
+	var playing, normal, off changes
+	m.OnTransition(func(c Change) {
+		// Listeners run outside the lock and see the machine after the change
+		if !m.In(c.To) {
+			t.Errorf("listener told about %s before the machine is in it", c.To)
+		}
+		all = append(all, c)
+	})
+	m.OnState("playing", playing.listen)
+	m.OnState("normal", normal.listen)
+	m.OnState("off", off.listen)
+
+	for _, event := range []Event{"power", "play", "ff", "play", "power"} {
+		m.Fire(event)
+	}
+
+	for i, want := range []Change{
+		{Step: Step{From: "off", Event: "power", To: "on"}, Exited: []State{"off"}, Entered: []State{"on", "stopped"}},
+		{Step: Step{From: "stopped", Event: "play", To: "playing"}, Exited: []State{"stopped"}, Entered: []State{"playing", "normal"}},
+		{Step: Step{From: "normal", Event: "ff", To: "fast"}, Exited: []State{"normal"}, Entered: []State{"fast"}},
+		// The refused play is not a change
+		{Step: Step{From: "on", Event: "power", To: "off"}, Exited: []State{"fast", "playing", "on"}, Entered: []State{"off"}},
+	} {
+		if i >= len(all) {
+			t.Errorf("missing change %d: %v", i, want.Step)
+			continue
+		}
+		got := all[i]
+		if got.Step != want.Step || !slices.Equal(got.Exited, want.Exited) || !slices.Equal(got.Entered, want.Entered) {
+			t.Errorf("change %d = %v exited %v entered %v, want %v exited %v entered %v",
+				i, got.Step, got.Exited, got.Entered, want.Step, want.Exited, want.Entered)
+		}
+		if got.Time.IsZero() {
+			t.Errorf("change %d has no time", i)
+		}
+	}
+	if len(all) != 4 {
+		t.Errorf("OnTransition got %d changes, want 4", len(all))
+	}
+
+	for name, tc := range map[string]struct {
+		got, want changes
+	}{
+		// Leaving on exits playing too, though the transition is from on
+		"playing": {playing, changes{"stopped -(play)-> playing", "on -(power)-> off"}},
+		"normal":  {normal, changes{"stopped -(play)-> playing", "normal -(ff)-> fast"}},
+		"off":     {off, changes{"off -(power)-> on", "on -(power)-> off"}},
+	} {
+		if !slices.Equal(tc.got, tc.want) {
+			t.Errorf("OnState(%s) got %v, want %v", name, tc.got, tc.want)
+		}
+	}
+}
+
+func TestListenersOfParallelRegions(t *testing.T) {
+	m := mustDefine(t, editor(NoHistory)).NewMachine()
+	var all, bold, italic changes
+	m.OnTransition(all.listen)
+	m.OnState("bold", bold.listen)
+	m.OnState("italicOn", italic.listen)
+
+	for _, event := range []Event{"edit", "b", "i", "reset"} {
+		m.Fire(event)
+	}
+
+	// reset moves both regions: one Fire, two changes, each told only to
+	// the listeners of the states it touched
+	want := changes{"idle -(edit)-> editing", "boldOff -(b)-> boldOn", "italicOff -(i)-> italicOn",
+		"boldOn -(reset)-> boldOff", "italicOn -(reset)-> italicOff"}
+	if !slices.Equal(all, want) {
+		t.Errorf("OnTransition got %v, want %v", all, want)
+	}
+	if want := (changes{"idle -(edit)-> editing"}); !slices.Equal(bold, want) {
+		t.Errorf("OnState(bold) got %v, want %v", bold, want)
+	}
+	if want := (changes{"italicOff -(i)-> italicOn", "italicOn -(reset)-> italicOff"}); !slices.Equal(italic, want) {
+		t.Errorf("OnState(italicOn) got %v, want %v", italic, want)
+	}
+}
+
+func TestRemoveListener(t *testing.T) {
+	m := mustDefine(t, player(NoHistory)).NewMachine()
+	var first, second, stateful changes
+	removeFirst := m.OnTransition(first.listen)
+	m.OnTransition(second.listen)
+	removeStateful := m.OnState("on", stateful.listen)
+
+	m.Fire("power")
+	removeFirst()
+	removeFirst() // removing twice is harmless
+	removeStateful()
+	m.Fire("power")
+
+	if want := (changes{"off -(power)-> on"}); !slices.Equal(first, want) || !slices.Equal(stateful, want) {
+		t.Errorf("removed listeners got %v and %v, want %v", first, stateful, want)
+	}
+	if want := (changes{"off -(power)-> on", "on -(power)-> off"}); !slices.Equal(second, want) {
+		t.Errorf("the remaining listener got %v, want %v", second, want)
+	}
+}
+
+var logStart = time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+
+// logged makes the change from -(event)-> to at minute past logStart
+func logged(minute int, from State, event Event, to State) Change {
+	return Change{
+		Step:    Step{From: from, Event: event, To: to},
+		Time:    logStart.Add(time.Duration(minute) * time.Minute),
+		Exited:  []State{from},
+		Entered: []State{to},
+	}
+}
+
+func events(cs []Change) []Event {
+	var events []Event
+	for _, c := range cs {
+		events = append(events, c.Event)
+	}
+	return events
+}
+
+func TestTransitionLogWrapsAround(t *testing.T) {
+	for _, tc := range []struct {
+		capacity, records int
+		want              []Event
+		dropped           int
+	}{
+		{3, 0, nil, 0},
+		{3, 2, []Event{"e1", "e2"}, 0},
+		{3, 3, []Event{"e1", "e2", "e3"}, 0},
+		{3, 4, []Event{"e2", "e3", "e4"}, 1},
+		{3, 7, []Event{"e5", "e6", "e7"}, 4},
+		{1, 3, []Event{"e3"}, 2},
+		{0, 2, []Event{"e2"}, 1}, // capacity is at least 1
+	} {
+		log := NewTransitionLog(tc.capacity)
+		for i := 1; i <= tc.records; i++ {
+			log.Record(logged(i, "a", Event(fmt.Sprintf("e%d", i)), "b"))
+		}
+		if got := events(log.Entries()); !slices.Equal(got, tc.want) {
+			t.Errorf("capacity %d after %d records: Entries() = %v, want %v", tc.capacity, tc.records, got, tc.want)
+		}
+		if got := log.Dropped(); got != tc.dropped {
+			t.Errorf("capacity %d after %d records: Dropped() = %d, want %d", tc.capacity, tc.records, got, tc.dropped)
+		}
+	}
+}
+
+func TestTransitionLogFind(t *testing.T) {
+	log := NewTransitionLog(10)
+	log.Record(logged(0, "off", "power", "on"))
+	log.Record(logged(1, "on", "play", "playing"))
+	log.Record(logged(2, "playing", "stop", "on"))
+	log.Record(logged(3, "on", "power", "off"))
+
+	at := func(minute int) time.Time { return logStart.Add(time.Duration(minute) * time.Minute) }
+	for _, tc := range []struct {
+		name  string
+		query Query
+		want  []Event
+	}{
+		{"everything", Query{}, []Event{"power", "play", "stop", "power"}},
+		{"by state", Query{State: "playing"}, []Event{"play", "stop"}},
+		{"by event", Query{Event: "power"}, []Event{"power", "power"}},
+		{"since is inclusive", Query{Since: at(2)}, []Event{"stop", "power"}},
+		{"until is exclusive", Query{Until: at(2)}, []Event{"power", "play"}},
+		{"window", Query{Since: at(1), Until: at(3)}, []Event{"play", "stop"}},
+		{"combined", Query{State: "off", Event: "power", Since: at(1)}, []Event{"power"}},
+		{"no match", Query{State: "paused"}, nil},
+	} {
+		if got := events(log.Find(tc.query)); !slices.Equal(got, tc.want) {
+			t.Errorf("%s: Find(%+v) = %v, want %v", tc.name, tc.query, got, tc.want)
+		}
+	}
+}
+
+func TestTransitionLogDump(t *testing.T) {
+	log := NewTransitionLog(2)
+	var b bytes.Buffer
+	if err := log.Dump(&b); err != nil || b.Len() > 0 {
+		t.Errorf("Dump of an empty log wrote %q, %v", b.String(), err)
+	}
+
+	log.Record(logged(0, "off", "power", "on"))
+	b.Reset()
+	log.Dump(&b)
+	if want := "09:30:00.000 off -(power)-> on exited [off] entered [on]\n"; b.String() != want {
+		t.Errorf("Dump() wrote %q, want %q", b.String(), want)
+	}
+
+	log.Record(logged(1, "on", "play", "playing"))
+	log.Record(logged(2, "playing", "stop", "on"))
+	b.Reset()
+	log.Dump(&b)
+	want := "(1 older transitions dropped)\n" +
+		"09:31:00.000 on -(play)-> playing exited [on] entered [playing]\n" +
+		"09:32:00.000 playing -(stop)-> on exited [playing] entered [on]\n"
+	if b.String() != want {
+		t.Errorf("Dump() wrote\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func TestTransitionLogRecordsAMachine(t *testing.T) {
+	m := mustDefine(t, player(NoHistory)).NewMachine()
+	log := NewTransitionLog(2)
+	m.OnTransition(log.Record)
+	for _, event := range []Event{"power", "play", "stop"} {
+		m.Fire(event)
+	}
+	if got := events(log.Entries()); !slices.Equal(got, []Event{"play", "stop"}) || log.Dropped() != 1 {
+		t.Errorf("logged %v and dropped %d, want [play stop] and 1", got, log.Dropped())
+	}
+	if got := log.Find(Query{State: "normal"}); len(got) != 2 {
+		t.Errorf("Find(normal) = %v, want both play and stop", got)
+	}
+}
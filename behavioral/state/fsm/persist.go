@@ -0,0 +1,173 @@
+package fsm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Snapshot is the serializable state of a Machine: its active configuration
+// and history. Data is free for the machine's owner to store its own context
+// alongside; the engine carries it through migrations untouched.
+type Snapshot struct {
+	Machine string `json:"machine"`
+	Version int    `json:"version"`
+	// Active lists the innermost active state of every region; their
+	// ancestors are implied
+	Active []State `json:"active"`
+	// History maps each composite state to the child it was last exited from
+	History map[State]State `json:"history,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// Migration upgrades a snapshot by one version. It does not need to set
+// Version; the engine does.
+type Migration func(s Snapshot) (Snapshot, error)
+
+// ErrInvalidSnapshot is wrapped by every error caused by a snapshot that does
+// not fit the definition
+var ErrInvalidSnapshot = errors.New("fsm: invalid snapshot")
+
+// VersionError is returned when a snapshot's version is newer than the
+// definition's, or older with no migration path
+type VersionError struct {
+	Machine string
+	Version int
+	Want    int
+}
+
+func (e *VersionError) Error() string {
+	if e.Version > e.Want {
+		return fmt.Sprintf("fsm: snapshot of %q is version %d, newer than supported version %d", e.Machine, e.Version, e.Want)
+	}
+	return fmt.Sprintf("fsm: no migration for %q from version %d to %d", e.Machine, e.Version, e.Want)
+}
+
+// ParseSnapshot decodes a snapshot saved as JSON. Malformed input wraps
+// ErrInvalidSnapshot like any other snapshot that cannot be restored.
+func ParseSnapshot(data []byte) (Snapshot, error) {
+	var s Snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return Snapshot{}, fmt.Errorf("%w: %w", ErrInvalidSnapshot, err)
+	}
+	return s, nil
+}
+
+// Snapshot captures the machine's active states and history
+func (m *Machine) Snapshot() Snapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	snapshot := Snapshot{
+		Machine: m.def.name,
+		Version: m.def.version,
+		Active:  m.leaves(),
+	}
+	if len(m.lastChild) > 0 {
+		snapshot.History = make(map[State]State, len(m.lastChild))
+		for state, child := range m.lastChild {
+			snapshot.History[state] = child
+		}
+	}
+	return snapshot
+}
+
+// Migrate brings a snapshot taken with an older version of the definition up
+// to the current one, one migration at a time
+func (d *Definition) Migrate(s Snapshot) (Snapshot, error) {
+	if s.Machine != d.name {
+		return s, fmt.Errorf("%w: taken from machine %q, not %q", ErrInvalidSnapshot, s.Machine, d.name)
+	}
+	if s.Version > d.version {
+		return s, &VersionError{Machine: d.name, Version: s.Version, Want: d.version}
+	}
+	for s.Version < d.version {
+		migrate, ok := d.migrations[s.Version]
+		if !ok {
+			return s, &VersionError{Machine: d.name, Version: s.Version, Want: d.version}
+		}
+		migrated, err := migrate(s)
+		if err != nil {
+			return s, fmt.Errorf("fsm: migrating %q from version %d: %w", d.name, s.Version, err)
+		}
+		migrated.Machine, migrated.Version = s.Machine, s.Version+1
+		s = migrated
+	}
+	return s, nil
+}
+
+// Restore recreates a machine from a snapshot, migrating it first if needed.
+// The restored states are not entered again, so no entry actions run; owners
+// that derive their own state from entry actions must rebuild it themselves.
+func (d *Definition) Restore(s Snapshot) (*Machine, error) {
+	s, err := d.Migrate(s)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Machine{
+		def:         d,
+		active:      make(map[State]bool),
+		activeChild: make(map[State]State),
+		lastChild:   make(map[State]State),
+	}
+	var errs []error
+	for _, leaf := range s.Active {
+		if _, ok := d.states[leaf]; !ok {
+			errs = append(errs, fmt.Errorf("unknown state %q", leaf))
+			continue
+		}
+		if d.compound(leaf) {
+			errs = append(errs, fmt.Errorf("state %q is not innermost", leaf))
+			continue
+		}
+		for state := leaf; state != root; state = d.parent(state) {
+			m.active[state] = true
+		}
+	}
+	for state, child := range s.History {
+		if state == root || d.parent(child) != state || d.parallel(state) {
+			errs = append(errs, fmt.Errorf("history %q -> %q is not a composite state and its child", state, child))
+			continue
+		}
+		m.lastChild[state] = child
+	}
+	if len(errs) == 0 {
+		errs = m.adoptConfiguration(root)
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSnapshot, errors.Join(errs...))
+	}
+	return m, nil
+}
+
+// adoptConfiguration checks that the active states below parent form a
+// configuration the machine could be in, recording the active children
+func (m *Machine) adoptConfiguration(parent State) []error {
+	if parent != root && !m.def.compound(parent) {
+		return nil
+	}
+	var active []State
+	for _, child := range m.def.children[parent] {
+		if m.active[child] {
+			active = append(active, child)
+		}
+	}
+
+	var errs []error
+	switch {
+	case parent != root && m.def.parallel(parent):
+		if len(active) != len(m.def.children[parent]) {
+			errs = append(errs, fmt.Errorf("not every region of %q is active", parent))
+		}
+	case len(active) == 1:
+		m.activeChild[parent] = active[0]
+	case parent == root:
+		errs = append(errs, fmt.Errorf("%d top-level states active, want 1", len(active)))
+	default:
+		errs = append(errs, fmt.Errorf("%d children of %q active, want 1", len(active), parent))
+	}
+	for _, child := range active {
+		errs = append(errs, m.adoptConfiguration(child)...)
+	}
+	return errs
+}
@@ -0,0 +1,253 @@
+package fsm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// roundTrip saves m as JSON and restores it from def
+func roundTrip(t *testing.T, def *Definition, m *Machine) *Machine {
+	t.Helper()
+	saved, err := json.Marshal(m.Snapshot())
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := ParseSnapshot(saved)
+	if err != nil {
+		t.Fatalf("ParseSnapshot(%s): %v", saved, err)
+	}
+	restored, err := def.Restore(snapshot)
+	if err != nil {
+		t.Fatalf("Restore(%s): %v", saved, err)
+	}
+	if got, want := restored.Snapshot(), m.Snapshot(); !reflect.DeepEqual(got, want) {
+		t.Errorf("restored machine snapshots as %+v, want %+v", got, want)
+	}
+	return restored
+}
+
+func TestSnapshotRoundTripKeepsHistory(t *testing.T) {
+	tr := &tracer{}
+	def := mustDefine(t, tr.trace(player(DeepHistory)))
+	m := def.NewMachine()
+	for _, event := range []Event{"power", "play", "ff", "power"} {
+		m.Fire(event)
+	}
+
+	snapshot := m.Snapshot()
+	want := Snapshot{
+		Machine: "player",
+		Active:  []State{"off"},
+		History: map[State]State{"on": "playing", "playing": "fast"},
+	}
+	if !reflect.DeepEqual(snapshot, want) {
+		t.Errorf("Snapshot() = %+v, want %+v", snapshot, want)
+	}
+
+	tr.take()
+	restored := roundTrip(t, def, m)
+	if got := tr.take(); len(got) > 0 {
+		t.Errorf("Restore ran %v, want no actions", got)
+	}
+	if got := restored.Active(); !slices.Equal(got, []State{"off"}) {
+		t.Errorf("Active() = %v", got)
+	}
+	// The restored history takes the machine back where it was
+	restored.Fire("power")
+	if got := restored.Active(); !slices.Equal(got, []State{"on", "playing", "fast"}) {
+		t.Errorf("Active() after turning back on = %v, want [on playing fast]", got)
+	}
+}
+
+func TestSnapshotRoundTripOfParallelRegions(t *testing.T) {
+	def := mustDefine(t, editor(DeepHistory))
+	m := def.NewMachine()
+	for _, event := range []Event{"edit", "b", "done", "edit", "i"} {
+		m.Fire(event)
+	}
+	if got, want := m.Snapshot().History, map[State]State{"bold": "boldOn", "italic": "italicOff"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("History = %v, want %v", got, want)
+	}
+
+	restored := roundTrip(t, def, m)
+	if got := restored.Leaves(); !slices.Equal(got, []State{"boldOn", "italicOn"}) {
+		t.Fatalf("Leaves() = %v, want [boldOn italicOn]", got)
+	}
+	// Both regions still take their own transitions
+	if err := restored.Fire("reset"); err != nil {
+		t.Fatal(err)
+	}
+	if got := restored.Leaves(); !slices.Equal(got, []State{"boldOff", "italicOff"}) {
+		t.Errorf("Leaves() after reset = %v, want [boldOff italicOff]", got)
+	}
+	restored.Fire("done")
+	restored.Fire("edit")
+	if got := restored.Leaves(); !slices.Equal(got, []State{"boldOff", "italicOff"}) {
+		t.Errorf("Leaves() after editing again = %v, want [boldOff italicOff]", got)
+	}
+}
+
+// lamp is at version 2: version 0 called its states down and up, version 1
+// stopped and started
+func lamp(migrations map[int]Migration) Spec {
+	return Spec{
+		Name:        "lamp",
+		Version:     2,
+		Initial:     "off",
+		States:      []StateSpec{{Name: "off"}, {Name: "on"}},
+		Transitions: []Transition{{From: "off", Event: "toggle", To: "on"}, {From: "on", Event: "toggle", To: "off"}},
+		Migrations:  migrations,
+	}
+}
+
+// rename returns a migration renaming active states, recording each run
+func rename(ran *[]string, names map[State]State) Migration {
+	return func(s Snapshot) (Snapshot, error) {
+		*ran = append(*ran, fmt.Sprintf("from %d: %v", s.Version, s.Active))
+		active := make([]State, len(s.Active))
+		for i, state := range s.Active {
+			renamed, ok := names[state]
+			if !ok {
+				return s, fmt.Errorf("unexpected state %q", state)
+			}
+			active[i] = renamed
+		}
+		s.Active = active
+		return s, nil
+	}
+}
+
+func TestMigrateChain(t *testing.T) {
+	var ran []string
+	def := mustDefine(t, lamp(map[int]Migration{
+		0: rename(&ran, map[State]State{"down": "stopped", "up": "started"}),
+		1: rename(&ran, map[State]State{"stopped": "off", "started": "on"}),
+	}))
+
+	old := Snapshot{Machine: "lamp", Active: []State{"up"}, Data: json.RawMessage(`{"watts":40}`)}
+	migrated, err := def.Migrate(old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"from 0: [up]", "from 1: [started]"}; !slices.Equal(ran, want) {
+		t.Errorf("migrations ran %v, want %v", ran, want)
+	}
+	want := Snapshot{Machine: "lamp", Version: 2, Active: []State{"on"}, Data: json.RawMessage(`{"watts":40}`)}
+	if !reflect.DeepEqual(migrated, want) {
+		t.Errorf("Migrate() = %+v, want %+v", migrated, want)
+	}
+
+	// A current snapshot needs no migration
+	ran = nil
+	if again, err := def.Migrate(migrated); err != nil || !reflect.DeepEqual(again, migrated) || len(ran) > 0 {
+		t.Errorf("Migrate of a current snapshot = %+v, %v after running %v", again, err, ran)
+	}
+
+	// Restore migrates too
+	m, err := def.Restore(Snapshot{Machine: "lamp", Active: []State{"down"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Current() != "off" || m.Snapshot().Version != 2 {
+		t.Errorf("restored %q at version %d, want off at version 2", m.Current(), m.Snapshot().Version)
+	}
+	if err := m.Fire("toggle"); err != nil || m.Current() != "on" {
+		t.Errorf("Fire(toggle) = %v, now in %q", err, m.Current())
+	}
+
+	// A failing migration stops the chain
+	_, err = def.Restore(Snapshot{Machine: "lamp", Version: 1, Active: []State{"dimmed"}})
+	if err == nil || !strings.Contains(err.Error(), `migrating "lamp" from version 1: unexpected state "dimmed"`) {
+		t.Errorf("Restore with a failing migration = %v", err)
+	}
+}
+
+func TestVersionError(t *testing.T) {
+	var ran []string
+	def := mustDefine(t, lamp(map[int]Migration{
+		0: rename(&ran, map[State]State{"down": "off", "up": "on"}),
+	}))
+
+	for _, tc := range []struct {
+		name     string
+		snapshot Snapshot
+		want     VersionError
+		message  string
+	}{
+		{"newer", Snapshot{Machine: "lamp", Version: 3, Active: []State{"on"}},
+			VersionError{Machine: "lamp", Version: 3, Want: 2},
+			`fsm: snapshot of "lamp" is version 3, newer than supported version 2`},
+		// Migrated to 1, which has no way on to 2
+		{"no migration path", Snapshot{Machine: "lamp", Active: []State{"up"}},
+			VersionError{Machine: "lamp", Version: 1, Want: 2},
+			`fsm: no migration for "lamp" from version 1 to 2`},
+		{"no migration at all", Snapshot{Machine: "lamp", Version: 1, Active: []State{"on"}},
+			VersionError{Machine: "lamp", Version: 1, Want: 2},
+			`fsm: no migration for "lamp" from version 1 to 2`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := def.Restore(tc.snapshot)
+			var versionErr *VersionError
+			if !errors.As(err, &versionErr) {
+				t.Fatalf("Restore() = %v, %v, want a *VersionError", m, err)
+			}
+			if *versionErr != tc.want {
+				t.Errorf("VersionError = %+v, want %+v", *versionErr, tc.want)
+			}
+			if err.Error() != tc.message {
+				t.Errorf("Error() = %q, want %q", err, tc.message)
+			}
+			if errors.Is(err, ErrInvalidSnapshot) {
+				t.Error("a version mismatch also wraps ErrInvalidSnapshot")
+			}
+		})
+	}
+}
+
+func TestInvalidSnapshot(t *testing.T) {
+	def := mustDefine(t, editor(NoHistory))
+	for _, tc := range []struct {
+		name     string
+		snapshot string
+		want     string
+	}{
+		{"other machine", `{"machine":"lamp","active":["idle"]}`, `taken from machine "lamp", not "editor"`},
+		{"unknown state", `{"machine":"editor","active":["nowhere"]}`, `unknown state "nowhere"`},
+		{"composite state", `{"machine":"editor","active":["editing"]}`, `state "editing" is not innermost`},
+		{"nothing active", `{"machine":"editor","active":[]}`, "0 top-level states active, want 1"},
+		{"two top-level states", `{"machine":"editor","active":["idle","boldOff","italicOff"]}`, "2 top-level states active, want 1"},
+		{"missing region", `{"machine":"editor","active":["boldOn"]}`, `not every region of "editing" is active`},
+		{"two siblings", `{"machine":"editor","active":["boldOff","boldOn","italicOff"]}`, `2 children of "bold" active, want 1`},
+		{"history of a stranger", `{"machine":"editor","active":["idle"],"history":{"bold":"italicOn"}}`, `history "bold" -> "italicOn"`},
+		{"history of a parallel state", `{"machine":"editor","active":["idle"],"history":{"editing":"bold"}}`, `history "editing" -> "bold"`},
+		{"history of an atomic state", `{"machine":"editor","active":["idle"],"history":{"idle":"boldOn"}}`, `history "idle" -> "boldOn"`},
+		{"truncated JSON", `{"machine":"editor","active":[`, "unexpected end of JSON input"},
+		{"mistyped JSON", `{"machine":"editor","active":"idle"}`, "cannot unmarshal string"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			snapshot, err := ParseSnapshot([]byte(tc.snapshot))
+			if err == nil {
+				_, err = def.Restore(snapshot)
+			}
+			if !errors.Is(err, ErrInvalidSnapshot) {
+				t.Fatalf("err = %v, want one wrapping ErrInvalidSnapshot", err)
+			}
+			if !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("err = %q, want it to mention %q", err, tc.want)
+			}
+		})
+	}
+
+	// Every problem is reported, not just the first
+	_, err := def.Restore(Snapshot{Machine: "editor", Active: []State{"nowhere", "editing"}})
+	for _, want := range []string{`unknown state "nowhere"`, `"editing" is not innermost`} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("err = %v, want it to mention %q", err, want)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"errors"
+
+	"go-design-patterns/behavioral/state/fsm"
+)
+
+// InputMode is the state deciding what a keystroke does to the editor: type
+// into the buffer, run a command, or nothing at all
+type InputMode interface {
+	Press(te *TextEditor, key Key) error
+}
+
+var errReadOnly = errors.New("the buffer is read-only")
+
+// InsertMode types characters in front of the cursor
+type InsertMode struct{}
+
+func (InsertMode) Press(te *TextEditor, key Key) error {
+	return typeKey(te, key, (*Buffer).Insert)
+}
+
+// ReplaceMode types over the characters after the cursor
+type ReplaceMode struct{}
+
+func (ReplaceMode) Press(te *TextEditor, key Key) error {
+	return typeKey(te, key, (*Buffer).Overwrite)
+}
+
+// typeKey is the key handling shared by the typing modes; they differ only in
+// how a character lands in the buffer
+func typeKey(te *TextEditor, key Key, write func(*Buffer, rune)) error {
+	switch {
+	case key.printable():
+		write(&te.buffer, te.state.Write(te.buffer.Previous(), rune(key)))
+	case key == KeyEscape:
+		return te.Handle(EventEscape)
+	case key == KeyInsert:
+		return te.Handle(EventInsertKey)
+	case key == KeyBackspace:
+		te.buffer.Backspace()
+	case key == KeyDelete:
+		te.buffer.Delete()
+	default:
+		return moveKey(te, key)
+	}
+	return nil
+}
+
+// moveKey handles the keys every editable mode treats alike
+func moveKey(te *TextEditor, key Key) error {
+	switch key {
+	case KeyLeft:
+		te.buffer.Move(-1)
+	case KeyRight:
+		te.buffer.Move(1)
+	case KeyHome:
+		te.buffer.Home()
+	case KeyEnd:
+		te.buffer.End()
+	case KeyCapsLock:
+		return te.Handle(EventCapsLock)
+	}
+	return nil
+}
+
+// NormalMode treats keys as vim-style commands instead of text
+type NormalMode struct{}
+
+func (NormalMode) Press(te *TextEditor, key Key) error {
+	switch key {
+	case 'h':
+		te.buffer.Move(-1)
+	case 'l':
+		te.buffer.Move(1)
+	case '0':
+		te.buffer.Home()
+	case '$':
+		te.buffer.End()
+	case 'x':
+		te.buffer.Delete()
+	case 'X':
+		te.buffer.Backspace()
+	case 'i':
+		return te.Handle(EventInsertMode)
+	case 'a':
+		te.buffer.Move(1)
+		return te.Handle(EventInsertMode)
+	case 'I':
+		te.buffer.Home()
+		return te.Handle(EventInsertMode)
+	case 'A':
+		te.buffer.End()
+		return te.Handle(EventInsertMode)
+	case 'R':
+		return te.Handle(EventReplaceMode)
+	default:
+		// Unknown commands are ignored, as are characters: nothing is typed
+		return moveKey(te, key)
+	}
+	return nil
+}
+
+// ReadOnly lets the cursor move but refuses every change to the buffer
+type ReadOnly struct{}
+
+func (ReadOnly) Press(te *TextEditor, key Key) error {
+	switch key {
+	case KeyLeft, KeyRight, KeyHome, KeyEnd:
+		return moveKey(te, key)
+	}
+	return errReadOnly
+}
+
+// MenuOpen swallows keystrokes while the editor's menu has focus; Escape
+// closes the menu
+type MenuOpen struct{}
+
+func (MenuOpen) Press(te *TextEditor, key Key) error {
+	if key == KeyEscape {
+		return te.Handle(EventCloseMenu)
+	}
+	return nil
+}
+
+// inputModes is the state registry for the editor's input behaviour, next to
+// writingStates for its case behaviour
+var inputModes = map[fsm.State]InputMode{
+	StateInsert:   InsertMode{},
+	StateReplace:  ReplaceMode{},
+	StateNormal:   NormalMode{},
+	StateReadOnly: ReadOnly{},
+	StateMenu:     MenuOpen{},
+}
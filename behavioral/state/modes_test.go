@@ -0,0 +1,189 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"go-design-patterns/behavioral/state/fsm"
+)
+
+// keys spells out keystrokes: strings are typed character by character
+func keys(parts ...any) []Key {
+	var keys []Key
+	for _, part := range parts {
+		switch part := part.(type) {
+		case string:
+			for _, r := range part {
+				keys = append(keys, Key(r))
+			}
+		case Key:
+			keys = append(keys, part)
+		}
+	}
+	return keys
+}
+
+func TestModeKeys(t *testing.T) {
+	var (
+		defaultInsert  = []fsm.State{StateDefault, StateInsert}
+		defaultReplace = []fsm.State{StateDefault, StateReplace}
+		defaultNormal  = []fsm.State{StateDefault, StateNormal}
+	)
+	for _, tc := range []struct {
+		name      string
+		noShout   bool
+		typed     string      // typed before the events
+		events    []fsm.Event // fired before the keys
+		keys      []Key
+		want      string // the buffer, with | marking the cursor
+		wantModes []fsm.State
+		wantErr   error
+	}{
+		// Insert
+		{name: "insert types", keys: keys("Hi there"), want: "Hi there|", wantModes: defaultInsert},
+		{name: "insert edits at the cursor", keys: keys("abc", KeyLeft, KeyLeft, KeyBackspace, KeyDelete, "x"),
+			want: "x|c", wantModes: defaultInsert},
+		{name: "insert home and end", keys: keys("ab", KeyEnter, "cd", KeyHome, "x", KeyEnd, "y"),
+			want: "ab\nxcdy|", wantModes: defaultInsert},
+		{name: "caps lock shapes what is typed", keys: keys("a", KeyCapsLock, "b", KeyCapsLock, "c"),
+			want: "aBc|", wantModes: defaultInsert},
+		{name: "caps lock refused", noShout: true, keys: keys("a", KeyCapsLock, "b"),
+			want: "a|", wantModes: defaultInsert, wantErr: errShoutingDisabled},
+		{name: "title case", events: []fsm.Event{EventTitleCase}, keys: keys("hello WORLD"),
+			want: "Hello World|", wantModes: []fsm.State{StateTitle, StateInsert}},
+
+		// Replace
+		{name: "insert key switches to replace", keys: keys("abc", KeyHome, KeyInsert, "xy"),
+			want: "xy|c", wantModes: defaultReplace},
+		{name: "replace appends past the end", keys: keys("ab", KeyHome, KeyInsert, "xyz"),
+			want: "xyz|", wantModes: defaultReplace},
+		{name: "replace edits like insert", keys: keys("abc", KeyInsert, KeyBackspace, KeyHome, KeyDelete),
+			want: "|b", wantModes: defaultReplace},
+		{name: "insert key switches back", keys: keys("ab", KeyInsert, KeyInsert, KeyHome, "x"),
+			want: "x|ab", wantModes: defaultInsert},
+
+		// Normal
+		{name: "escape switches to normal", keys: keys("hello", KeyEscape),
+			want: "hello|", wantModes: defaultNormal},
+		{name: "escape in normal does nothing", typed: "ab", events: []fsm.Event{EventEscape}, keys: keys(KeyEscape),
+			want: "ab|", wantModes: defaultNormal},
+		{name: "normal commands", typed: "hello", events: []fsm.Event{EventEscape}, keys: keys("0xlX$"),
+			want: "llo|", wantModes: defaultNormal},
+		{name: "normal types nothing", typed: "ab", events: []fsm.Event{EventEscape}, keys: keys("hqz7 "),
+			want: "a|b", wantModes: defaultNormal},
+		{name: "normal arrows and caps lock", typed: "abc", events: []fsm.Event{EventEscape}, keys: keys(KeyHome, KeyRight, KeyCapsLock),
+			want: "a|bc", wantModes: []fsm.State{StateUpper, StateNormal}},
+		{name: "normal i", typed: "abc", events: []fsm.Event{EventEscape}, keys: keys("0ix"),
+			want: "x|abc", wantModes: defaultInsert},
+		{name: "normal a", typed: "abc", events: []fsm.Event{EventEscape}, keys: keys("0ax"),
+			want: "ax|bc", wantModes: defaultInsert},
+		{name: "normal I", typed: "abc", events: []fsm.Event{EventEscape}, keys: keys("Ix"),
+			want: "x|abc", wantModes: defaultInsert},
+		{name: "normal A", typed: "abc", events: []fsm.Event{EventEscape}, keys: keys("0Ax"),
+			want: "abcx|", wantModes: defaultInsert},
+		{name: "normal R", typed: "abc", events: []fsm.Event{EventEscape}, keys: keys("0Rxy"),
+			want: "xy|c", wantModes: defaultReplace},
+
+		// Read-only
+		{name: "read-only moves the cursor", typed: "abc", events: []fsm.Event{EventLock}, keys: keys(KeyHome, KeyEnd, KeyLeft),
+			want: "ab|c", wantModes: []fsm.State{StateReadOnly}},
+		{name: "read-only refuses typing", typed: "abc", events: []fsm.Event{EventLock}, keys: keys("x"),
+			want: "abc|", wantModes: []fsm.State{StateReadOnly}, wantErr: errReadOnly},
+		{name: "read-only refuses deleting", typed: "abc", events: []fsm.Event{EventLock}, keys: keys(KeyBackspace),
+			want: "abc|", wantModes: []fsm.State{StateReadOnly}, wantErr: errReadOnly},
+		{name: "read-only refuses mode keys", typed: "abc", events: []fsm.Event{EventLock}, keys: keys(KeyEscape),
+			want: "abc|", wantModes: []fsm.State{StateReadOnly}, wantErr: errReadOnly},
+		{name: "unlocking restores the modes", typed: "abc", events: []fsm.Event{EventCapsLock, EventEscape, EventLock, EventUnlock},
+			keys: keys("0x"), want: "|bc", wantModes: []fsm.State{StateUpper, StateNormal}},
+
+		// Menu
+		{name: "menu swallows keys", typed: "abc", events: []fsm.Event{EventOpenMenu}, keys: keys("xyz", KeyBackspace, KeyLeft),
+			want: "abc|", wantModes: []fsm.State{StateMenu}},
+		{name: "escape closes the menu", typed: "abc", events: []fsm.Event{EventInsertKey, EventOpenMenu}, keys: keys("xy", KeyEscape, KeyHome, "d"),
+			want: "d|bc", wantModes: defaultReplace},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			te := NewTextEditor(!tc.noShout)
+			if err := te.Type(tc.typed); err != nil {
+				t.Fatal(err)
+			}
+			for _, event := range tc.events {
+				if err := te.Handle(event); err != nil {
+					t.Fatalf("Handle(%s): %v", event, err)
+				}
+			}
+			var err error
+			for _, key := range tc.keys {
+				if err = te.Press(key); err != nil {
+					break
+				}
+			}
+
+			if !errors.Is(err, tc.wantErr) || (err == nil) != (tc.wantErr == nil) {
+				t.Errorf("Press: err = %v, want %v", err, tc.wantErr)
+			}
+			if got := te.buffer.marked(); got != tc.want {
+				t.Errorf("buffer %q, want %q", got, tc.want)
+			}
+			if got := te.Modes(); !slices.Equal(got, tc.wantModes) {
+				t.Errorf("Modes() = %v, want %v", got, tc.wantModes)
+			}
+		})
+	}
+}
+
+func TestModeSwitches(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		events    []fsm.Event
+		wantModes []fsm.State
+		refused   bool // the last event is refused
+	}{
+		{"initial", nil, []fsm.State{StateDefault, StateInsert}, false},
+		{"caps lock", []fsm.Event{EventCapsLock}, []fsm.State{StateUpper, StateInsert}, false},
+		{"caps lock twice", []fsm.Event{EventCapsLock, EventCapsLock}, []fsm.State{StateDefault, StateInsert}, false},
+		{"lower case from upper", []fsm.Event{EventCapsLock, EventLowerCase}, []fsm.State{StateLower, StateInsert}, false},
+		{"lower case twice", []fsm.Event{EventLowerCase, EventLowerCase}, []fsm.State{StateLower, StateInsert}, true},
+		// title_case is declared on the case region, and title overrides it
+		{"title case from lower", []fsm.Event{EventLowerCase, EventTitleCase}, []fsm.State{StateTitle, StateInsert}, false},
+		{"title case toggles off", []fsm.Event{EventTitleCase, EventTitleCase}, []fsm.State{StateDefault, StateInsert}, false},
+		{"reset", []fsm.Event{EventLowerCase, EventReset}, []fsm.State{StateDefault, StateInsert}, false},
+		{"case and input are independent", []fsm.Event{EventCapsLock, EventEscape, EventReplaceMode},
+			[]fsm.State{StateUpper, StateReplace}, false},
+		{"insert mode from insert", []fsm.Event{EventInsertMode}, []fsm.State{StateDefault, StateInsert}, true},
+		{"escape from normal", []fsm.Event{EventEscape, EventEscape}, []fsm.State{StateDefault, StateNormal}, true},
+		{"lock", []fsm.Event{EventLowerCase, EventLock}, []fsm.State{StateReadOnly}, false},
+		{"caps lock while locked", []fsm.Event{EventLock, EventCapsLock}, []fsm.State{StateReadOnly}, true},
+		{"unlock resumes every region", []fsm.Event{EventLowerCase, EventEscape, EventLock, EventUnlock},
+			[]fsm.State{StateLower, StateNormal}, false},
+		{"menu from read-only", []fsm.Event{EventLock, EventOpenMenu}, []fsm.State{StateReadOnly}, true},
+		{"closing the menu resumes every region", []fsm.Event{EventTitleCase, EventInsertKey, EventOpenMenu, EventCloseMenu},
+			[]fsm.State{StateTitle, StateReplace}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			te := NewTextEditor(true)
+			var err error
+			for i, event := range tc.events {
+				err = te.Handle(event)
+				if err != nil && i < len(tc.events)-1 {
+					t.Fatalf("Handle(%s): %v", event, err)
+				}
+			}
+			var invalid *fsm.InvalidTransitionError
+			if tc.refused != errors.As(err, &invalid) {
+				t.Errorf("last event: err = %v, want refused %t", err, tc.refused)
+			}
+			if got := te.Modes(); !slices.Equal(got, tc.wantModes) {
+				t.Errorf("Modes() = %v, want %v", got, tc.wantModes)
+			}
+			// The behaviour follows the machine
+			if mode, ok := inputModes[te.Modes()[len(te.Modes())-1]]; ok && te.mode != mode {
+				t.Errorf("input mode %T, want %T", te.mode, mode)
+			}
+			if writing, ok := writingStates[te.Modes()[0]]; ok && te.state != writing {
+				t.Errorf("writing state %T, want %T", te.state, writing)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"cmp"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+	"text/tabwriter"
+
+	"go-design-patterns/behavioral/strategy/sorting"
+)
+
+// BenchResult is how one strategy did on one input
+type BenchResult struct {
+	Strategy    string
+	Shape       string
+	Size        int
+	NsPerOp     int64
+	BytesPerOp  int64
+	AllocsPerOp int64
+	Comparisons int64
+	// Swaps and Moves are -1 when the strategy cannot count them
+	Swaps int64
+	Moves int64
+}
+
+// benchmark runs every strategy on every shape and size. Timings and
+// allocations come from testing.Benchmark, so the run honours
+// -test.benchtime; the counts come from one extra instrumented sort.
+func benchmark(strategies []sorting.SortStrategy[int], shapes []sorting.InputShape, sizes []int, seed int64, progress io.Writer) []BenchResult {
+	r := rand.New(rand.NewSource(seed))
+	var results []BenchResult
+	for _, shape := range shapes {
+		for _, n := range sizes {
+			input := shape.Generate(r, n)
+			for _, strategy := range strategies {
+				fmt.Fprintf(progress, "%s on %d %s ints\n", strategy.GetName(), n, shape.Name)
+				timing := testing.Benchmark(func(b *testing.B) {
+					b.ReportAllocs()
+					for i := 0; i < b.N; i++ {
+						strategy.Sort(input, cmp.Compare[int])
+					}
+				})
+
+				instrumented := sorting.Instrument(strategy)
+				instrumented.Sort(input, cmp.Compare[int])
+				result := BenchResult{
+					Strategy:    strategy.GetName(),
+					Shape:       shape.Name,
+					Size:        n,
+					NsPerOp:     timing.NsPerOp(),
+					BytesPerOp:  timing.AllocedBytesPerOp(),
+					AllocsPerOp: timing.AllocsPerOp(),
+					Comparisons: instrumented.Counts.Comparisons.Load(),
+					Swaps:       -1,
+					Moves:       -1,
+				}
+				if instrumented.CountsMoves() {
+					result.Swaps = instrumented.Counts.Swaps.Load()
+					result.Moves = instrumented.Counts.Moves.Load()
+				}
+				results = append(results, result)
+			}
+		}
+	}
+	return results
+}
+
+var benchColumns = []string{"strategy", "shape", "n", "ns/op", "B/op", "allocs/op", "comparisons", "swaps", "moves"}
+
+func (r BenchResult) columns() []string {
+	count := func(n int64) string {
+		if n < 0 {
+			return "-"
+		}
+		return strconv.FormatInt(n, 10)
+	}
+	return []string{
+		r.Strategy, r.Shape, strconv.Itoa(r.Size),
+		strconv.FormatInt(r.NsPerOp, 10), strconv.FormatInt(r.BytesPerOp, 10), strconv.FormatInt(r.AllocsPerOp, 10),
+		strconv.FormatInt(r.Comparisons, 10), count(r.Swaps), count(r.Moves),
+	}
+}
+
+// writeBenchResults writes results as an aligned text table, CSV or a
+// Markdown table
+func writeBenchResults(w io.Writer, format string, results []BenchResult) error {
+	switch format {
+	case "text":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintln(tw, strings.Join(benchColumns, "\t")+"\t")
+		for _, r := range results {
+			fmt.Fprintln(tw, strings.Join(r.columns(), "\t")+"\t")
+		}
+		return tw.Flush()
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write(benchColumns)
+		for _, r := range results {
+			cw.Write(r.columns())
+		}
+		cw.Flush()
+		return cw.Error()
+	case "markdown":
+		var b strings.Builder
+		b.WriteString("| " + strings.Join(benchColumns, " | ") + " |\n")
+		b.WriteString("|" + strings.Repeat("---|", 3) + strings.Repeat("---:|", len(benchColumns)-3) + "\n")
+		for _, r := range results {
+			b.WriteString("| " + strings.Join(r.columns(), " | ") + " |\n")
+		}
+		_, err := io.WriteString(w, b.String())
+		return err
+	default:
+		return fmt.Errorf("unknown format %q, want text, csv or markdown", format)
+	}
+}
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"go-design-patterns/behavioral/strategy/registry"
+	"go-design-patterns/behavioral/strategy/sorting"
+)
+
+const usage = `usage: strategy [command]
+
+Without a command the demo runs. Commands:
+  sort [flags]                  sort ints, one per line, from stdin to stdout; -h lists the flags
+  extsort [flags]               sort a file larger than memory through temporary files; -h lists the flags
+  bench [flags]                 compare the strategies on several input shapes and sizes; -h lists the flags
+  calibrate [-budget d]         measure the AutoSorter thresholds of this machine`
+
+// runCommand dispatches the subcommands of the strategy demo
+func runCommand(args []string) error {
+	switch args[0] {
+	case "sort":
+		return runSort(args[1:])
+	case "extsort":
+		return runExternalSort(args[1:])
+	case "bench":
+		return runBench(args[1:])
+	case "calibrate":
+		return runCalibrate(args[1:])
+	case "help", "-h", "-help", "--help":
+		fmt.Println(usage)
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q\n%s", args[0], usage)
+	}
+}
+
+// sortConfig is the file given to sort -config, for example
+//
+//	{"strategy": "auto", "order": "descending", "thresholds": {"insertion": 32, ...}}
+//
+// where thresholds, as written by calibrate, tune the auto strategies. The
+// choices are values rather than pointers, so a missing or null strategy or
+// order keeps its default instead of leaving a nil choice behind.
+type sortConfig struct {
+	Strategy   registry.Choice[sorting.SortStrategy[int]] `json:"strategy"`
+	Order      registry.Choice[sorting.Comparator[int]]   `json:"order"`
+	Thresholds *sorting.Thresholds                        `json:"thresholds"`
+}
+
+func runSort(args []string) error {
+	config := sortConfig{Strategy: *sorting.Sorts.Choice("auto"), Order: *sorting.Orders.Choice("ascending")}
+	flags := flag.NewFlagSet("sort", flag.ContinueOnError)
+	flags.Var(&config.Strategy, "strategy", "sorting strategy, one of: "+strings.Join(sorting.Sorts.Names(), ", "))
+	flags.Var(&config.Order, "order", "order, one of: "+strings.Join(sorting.Orders.Names(), ", "))
+	configFile := flags.String("config", "", "JSON file choosing the strategy, order and thresholds; flags override it")
+	timeout := flags.Duration("timeout", 0, "give up after this long; 0 means no limit")
+	verbose := flags.Bool("v", false, "log the chosen strategy and timing to stderr")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *configFile != "" {
+		data, err := os.ReadFile(*configFile)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, &config); err != nil {
+			return fmt.Errorf("%s: %w", *configFile, err)
+		}
+		// Parse again so that flags win over the file
+		if err := flags.Parse(args); err != nil {
+			return err
+		}
+	}
+
+	strategy := config.Strategy.Value()
+	if auto, ok := strategy.(sorting.AutoSorter[int]); ok && config.Thresholds != nil {
+		auto.Thresholds = *config.Thresholds
+		strategy = auto
+	}
+
+	var data []int
+	scanner := bufio.NewScanner(os.Stdin)
+	for line := 1; scanner.Scan(); line++ {
+		field := strings.TrimSpace(scanner.Text())
+		if field == "" {
+			continue
+		}
+		v, err := strconv.Atoi(field)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		data = append(data, v)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	// Interrupting stops the sort rather than the process, so the log
+	// still tells how far it got
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+	sorter := sorting.NewSorterFunc(strategy, config.Order.Value())
+	if *verbose {
+		sorter.SetLogger(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	}
+	sorted, err := sorter.SortContext(ctx, data)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	for _, v := range sorted {
+		w.WriteString(strconv.Itoa(v))
+		w.WriteByte('\n')
+	}
+	return w.Flush()
+}
+
+func runExternalSort(args []string) error {
+	flags := flag.NewFlagSet("extsort", flag.ContinueOnError)
+	codec := flags.String("codec", "lines", "record format: lines (an integer per line), csv or fixed (binary records of -width bytes)")
+	memory := flags.String("memory", "64MiB", "memory budget for records, in bytes or with a KiB, MiB or GiB suffix")
+	column := flags.Int("column", 0, "csv: field to sort by, counted from 0")
+	numeric := flags.Bool("numeric", false, "csv: compare the field as a number")
+	comma := flags.String("comma", ",", "csv: field separator")
+	header := flags.Bool("header", false, "lines and csv: keep the first line on top, unsorted")
+	width := flags.Int("width", 0, "fixed: record width in bytes")
+	descending := flags.Bool("descending", false, "sort in descending order")
+	fanIn := flags.Int("fanin", 0, "chunk files merged at once; 0 means 64")
+	tempDir := flags.String("tmp", "", "directory for the chunk files; empty means the system's")
+	input := flags.String("i", "", "read this file instead of stdin")
+	output := flags.String("o", "", "write to this file instead of stdout")
+	timeout := flags.Duration("timeout", 0, "give up after this long; 0 means no limit")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	budget, err := parseSize(*memory)
+	if err != nil {
+		return fmt.Errorf("bad -memory: %w", err)
+	}
+
+	var r io.Reader = os.Stdin
+	if *input != "" {
+		file, err := os.Open(*input)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	}
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	buffered := bufio.NewWriter(w)
+	if *header {
+		in := bufio.NewReader(r)
+		line, err := in.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		buffered.WriteString(line)
+		r = in
+	}
+
+	// Handling SIGPIPE too makes a closed output, as when piping into head,
+	// fail the write instead of killing the process before the chunk files
+	// are removed
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGPIPE)
+	defer stop()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	switch *codec {
+	case "lines":
+		err = streamSort(ctx, sorting.IntLines{}, cmp.Compare[int], *descending, budget, *fanIn, *tempDir, r, buffered)
+	case "csv":
+		separator := []rune(*comma)
+		if len(separator) != 1 {
+			return fmt.Errorf("bad -comma %q: want a single character", *comma)
+		}
+		compare := sorting.Column(*column)
+		if *numeric {
+			compare = sorting.NumericColumn(*column)
+		}
+		err = streamSort(ctx, sorting.CSV{Comma: separator[0]}, compare, *descending, budget, *fanIn, *tempDir, r, buffered)
+	case "fixed":
+		if *width <= 0 {
+			return fmt.Errorf("-codec fixed needs a positive -width")
+		}
+		err = streamSort(ctx, sorting.FixedWidth{Width: *width}, bytes.Compare, *descending, budget, *fanIn, *tempDir, r, buffered)
+	default:
+		return fmt.Errorf("unknown codec %q", *codec)
+	}
+	if err != nil {
+		return err
+	}
+	return buffered.Flush()
+}
+
+func streamSort[T any](ctx context.Context, codec sorting.Codec[T], compare sorting.Comparator[T], descending bool,
+	budget, fanIn int, tempDir string, r io.Reader, w io.Writer) error {
+	if descending {
+		compare = sorting.Reverse(compare)
+	}
+	sorter := sorting.ExternalSort[T]{Codec: codec, MemoryBudget: budget, MaxFanIn: fanIn, TempDir: tempDir}
+	return sorter.SortStream(ctx, r, w, compare)
+}
+
+// parseSize reads a byte count such as 512, 64KiB or 1GiB
+func parseSize(size string) (int, error) {
+	s, multiplier := size, 1
+	for _, unit := range []struct {
+		suffix string
+		bytes  int
+	}{{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30}} {
+		if number, ok := strings.CutSuffix(s, unit.suffix); ok {
+			s, multiplier = number, unit.bytes
+			break
+		}
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("%q is not a positive size", size)
+	}
+	return n * multiplier, nil
+}
+
+func runCalibrate(args []string) error {
+	flags := flag.NewFlagSet("calibrate", flag.ContinueOnError)
+	budget := flags.Duration("budget", 20*time.Millisecond, "time spent on each measurement")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Calibrating for about %v...\n", 50**budget)
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sorting.Calibrate(*budget))
+}
+
+func runBench(args []string) error {
+	flags := flag.NewFlagSet("bench", flag.ContinueOnError)
+	names := flags.String("strategies", "", "comma-separated strategy names; empty means all")
+	shapeNames := flags.String("shapes", "random,sorted,reversed,many duplicates", "comma-separated input shapes")
+	sizeList := flags.String("sizes", "100,1000,10000", "comma-separated input sizes")
+	benchtime := flags.String("benchtime", "100ms", "time per measurement, or a count such as 10x")
+	format := flags.String("format", "text", "output format: text, csv or markdown")
+	output := flags.String("o", "", "write to this file instead of stdout")
+	seed := flags.Int64("seed", 1, "random seed for the inputs")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	strategies := sorting.Sorts.All()
+	if *names != "" {
+		strategies = nil
+		for _, name := range splitList(*names) {
+			strategy, err := sorting.Sorts.Lookup(name)
+			if err != nil {
+				return err
+			}
+			strategies = append(strategies, strategy)
+		}
+	}
+	var shapes []sorting.InputShape
+	for _, name := range splitList(*shapeNames) {
+		i := slices.IndexFunc(sorting.InputShapes, func(shape sorting.InputShape) bool { return shape.Name == name })
+		if i < 0 {
+			return fmt.Errorf("unknown input shape %q", name)
+		}
+		shapes = append(shapes, sorting.InputShapes[i])
+	}
+	var sizes []int
+	for _, field := range splitList(*sizeList) {
+		size, err := strconv.Atoi(field)
+		if err != nil || size < 0 {
+			return fmt.Errorf("bad size %q", field)
+		}
+		sizes = append(sizes, size)
+	}
+	// Check the format before spending the time to measure
+	if err := writeBenchResults(io.Discard, *format, nil); err != nil {
+		return err
+	}
+
+	// testing.Benchmark reads its run time from the testing flags, which
+	// exist outside go test only after testing.Init
+	testing.Init()
+	if err := flag.Set("test.benchtime", *benchtime); err != nil {
+		return fmt.Errorf("bad -benchtime: %w", err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	results := benchmark(strategies, shapes, sizes, *seed, os.Stderr)
+	return writeBenchResults(w, *format, results)
+}
+
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
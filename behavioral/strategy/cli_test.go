@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	_ "go-design-patterns/behavioral/strategy/shellsort"
+)
+
+// The extsort command, end to end, including -header
+func TestExternalSortCommand(t *testing.T) {
+	dir := t.TempDir()
+	for _, tc := range []struct {
+		name  string
+		args  []string
+		input string
+		want  string
+	}{
+		{"lines", nil, "3\n1\n\n2\n", "1\n2\n3\n"},
+		{"lines descending", []string{"-descending"}, "3\n1\n2\n", "3\n2\n1\n"},
+		{"lines with header", []string{"-header"}, "n\n3\n1\n2\n", "n\n1\n2\n3\n"},
+		{"header only", []string{"-header"}, "n", "n"},
+		{
+			"csv with header", []string{"-codec", "csv", "-header", "-column", "1", "-numeric"},
+			"name,age\nbob,42\n\"smith, al\",7\ncy,100\n",
+			"name,age\n\"smith, al\",7\nbob,42\ncy,100\n",
+		},
+		{
+			"csv by text", []string{"-codec", "csv", "-comma", ";"},
+			"b;\"x\"\"y\"\na;2\n",
+			"a;2\nb;\"x\"\"y\"\n",
+		},
+		{"fixed", []string{"-codec", "fixed", "-width", "2"}, "zzaayy", "aayyzz"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			in, out := filepath.Join(dir, "in"), filepath.Join(dir, "out")
+			if err := os.WriteFile(in, []byte(tc.input), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			args := append([]string{"-i", in, "-o", out, "-memory", "8", "-fanin", "2", "-tmp", dir}, tc.args...)
+			if err := runExternalSort(args); err != nil {
+				t.Fatal(err)
+			}
+			got, _ := os.ReadFile(out)
+			if string(got) != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+			if left, _ := filepath.Glob(filepath.Join(dir, "sort-chunk-*")); len(left) > 0 {
+				t.Errorf("chunk files left behind: %v", left)
+			}
+		})
+	}
+}
+
+// The bench command, end to end, in every format
+func TestBenchCommand(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "bench")
+	bench := func(format string) string {
+		t.Helper()
+		err := runBench([]string{"-strategies", "insertion sort, shell sort", "-shapes", "sorted", "-sizes", "10,20",
+			"-benchtime", "1x", "-format", format, "-o", out})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, _ := os.ReadFile(out)
+		return string(got)
+	}
+
+	rows, err := csv.NewReader(strings.NewReader(bench("csv"))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 5 || !slices.Equal(rows[0], benchColumns) {
+		t.Fatalf("got %d rows starting with %v", len(rows), rows[0])
+	}
+	// Sorted input takes insertion sort n-1 comparisons and no swaps; shell
+	// sort comes from elsewhere, so only its comparisons are counted
+	for _, want := range [][]string{
+		{"insertion sort", "sorted", "10", "9", "0", "0"},
+		{"shell sort", "sorted", "10", "", "-", "-"},
+		{"insertion sort", "sorted", "20", "19", "0", "0"},
+	} {
+		i := slices.IndexFunc(rows, func(row []string) bool { return slices.Equal(row[:3], want[:3]) })
+		if i < 0 {
+			t.Errorf("no row for %v", want[:3])
+			continue
+		}
+		if got := rows[i][6:]; (want[3] != "" && got[0] != want[3]) || got[1] != want[4] || got[2] != want[5] {
+			t.Errorf("%v: comparisons, swaps and moves are %v, want %v", want[:3], got, want[3:])
+		}
+	}
+
+	if text := bench("text"); !strings.Contains(text, "comparisons") || strings.Count(text, "\n") != 5 {
+		t.Errorf("text table:\n%s", text)
+	}
+	if markdown := bench("markdown"); !strings.HasPrefix(markdown, "| strategy | shape | n |") || strings.Count(markdown, "\n") != 6 {
+		t.Errorf("markdown table:\n%s", markdown)
+	}
+
+	for _, args := range [][]string{
+		{"-strategies", "no such sort"},
+		{"-shapes", "no such shape"},
+		{"-sizes", "ten"},
+		{"-format", "xml"},
+		{"-benchtime", "forever"},
+	} {
+		if err := runBench(append([]string{"-sizes", "1", "-benchtime", "1x", "-o", out}, args...)); err == nil {
+			t.Errorf("bench %v succeeded", args)
+		}
+	}
+}
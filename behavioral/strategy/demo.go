@@ -1,78 +1,42 @@
 package main
 
 import (
+	"cmp"
+	"context"
 	"fmt"
-	"sort"
-)
-
-// SortStrategy interface
-type SortStrategy interface {
-	Sort(data []int) []int
-	GetName() string
-}
+	"log/slog"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
 
-// BubbleSort strategy
-type BubbleSort struct{}
+	_ "go-design-patterns/behavioral/strategy/shellsort" // registers shell sort in sorting.Sorts
+	"go-design-patterns/behavioral/strategy/sorting"
+)
 
-func (bs BubbleSort) GetName() string {
-	return "bubble sort"
+// Employee is a record sorted by one of its fields in the demo
+type Employee struct {
+	Name string
+	Team string
+	Age  int
 }
 
-func (bs BubbleSort) Sort(data []int) []int {
-	result := make([]int, len(data))
-	copy(result, data)
-	
-	n := len(result)
-	for i := 0; i < n-1; i++ {
-		for j := 0; j < n-i-1; j++ {
-			if result[j] > result[j+1] {
-				result[j], result[j+1] = result[j+1], result[j]
-			}
+func main() {
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
 		}
+		return
 	}
-	return result
-}
-
-// QuickSort strategy (using Go's built-in sort)
-type QuickSort struct{}
-
-func (qs QuickSort) GetName() string {
-	return "quick sort"
-}
-
-func (qs QuickSort) Sort(data []int) []int {
-	result := make([]int, len(data))
-	copy(result, data)
-	sort.Ints(result)
-	return result
-}
 
-// Sorter context
-type Sorter struct {
-	strategy SortStrategy
-}
-
-func NewSorter(strategy SortStrategy) *Sorter {
-	return &Sorter{strategy: strategy}
-}
-
-func (s *Sorter) SetStrategy(strategy SortStrategy) {
-	s.strategy = strategy
-}
-
-func (s *Sorter) Sort(data []int) []int {
-	fmt.Printf("Sorting using %s\n", s.strategy.GetName())
-	return s.strategy.Sort(data)
-}
-
-func main() {
 	fmt.Println("=== Strategy Pattern Demo ===")
 	
 	smallDataset := []int{1, 3, 4, 2}
 	largeDataset := []int{1, 4, 3, 2, 8, 10, 5, 6, 9, 7}
 	
 	// Create sorter with bubble sort strategy
-	sorter := NewSorter(BubbleSort{})
+	sorter := sorting.NewSorter(sorting.BubbleSort[int]{})
 	
 	// Sort small dataset with bubble sort
 	fmt.Printf("Small dataset: %v\n", smallDataset)
@@ -83,9 +47,113 @@ func main() {
 	
 	// Switch to quick sort for large dataset
 	fmt.Printf("Large dataset: %v\n", largeDataset)
-	sorter.SetStrategy(QuickSort{})
+	sorter.SetStrategy(sorting.QuickSort[int]{})
 	sorted = sorter.Sort(largeDataset)
 	fmt.Printf("Sorted: %v\n", sorted)
 	
+
+	// Every strategy gives the same order; they differ in speed, memory and
+	// stability
+	fmt.Println()
+	for _, strategy := range sorting.Sorts.All() {
+		sorter.SetStrategy(strategy)
+		fmt.Printf("  -> %v (stable: %t)\n", sorter.Sort(largeDataset), strategy.Stable())
+	}
+
+	// Strategies are also found by name, as the sort command does for its
+	// -strategy flag and config file
+	fmt.Println()
+	fmt.Printf("Registered: %s\n", strings.Join(sorting.Sorts.Names(), ", "))
+	if strategy, err := sorting.Sorts.Lookup("Heap-Sort"); err == nil {
+		sorter.SetStrategy(strategy)
+		fmt.Printf("Sorted: %v\n", sorter.Sort(largeDataset))
+	}
+	if _, err := sorting.Sorts.Lookup("bogosort"); err != nil {
+		fmt.Println(err)
+	}
+
+	// AutoSorter looks at each input and picks the strategy for it
+	fmt.Println()
+	auto := sorting.NewIntegerAutoSorter[int]()
+	r := rand.New(rand.NewSource(1))
+	for _, input := range []struct {
+		name string
+		data []int
+	}{
+		{"small dataset", smallDataset},
+		{"100k sorted", sorting.InputShapes[1].Generate(r, 100_000)},
+		{"100k nearly sorted", sorting.InputShapes[3].Generate(r, 100_000)},
+		{"10k with many duplicates", sorting.InputShapes[4].Generate(r, 10_000)},
+		{"100k random", sorting.InputShapes[0].Generate(r, 100_000)},
+		{"100k random over a wide range", wideRange(r, 100_000)},
+	} {
+		fmt.Printf("Auto picks %s for the %s\n", auto.Choose(input.data, cmp.Compare[int]).GetName(), input.name)
+	}
+	sorter.SetStrategy(auto)
+	fmt.Printf("Sorted: %v\n", sorter.Sort(largeDataset))
+
+	// Sorting prints nothing; a hook or logger reports what was sorted how,
+	// and a context stops sorts that take too long
+	fmt.Println()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{} // keep the lines short
+			}
+			return a
+		},
+	}))
+	sorter.SetLogger(logger)
+	sorter.Sort(largeDataset)
+	sorter.SetStrategy(sorting.BubbleSort[int]{})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := sorter.SortContext(ctx, sorting.InputShapes[0].Generate(r, 200_000)); err != nil {
+		fmt.Printf("Bubble sort of 200k: %v\n", err)
+	}
+	sorter.SetHook(nil)
+
+	// Any type sorts with a comparator; stable strategies keep records with
+	// equal keys in their original order
+	fmt.Println()
+	employees := []Employee{
+		{"Ana", "platform", 34}, {"Ben", "mobile", 28}, {"Cleo", "platform", 25},
+		{"Dev", "mobile", 41}, {"Eve", "data", 30},
+	}
+	byTeam := sorting.NewSorterFunc(sorting.BubbleSort[Employee]{}, sorting.By(func(e Employee) string { return e.Team }))
+	fmt.Printf("By team, stable: %v\n", names(byTeam.Sort(employees)))
+	byTeamThenAge := sorting.NewSorterFunc(sorting.QuickSort[Employee]{},
+		sorting.By(func(e Employee) string { return e.Team }).Then(sorting.Reverse(sorting.By(func(e Employee) int { return e.Age }))))
+	fmt.Printf("By team, oldest first: %v\n", names(byTeamThenAge.Sort(employees)))
+
+	words := sorting.NewSorter(sorting.QuickSort[string]{})
+	fmt.Printf("Words: %v\n", words.Sort([]string{"strategy", "observer", "state", "builder"}))
+
+	// Data larger than memory streams through ExternalSort; this budget is
+	// small enough to spill every two rows to a temporary file
+	fmt.Println()
+	staff := "Ana,platform,34\nBen,mobile,28\nCleo,platform,25\nDev,mobile,41\nEve,data,30\n"
+	external := sorting.ExternalSort[[]string]{Codec: sorting.CSV{}, MemoryBudget: 150}
+	if err := external.SortStream(context.Background(), strings.NewReader(staff), os.Stdout, sorting.NumericColumn(2)); err != nil {
+		fmt.Println(err)
+	}
+
 	fmt.Println("\nStrategy pattern allows switching algorithms at runtime!")
 }
+
+func names(employees []Employee) []string {
+	names := make([]string, len(employees))
+	for i, e := range employees {
+		names[i] = e.Name + "/" + e.Team
+	}
+	return names
+}
+
+func wideRange(r *rand.Rand, n int) []int {
+	data := make([]int, n)
+	for i := range data {
+		data[i] = int(r.Int63())
+	}
+	return data
+}
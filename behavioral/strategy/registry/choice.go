@@ -0,0 +1,66 @@
+package registry
+
+// Choice is a strategy picked by name. It is a flag.Value, so it can back a
+// command-line flag, and it decodes from a name in JSON or any other format
+// using encoding.TextUnmarshaler, so it can sit in a config struct:
+//
+//	strategy := Sorts.Choice("merge sort")
+//	flag.Var(strategy, "strategy", "sorting strategy")
+//
+//	var config struct{ Strategy registry.Choice[SortStrategy] }
+//	config.Strategy = *Sorts.Choice("merge sort") // default
+//	json.Unmarshal(data, &config)                // {"Strategy": "heap sort"}
+//
+// Hold it by value in config structs: JSON null leaves a value untouched, but
+// sets a *Choice field to nil.
+type Choice[S any] struct {
+	registry *Registry[S]
+	name     string
+	value    S
+}
+
+// Choice returns a choice of this registry's strategies, initially the one
+// registered under def. An unknown default panics, as a misspelt default is a
+// programming error.
+func (r *Registry[S]) Choice(def string) *Choice[S] {
+	c := &Choice[S]{registry: r}
+	if err := c.Set(def); err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// Name returns the name the strategy was chosen by
+func (c *Choice[S]) Name() string {
+	return c.name
+}
+
+// Value returns the chosen strategy
+func (c *Choice[S]) Value() S {
+	return c.value
+}
+
+func (c *Choice[S]) String() string {
+	if c == nil {
+		return ""
+	}
+	return c.name
+}
+
+// Set picks the strategy registered under name
+func (c *Choice[S]) Set(name string) error {
+	value, err := c.registry.Lookup(name)
+	if err != nil {
+		return err
+	}
+	c.name, c.value = name, value
+	return nil
+}
+
+func (c *Choice[S]) MarshalText() ([]byte, error) {
+	return []byte(c.name), nil
+}
+
+func (c *Choice[S]) UnmarshalText(text []byte) error {
+	return c.Set(string(text))
+}
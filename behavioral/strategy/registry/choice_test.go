@@ -0,0 +1,47 @@
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func colours() *Registry[string] {
+	r := New[string]("colour")
+	r.MustRegister("red", func() string { return "#f00" })
+	r.MustRegister("dark blue", func() string { return "#008" })
+	return r
+}
+
+func TestChoiceInConfig(t *testing.T) {
+	r := colours()
+	for _, tc := range []struct {
+		json, name, value string
+	}{
+		{`{}`, "red", "#f00"},
+		{`{"colour": null}`, "red", "#f00"},
+		{`{"colour": "Dark-Blue"}`, "Dark-Blue", "#008"},
+	} {
+		config := struct {
+			Colour Choice[string] `json:"colour"`
+		}{Colour: *r.Choice("red")}
+		if err := json.Unmarshal([]byte(tc.json), &config); err != nil {
+			t.Fatalf("%s: %v", tc.json, err)
+		}
+		if config.Colour.Name() != tc.name || config.Colour.Value() != tc.value {
+			t.Errorf("%s: got %s (%s), want %s (%s)", tc.json, config.Colour.Name(), config.Colour.Value(), tc.name, tc.value)
+		}
+	}
+}
+
+func TestChoiceRejectsUnknownName(t *testing.T) {
+	choice := colours().Choice("red")
+	err := json.Unmarshal([]byte(`"green"`), choice)
+	var unknown *UnknownError
+	if !errors.As(err, &unknown) {
+		t.Fatalf("got %v, want an UnknownError", err)
+	}
+	if choice.Name() != "red" {
+		t.Errorf("a failed Set changed the choice to %q", choice.Name())
+	}
+}
@@ -0,0 +1,121 @@
+// Package registry lets a family of strategies register under names so they
+// can be chosen by name: from a flag, a config file or user input. Any type
+// can be a family; each registry holds one.
+//
+//	var Compressors = registry.New[Compressor]("compressor")
+//
+//	func init() {
+//		Compressors.MustRegister("gzip", func() Compressor { return Gzip{} })
+//	}
+//
+//	compressor, err := Compressors.Lookup("gzip")
+//
+// Strategies usually register themselves from an init function, which is also
+// how a third-party package plugs its own strategies in.
+package registry
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Factory creates a fresh strategy each time it is looked up
+type Factory[S any] func() S
+
+// Registry maps names to the strategies of one family. Names are matched
+// case-insensitively, and "-" and "_" match a space, so "merge sort" can be
+// given as merge-sort on a command line. It is safe for concurrent use.
+type Registry[S any] struct {
+	family string
+
+	mu        sync.RWMutex
+	factories map[string]Factory[S]
+	names     map[string]string // normalized to registered name
+}
+
+// New returns an empty registry; family names the kind of strategy it holds
+// in error messages
+func New[S any](family string) *Registry[S] {
+	return &Registry[S]{
+		family:    family,
+		factories: make(map[string]Factory[S]),
+		names:     make(map[string]string),
+	}
+}
+
+// Register adds a strategy under name. A name can only be registered once.
+func (r *Registry[S]) Register(name string, factory Factory[S]) error {
+	key := normalize(name)
+	if key == "" {
+		return fmt.Errorf("registry: %s with empty name", r.family)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if existing, ok := r.names[key]; ok {
+		return fmt.Errorf("registry: %s %q is already registered as %q", r.family, name, existing)
+	}
+	r.factories[key] = factory
+	r.names[key] = name
+	return nil
+}
+
+// MustRegister is Register for init functions: a clash between names is a
+// programming error, so it panics
+func (r *Registry[S]) MustRegister(name string, factory Factory[S]) {
+	if err := r.Register(name, factory); err != nil {
+		panic(err)
+	}
+}
+
+// Lookup creates the strategy registered under name
+func (r *Registry[S]) Lookup(name string) (S, error) {
+	r.mu.RLock()
+	factory, ok := r.factories[normalize(name)]
+	r.mu.RUnlock()
+	if !ok {
+		var zero S
+		return zero, &UnknownError{Family: r.family, Name: name, Known: r.Names()}
+	}
+	return factory(), nil
+}
+
+// Names returns the registered names, sorted
+func (r *Registry[S]) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.names))
+	for _, name := range r.names {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// All creates one of every registered strategy, in Names order
+func (r *Registry[S]) All() []S {
+	var all []S
+	for _, name := range r.Names() {
+		if strategy, err := r.Lookup(name); err == nil {
+			all = append(all, strategy)
+		}
+	}
+	return all
+}
+
+func normalize(name string) string {
+	name = strings.NewReplacer("-", " ", "_", " ").Replace(strings.ToLower(name))
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// UnknownError is returned when no strategy is registered under a name
+type UnknownError struct {
+	Family string
+	Name   string
+	Known  []string
+}
+
+func (e *UnknownError) Error() string {
+	return fmt.Sprintf("registry: unknown %s %q (known: %s)", e.Family, e.Name, strings.Join(e.Known, ", "))
+}
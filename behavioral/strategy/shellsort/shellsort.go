@@ -0,0 +1,60 @@
+// Package shellsort plugs a strategy into package sorting from the outside,
+// the way a third party would: with nothing but a type that satisfies
+// sorting.SortStrategy and an init function registering it in sorting.Sorts.
+// A program imports it for that side effect,
+//
+//	import _ "go-design-patterns/behavioral/strategy/shellsort"
+//
+// and from then on picks shell sort by name like any built-in strategy.
+package shellsort
+
+import (
+	"context"
+	"slices"
+
+	"go-design-patterns/behavioral/strategy/sorting"
+)
+
+func init() {
+	sorting.Sorts.MustRegister("shell sort", func() sorting.SortStrategy[int] { return ShellSort[int]{} })
+}
+
+// ShellSort strategy. Not stable: elements jump over others in long strides.
+// Insertion sort over ever shorter gaps, ending with a plain insertion sort.
+type ShellSort[T any] struct{}
+
+func (ss ShellSort[T]) GetName() string {
+	return "shell sort"
+}
+
+func (ss ShellSort[T]) Stable() bool {
+	return false
+}
+
+func (ss ShellSort[T]) Sort(data []T, compare sorting.Comparator[T]) []T {
+	result, _ := ss.SortContext(context.Background(), data, compare)
+	return result
+}
+
+// SortContext makes ShellSort a sorting.ContextStrategy: it checks ctx
+// before every gap pass and gives up once it is done
+func (ss ShellSort[T]) SortContext(ctx context.Context, data []T, compare sorting.Comparator[T]) ([]T, error) {
+	result := slices.Clone(data)
+	// Ciura's gap sequence, extended by a factor of 2.25
+	gaps := []int{1, 4, 10, 23, 57, 132, 301, 701}
+	for gaps[len(gaps)-1] < len(result)/2 {
+		gaps = append(gaps, gaps[len(gaps)-1]*9/4)
+	}
+	for g := len(gaps) - 1; g >= 0; g-- {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		gap := gaps[g]
+		for i := gap; i < len(result); i++ {
+			for j := i; j >= gap && compare(result[j-gap], result[j]) > 0; j -= gap {
+				result[j-gap], result[j] = result[j], result[j-gap]
+			}
+		}
+	}
+	return result, nil
+}
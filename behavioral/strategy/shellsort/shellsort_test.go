@@ -0,0 +1,86 @@
+package shellsort
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"math/rand"
+	"slices"
+	"testing"
+
+	"go-design-patterns/behavioral/strategy/sorting"
+)
+
+func TestRegisteredInSorts(t *testing.T) {
+	strategy, err := sorting.Sorts.Lookup("Shell-Sort")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := strategy.(ShellSort[int]); !ok {
+		t.Errorf("Lookup returned a %T", strategy)
+	}
+	if !slices.Contains(sorting.Sorts.Names(), "shell sort") {
+		t.Errorf("Names() = %v, want shell sort among them", sorting.Sorts.Names())
+	}
+}
+
+func TestSortsLikeSlicesSort(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, shape := range sorting.InputShapes {
+		for _, n := range []int{0, 1, 2, 5, 100, 3000} {
+			input := shape.Generate(r, n)
+			original := slices.Clone(input)
+			for _, compare := range []sorting.Comparator[int]{cmp.Compare[int], sorting.Reverse(cmp.Compare[int])} {
+				want := slices.Clone(input)
+				slices.SortFunc(want, compare)
+				if got := (ShellSort[int]{}).Sort(input, compare); !slices.Equal(got, want) {
+					t.Errorf("%d %s ints: got a different order from slices.SortFunc", n, shape.Name)
+				}
+			}
+			if !slices.Equal(input, original) {
+				t.Fatalf("%d %s ints: the input was modified", n, shape.Name)
+			}
+		}
+	}
+}
+
+// The sorting package's services work for a strategy it knows nothing about
+func TestWorksWithSorting(t *testing.T) {
+	input := rand.New(rand.NewSource(1)).Perm(5000)
+
+	instrumented := sorting.Instrument[int](ShellSort[int]{})
+	if got := instrumented.Sort(input, cmp.Compare[int]); !slices.IsSorted(got) {
+		t.Error("instrumented sort is not sorted")
+	}
+	if instrumented.Counts.Comparisons.Load() == 0 || instrumented.CountsMoves() {
+		t.Errorf("counted %d comparisons, CountsMoves() = %t; want comparisons only",
+			instrumented.Counts.Comparisons.Load(), instrumented.CountsMoves())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := sorting.NewSorter[int](ShellSort[int]{}).SortContext(ctx, input); !errors.Is(err, context.Canceled) {
+		t.Errorf("SortContext with a cancelled context = %v", err)
+	}
+}
+
+func TestSortContextStopsBetweenGaps(t *testing.T) {
+	input := rand.New(rand.NewSource(1)).Perm(5000)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	comparisons := 0
+	sorted, err := ShellSort[int]{}.SortContext(ctx, input, func(a, b int) int {
+		if comparisons++; comparisons == 100 {
+			cancel()
+		}
+		return cmp.Compare(a, b)
+	})
+	if !errors.Is(err, context.Canceled) || sorted != nil {
+		t.Errorf("got %d elements and %v, want nil and context.Canceled", len(sorted), err)
+	}
+	// The first gap is at least half the input long, so its pass compares
+	// fewer than len(input) times
+	if comparisons > len(input) {
+		t.Errorf("%d comparisons after being cancelled at 100", comparisons)
+	}
+}
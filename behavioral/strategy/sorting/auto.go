@@ -0,0 +1,192 @@
+package sorting
+
+import (
+	"cmp"
+	"math/bits"
+	"math/rand"
+	"time"
+)
+
+func init() {
+	registerSort(func() SortStrategy[int] { return NewIntegerAutoSorter[int]() })
+	registerSort(func() SortStrategy[int] {
+		auto := NewIntegerAutoSorter[int]()
+		auto.StableOnly = true
+		return auto
+	})
+}
+
+// Thresholds tune which strategy AutoSorter picks. A zero Insertion, Parallel
+// or Radix disables that choice.
+type Thresholds struct {
+	// Insertion is the largest input insertion sort is used for
+	Insertion int `json:"insertion"`
+	// Presorted is the largest fraction of neighbours out of order for which
+	// the input counts as nearly sorted and goes to merge sort, whose merges
+	// are skipped where runs are already in order
+	Presorted float64 `json:"presorted"`
+	// Parallel is the smallest input sorted on several goroutines
+	Parallel int `json:"parallel"`
+	// Radix is the smallest integer input radix sort is used for, provided
+	// its values span at most RadixMaxBytes bytes
+	Radix         int `json:"radix"`
+	RadixMaxBytes int `json:"radix_max_bytes"`
+}
+
+// DefaultThresholds are typical of a current 64-bit machine; Calibrate
+// measures the ones of the machine it runs on
+var DefaultThresholds = Thresholds{
+	Insertion:     24,
+	Presorted:     0.05,
+	Parallel:      1 << 16,
+	Radix:         512,
+	RadixMaxBytes: 4,
+}
+
+// AutoSorter is a strategy that picks another strategy for every input. It
+// looks at the input's size, how much of it is already in order and, for
+// integers, how wide a range its values span.
+type AutoSorter[T any] struct {
+	Thresholds Thresholds
+	// StableOnly restricts the choice to stable strategies
+	StableOnly bool
+
+	// set for integers only
+	radix     SortStrategy[T]
+	spanBytes func(data []T) int
+}
+
+// NewAutoSorter picks among the comparison sorts
+func NewAutoSorter[T any]() AutoSorter[T] {
+	return AutoSorter[T]{Thresholds: DefaultThresholds}
+}
+
+// NewIntegerAutoSorter can also pick radix sort, so it must only be used with
+// the natural order of the integers or its reverse
+func NewIntegerAutoSorter[T Integer]() AutoSorter[T] {
+	auto := NewAutoSorter[T]()
+	auto.radix = RadixSort[T]{}
+	auto.spanBytes = spanBytes[T]
+	return auto
+}
+
+func (a AutoSorter[T]) GetName() string {
+	if a.StableOnly {
+		return "auto (stable)"
+	}
+	return "auto"
+}
+
+func (a AutoSorter[T]) Stable() bool {
+	return a.StableOnly
+}
+
+func (a AutoSorter[T]) Sort(data []T, compare Comparator[T]) []T {
+	return a.sortProgress(data, compare, progress{})
+}
+
+func (a AutoSorter[T]) sortProgress(data []T, compare Comparator[T], p progress) []T {
+	return sortWith(a.Choose(data, compare), data, compare, p)
+}
+
+// Choose returns the strategy Sort would use for data
+func (a AutoSorter[T]) Choose(data []T, compare Comparator[T]) SortStrategy[T] {
+	t := a.Thresholds
+	n := len(data)
+	if n <= t.Insertion {
+		return InsertionSort[T]{}
+	}
+
+	descents := 0
+	for i := 1; i < n; i++ {
+		if compare(data[i-1], data[i]) > 0 {
+			descents++
+		}
+	}
+	switch {
+	case descents == 0:
+		return InsertionSort[T]{} // already sorted: a single linear pass
+	case float64(descents)/float64(n-1) <= t.Presorted:
+		return MergeSort[T]{}
+	case a.radix != nil && t.Radix > 0 && n >= t.Radix && a.spanBytes(data) <= t.RadixMaxBytes:
+		return a.radix
+	case t.Parallel > 0 && n >= t.Parallel:
+		return ParallelMergeSort[T]{}
+	case a.StableOnly:
+		return MergeSort[T]{}
+	default:
+		return IntroSort[T]{}
+	}
+}
+
+// spanBytes returns how many bytes the difference between the largest and
+// smallest value needs, which is how many passes radix sort makes
+func spanBytes[T Integer](data []T) int {
+	lo, hi := data[0], data[0]
+	for _, v := range data[1:] {
+		lo, hi = min(lo, v), max(hi, v)
+	}
+	return (bits.Len64(uint64(hi)-uint64(lo)) + 7) / 8
+}
+
+// Calibrate times the strategies on random input on this machine and returns
+// the thresholds where one starts beating another. Each measurement runs for
+// about budget, so calibration takes some 50 budgets.
+func Calibrate(budget time.Duration) Thresholds {
+	t := DefaultThresholds
+	r := rand.New(rand.NewSource(1))
+	random := func(n, limit int) []int {
+		data := make([]int, n)
+		for i := range data {
+			data[i] = r.Intn(limit)
+		}
+		return data
+	}
+	faster := func(a, b SortStrategy[int], data []int) bool {
+		return timeSort(a, data, budget) < timeSort(b, data, budget)
+	}
+
+	// Insertion sort keeps up up to a point and then loses for good.
+	// Introsort itself uses insertion sort below 12 elements, so small sizes
+	// are a tie; those count for insertion sort.
+	t.Insertion = 0
+	for n := 8; n <= 256; n *= 2 {
+		if faster(IntroSort[int]{}, InsertionSort[int]{}, random(n, n)) {
+			break
+		}
+		t.Insertion = n
+	}
+
+	// Goroutines only pay off beyond some size, if at all
+	t.Parallel = 0
+	for n := 1 << 10; n <= 1<<20; n *= 2 {
+		if faster(ParallelMergeSort[int]{}, IntroSort[int]{}, random(n, n)) {
+			t.Parallel = n
+			break
+		}
+	}
+
+	// Radix sort's passes are fixed costs that more elements amortize
+	t.Radix = 0
+	limit := 1 << (8 * min(t.RadixMaxBytes, 7))
+	for n := 64; n <= 1<<16; n *= 2 {
+		if faster(RadixSort[int]{}, IntroSort[int]{}, random(n, limit)) {
+			t.Radix = n
+			break
+		}
+	}
+	return t
+}
+
+// timeSort returns the average time strategy takes to sort data, sorting it
+// repeatedly for about budget
+func timeSort(strategy SortStrategy[int], data []int, budget time.Duration) time.Duration {
+	compare := cmp.Compare[int]
+	start := time.Now()
+	runs := 0
+	for runs == 0 || time.Since(start) < budget {
+		strategy.Sort(data, compare)
+		runs++
+	}
+	return time.Since(start) / time.Duration(runs)
+}
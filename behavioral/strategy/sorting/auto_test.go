@@ -0,0 +1,40 @@
+package sorting
+
+import (
+	"cmp"
+	"slices"
+	"testing"
+)
+
+// plainSort is a strategy from elsewhere: it cannot report swaps or moves
+type plainSort struct{}
+
+func (plainSort) GetName() string { return "plain sort" }
+func (plainSort) Stable() bool    { return true }
+
+func (plainSort) Sort(data []int, compare Comparator[int]) []int {
+	result := slices.Clone(data)
+	slices.SortStableFunc(result, compare)
+	return result
+}
+
+func TestInstrumentedAutoSorterWithForeignChoice(t *testing.T) {
+	auto := NewIntegerAutoSorter[int]()
+	auto.radix = plainSort{}
+	auto.Thresholds.Insertion = 0
+	auto.Thresholds.Radix = 1
+
+	input := []int{5, 3, 9, 1, 7, 2, 8, 4, 6, 0}
+	if chosen := auto.Choose(input, cmp.Compare[int]); chosen.GetName() != "plain sort" {
+		t.Fatalf("chose %s, want plain sort", chosen.GetName())
+	}
+
+	instrumented := Instrument[int](auto)
+	got := instrumented.Sort(input, cmp.Compare[int])
+	if !slices.IsSorted(got) {
+		t.Errorf("got %v", got)
+	}
+	if instrumented.Counts.Comparisons.Load() == 0 {
+		t.Error("comparisons were not counted")
+	}
+}
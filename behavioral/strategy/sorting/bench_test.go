@@ -0,0 +1,54 @@
+package sorting
+
+import (
+	"cmp"
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+// benchSizes are the input sizes every strategy is benchmarked on
+var benchSizes = []int{100, 1000, 10000}
+
+// BenchmarkSort runs every registered strategy on every input shape and size,
+// as sub-benchmarks named strategy/shape/size, for example
+//
+//	go test -bench 'Sort/(merge|radix)_sort/random' ./behavioral/strategy
+//
+// Besides time and allocations it reports the comparisons, swaps and moves
+// of one instrumented sort; swaps and moves only for strategies that can
+// count them.
+func BenchmarkSort(b *testing.B) {
+	for _, strategy := range Sorts.All() {
+		b.Run(strategy.GetName(), func(b *testing.B) {
+			for _, shape := range InputShapes {
+				b.Run(shape.Name, func(b *testing.B) {
+					for _, n := range benchSizes {
+						input := shape.Generate(rand.New(rand.NewSource(1)), n)
+						b.Run(strconv.Itoa(n), func(b *testing.B) {
+							benchmarkSort(b, strategy, input)
+						})
+					}
+				})
+			}
+		})
+	}
+}
+
+func benchmarkSort(b *testing.B, strategy SortStrategy[int], input []int) {
+	instrumented := Instrument(strategy)
+	instrumented.Sort(input, cmp.Compare[int])
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		strategy.Sort(input, cmp.Compare[int])
+	}
+	b.StopTimer()
+
+	b.ReportMetric(float64(instrumented.Counts.Comparisons.Load()), "comparisons/op")
+	if instrumented.CountsMoves() {
+		b.ReportMetric(float64(instrumented.Counts.Swaps.Load()), "swaps/op")
+		b.ReportMetric(float64(instrumented.Counts.Moves.Load()), "moves/op")
+	}
+}
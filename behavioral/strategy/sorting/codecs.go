@@ -0,0 +1,216 @@
+package sorting
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Codec reads and writes a stream of records, which is how ExternalSort gets
+// records in and out of files
+type Codec[T any] interface {
+	NewReader(r io.Reader) RecordReader[T]
+	NewWriter(w io.Writer) RecordWriter[T]
+	// Size estimates the bytes of memory a decoded record takes, which is
+	// what the memory budget of ExternalSort counts
+	Size(record T) int
+}
+
+// RecordReader returns io.EOF once the stream is exhausted
+type RecordReader[T any] interface {
+	Read() (T, error)
+}
+
+// RecordWriter may buffer; nothing is guaranteed written before Flush
+type RecordWriter[T any] interface {
+	Write(record T) error
+	Flush() error
+}
+
+// IntLines is the codec of decimal integers, one per line. Blank lines are
+// skipped.
+type IntLines struct{}
+
+func (IntLines) NewReader(r io.Reader) RecordReader[int] {
+	return &intLineReader{scanner: bufio.NewScanner(r)}
+}
+
+func (IntLines) NewWriter(w io.Writer) RecordWriter[int] {
+	return &intLineWriter{w: bufio.NewWriter(w)}
+}
+
+func (IntLines) Size(int) int {
+	return strconv.IntSize / 8
+}
+
+type intLineReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func (r *intLineReader) Read() (int, error) {
+	for r.scanner.Scan() {
+		r.line++
+		field := strings.TrimSpace(r.scanner.Text())
+		if field == "" {
+			continue
+		}
+		v, err := strconv.Atoi(field)
+		if err != nil {
+			return 0, fmt.Errorf("line %d: %w", r.line, err)
+		}
+		return v, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, io.EOF
+}
+
+type intLineWriter struct {
+	w   *bufio.Writer
+	buf []byte
+}
+
+func (w *intLineWriter) Write(v int) error {
+	w.buf = append(strconv.AppendInt(w.buf[:0], int64(v), 10), '\n')
+	_, err := w.w.Write(w.buf)
+	return err
+}
+
+func (w *intLineWriter) Flush() error {
+	return w.w.Flush()
+}
+
+// CSV is the codec of comma-separated rows, as encoding/csv reads and writes
+// them. Rows may have different numbers of fields. Sort rows with Column or
+// NumericColumn.
+type CSV struct {
+	// Comma separates fields; 0 means ','
+	Comma rune
+}
+
+func (c CSV) NewReader(r io.Reader) RecordReader[[]string] {
+	reader := csv.NewReader(r)
+	if c.Comma != 0 {
+		reader.Comma = c.Comma
+	}
+	reader.FieldsPerRecord = -1
+	return reader
+}
+
+func (c CSV) NewWriter(w io.Writer) RecordWriter[[]string] {
+	writer := csv.NewWriter(w)
+	if c.Comma != 0 {
+		writer.Comma = c.Comma
+	}
+	return csvWriter{writer}
+}
+
+func (CSV) Size(row []string) int {
+	size := 24 // the slice header
+	for _, field := range row {
+		size += 16 + len(field)
+	}
+	return size
+}
+
+type csvWriter struct {
+	*csv.Writer
+}
+
+func (w csvWriter) Flush() error {
+	w.Writer.Flush()
+	return w.Writer.Error()
+}
+
+// Column orders CSV rows by the text of one field, counted from 0; rows too
+// short to have it sort as if it were empty
+func Column(i int) Comparator[[]string] {
+	return By(func(row []string) string { return field(row, i) })
+}
+
+// NumericColumn orders CSV rows by the number in one field. Fields that are
+// not numbers sort after all numbers, by their text.
+func NumericColumn(i int) Comparator[[]string] {
+	return func(a, b []string) int {
+		fa, fb := field(a, i), field(b, i)
+		na, errA := strconv.ParseFloat(strings.TrimSpace(fa), 64)
+		nb, errB := strconv.ParseFloat(strings.TrimSpace(fb), 64)
+		switch {
+		case errA == nil && errB == nil:
+			return cmp.Compare(na, nb)
+		case errA == nil:
+			return -1
+		case errB == nil:
+			return 1
+		default:
+			return strings.Compare(fa, fb)
+		}
+	}
+}
+
+func field(row []string, i int) string {
+	if i < len(row) {
+		return row[i]
+	}
+	return ""
+}
+
+// FixedWidth is the codec of binary records of Width bytes each, with no
+// separators. Sort them with bytes.Compare, which orders big-endian unsigned
+// integers by value, or with By on a decoded key.
+type FixedWidth struct {
+	Width int
+}
+
+func (f FixedWidth) NewReader(r io.Reader) RecordReader[[]byte] {
+	return fixedReader{r: bufio.NewReader(r), width: f.Width}
+}
+
+func (f FixedWidth) NewWriter(w io.Writer) RecordWriter[[]byte] {
+	return fixedWriter{w: bufio.NewWriter(w), width: f.Width}
+}
+
+func (f FixedWidth) Size([]byte) int {
+	return 24 + f.Width
+}
+
+type fixedReader struct {
+	r     *bufio.Reader
+	width int
+}
+
+func (r fixedReader) Read() ([]byte, error) {
+	if r.width <= 0 {
+		return nil, fmt.Errorf("fixed-width records need a positive width, not %d", r.width)
+	}
+	record := make([]byte, r.width)
+	_, err := io.ReadFull(r.r, record)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, fmt.Errorf("truncated record: the input is not a multiple of %d bytes", r.width)
+	}
+	return record, err
+}
+
+type fixedWriter struct {
+	w     *bufio.Writer
+	width int
+}
+
+func (w fixedWriter) Write(record []byte) error {
+	if len(record) != w.width {
+		return fmt.Errorf("record of %d bytes, want %d", len(record), w.width)
+	}
+	_, err := w.w.Write(record)
+	return err
+}
+
+func (w fixedWriter) Flush() error {
+	return w.w.Flush()
+}
@@ -0,0 +1,45 @@
+package sorting
+
+import (
+	"cmp"
+
+	"go-design-patterns/behavioral/strategy/registry"
+)
+
+// Orders is a second family of strategies in the same kind of registry as
+// Sorts: the orders the sort command can put ints in
+var Orders = registry.New[Comparator[int]]("order")
+
+func init() {
+	Orders.MustRegister("ascending", func() Comparator[int] { return cmp.Compare[int] })
+	Orders.MustRegister("descending", func() Comparator[int] { return Reverse(cmp.Compare[int]) })
+}
+
+// Comparator orders two values: negative when a sorts before b, zero when
+// they are equal and positive when a sorts after b. cmp.Compare is the
+// comparator of every ordered type.
+type Comparator[T any] func(a, b T) int
+
+// By orders values by a key extracted from them, such as a struct field
+func By[T any, K cmp.Ordered](key func(T) K) Comparator[T] {
+	return func(a, b T) int {
+		return cmp.Compare(key(a), key(b))
+	}
+}
+
+// Reverse flips an order
+func Reverse[T any](compare Comparator[T]) Comparator[T] {
+	return func(a, b T) int {
+		return compare(b, a)
+	}
+}
+
+// Then breaks the ties of compare with next
+func (compare Comparator[T]) Then(next Comparator[T]) Comparator[T] {
+	return func(a, b T) int {
+		if c := compare(a, b); c != 0 {
+			return c
+		}
+		return next(a, b)
+	}
+}
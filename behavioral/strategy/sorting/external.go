@@ -0,0 +1,285 @@
+package sorting
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"io"
+	"os"
+)
+
+// ExternalSort strategy, for data larger than memory. SortStream reads
+// records from an io.Reader until MemoryBudget is used up, sorts that chunk
+// and writes it to a temporary file; then it merges the sorted chunk files
+// into an io.Writer. Stable when Strategy is: ties in the merge go to the
+// earlier chunk.
+//
+// Only SortStream goes through files. Data handed to Sort is in memory
+// already, so Sort just sorts it with Strategy. ExternalSort is therefore not
+// in the Sorts registry: as a plain SortStrategy it would be merge sort by
+// another name.
+type ExternalSort[T any] struct {
+	// Codec reads the input, writes the output and stores the chunks
+	Codec Codec[T]
+	// MemoryBudget is the bytes of records, as Codec.Size counts them, read
+	// into memory before they are sorted and spilled; 0 means 64 MiB. Peak
+	// memory is about three times the budget: the records, the sorted copy
+	// every strategy makes, and the scratch space of MergeSort. Records can
+	// also take more memory than Codec.Size counts.
+	MemoryBudget int
+	// Strategy sorts each chunk in memory; nil means MergeSort
+	Strategy SortStrategy[T]
+	// MaxFanIn is the number of chunk files merged, and so open, at once;
+	// more chunks are merged in several passes. 0 means 64.
+	MaxFanIn int
+	// TempDir holds the chunk files; "" means os.TempDir()
+	TempDir string
+}
+
+func (es ExternalSort[T]) GetName() string {
+	return "external merge sort"
+}
+
+func (es ExternalSort[T]) Stable() bool {
+	return es.strategy().Stable()
+}
+
+// Sort sorts data in memory with Strategy. Use SortStream for data that does
+// not fit.
+func (es ExternalSort[T]) Sort(data []T, compare Comparator[T]) []T {
+	return es.strategy().Sort(data, compare)
+}
+
+// SortStream sorts the records read from r into w, using temporary files
+// for whatever does not fit in the memory budget. It gives up once ctx is
+// done, returning its error. The temporary files are removed either way.
+func (es ExternalSort[T]) SortStream(ctx context.Context, r io.Reader, w io.Writer, compare Comparator[T]) error {
+	// Chunks are kept as paths and only opened while they are merged, so at
+	// most MaxFanIn of them are open at a time
+	var chunks []string
+	defer func() {
+		removeChunks(chunks)
+	}()
+
+	reader := es.Codec.NewReader(r)
+	var records []T
+	size := 0
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		records = append(records, record)
+		size += es.Codec.Size(record)
+		if size >= es.budget() {
+			chunk, err := es.spill(ctx, records, compare)
+			if err != nil {
+				return err
+			}
+			chunks = append(chunks, chunk)
+			records, size = records[:0], 0
+		}
+	}
+
+	writer := es.Codec.NewWriter(w)
+	if len(chunks) == 0 {
+		// Everything fit in memory
+		sorted, err := sortContext(ctx, es.strategy(), records, compare)
+		if err != nil {
+			return err
+		}
+		return writeAll(writer, sorted)
+	}
+	if len(records) > 0 {
+		chunk, err := es.spill(ctx, records, compare)
+		if err != nil {
+			return err
+		}
+		chunks = append(chunks, chunk)
+	}
+
+	// Merge in passes until the remaining chunks can be merged at once
+	for len(chunks) > es.fanIn() {
+		var merged []string
+		for len(chunks) > 0 {
+			group := chunks[:min(es.fanIn(), len(chunks))]
+			chunk, err := es.mergeToChunk(ctx, group, compare)
+			if err != nil {
+				removeChunks(merged)
+				return err
+			}
+			removeChunks(group)
+			chunks = chunks[len(group):]
+			merged = append(merged, chunk)
+		}
+		chunks = merged
+	}
+	return es.merge(ctx, chunks, writer, compare)
+}
+
+func (es ExternalSort[T]) strategy() SortStrategy[T] {
+	if es.Strategy == nil {
+		return MergeSort[T]{}
+	}
+	return es.Strategy
+}
+
+func (es ExternalSort[T]) budget() int {
+	if es.MemoryBudget <= 0 {
+		return 64 << 20
+	}
+	return es.MemoryBudget
+}
+
+func (es ExternalSort[T]) fanIn() int {
+	if es.MaxFanIn < 2 {
+		return 64
+	}
+	return es.MaxFanIn
+}
+
+// spill sorts records into a new chunk file and returns its path
+func (es ExternalSort[T]) spill(ctx context.Context, records []T, compare Comparator[T]) (string, error) {
+	sorted, err := sortContext(ctx, es.strategy(), records, compare)
+	if err != nil {
+		return "", err
+	}
+	return es.newChunk(func(writer RecordWriter[T]) error {
+		return writeAll(writer, sorted)
+	})
+}
+
+// mergeToChunk merges chunks into a new chunk file and returns its path
+func (es ExternalSort[T]) mergeToChunk(ctx context.Context, chunks []string, compare Comparator[T]) (string, error) {
+	return es.newChunk(func(writer RecordWriter[T]) error {
+		return es.merge(ctx, chunks, writer, compare)
+	})
+}
+
+// newChunk writes a new chunk file and closes it again
+func (es ExternalSort[T]) newChunk(write func(RecordWriter[T]) error) (string, error) {
+	file, err := os.CreateTemp(es.TempDir, "sort-chunk-*")
+	if err != nil {
+		return "", err
+	}
+	err = write(es.Codec.NewWriter(file))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
+
+// merge k-way merges the sorted chunks into writer and flushes it. The
+// chunks are open until it returns.
+func (es ExternalSort[T]) merge(ctx context.Context, chunks []string, writer RecordWriter[T], compare Comparator[T]) error {
+	h := &mergeHeap[T]{compare: compare}
+	for i, path := range chunks {
+		chunk, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer chunk.Close()
+		c := &chunkCursor[T]{reader: es.Codec.NewReader(chunk), index: i}
+		if ok, err := c.next(); err != nil {
+			return err
+		} else if ok {
+			h.cursors = append(h.cursors, c)
+		}
+	}
+	heap.Init(h)
+
+	for written := 0; h.Len() > 0; written++ {
+		if written%4096 == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		c := h.cursors[0]
+		if err := writer.Write(c.record); err != nil {
+			return err
+		}
+		ok, err := c.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			heap.Fix(h, 0)
+		} else {
+			heap.Pop(h)
+		}
+	}
+	return writer.Flush()
+}
+
+// chunkCursor is the next unmerged record of a chunk
+type chunkCursor[T any] struct {
+	reader RecordReader[T]
+	record T
+	index  int // breaks ties in favour of earlier chunks, for stability
+}
+
+func (c *chunkCursor[T]) next() (bool, error) {
+	record, err := c.reader.Read()
+	if errors.Is(err, io.EOF) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	c.record = record
+	return true, nil
+}
+
+// mergeHeap is a container/heap of chunk cursors, smallest record on top
+type mergeHeap[T any] struct {
+	cursors []*chunkCursor[T]
+	compare Comparator[T]
+}
+
+func (h *mergeHeap[T]) Len() int {
+	return len(h.cursors)
+}
+
+func (h *mergeHeap[T]) Less(i, j int) bool {
+	a, b := h.cursors[i], h.cursors[j]
+	if c := h.compare(a.record, b.record); c != 0 {
+		return c < 0
+	}
+	return a.index < b.index
+}
+
+func (h *mergeHeap[T]) Swap(i, j int) {
+	h.cursors[i], h.cursors[j] = h.cursors[j], h.cursors[i]
+}
+
+func (h *mergeHeap[T]) Push(x any) {
+	h.cursors = append(h.cursors, x.(*chunkCursor[T]))
+}
+
+func (h *mergeHeap[T]) Pop() any {
+	last := h.cursors[len(h.cursors)-1]
+	h.cursors = h.cursors[:len(h.cursors)-1]
+	return last
+}
+
+func removeChunks(chunks []string) {
+	for _, chunk := range chunks {
+		os.Remove(chunk)
+	}
+}
+
+func writeAll[T any](writer RecordWriter[T], records []T) error {
+	for _, record := range records {
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
@@ -0,0 +1,198 @@
+package sorting
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// smallExternal has a budget of 64 records and merges 4 chunks at a time, so
+// a few hundred records spill to several chunk files merged in several passes
+func smallExternal[T any](t *testing.T, codec Codec[T]) ExternalSort[T] {
+	return ExternalSort[T]{Codec: codec, MemoryBudget: 64 * codec.Size(*new(T)), MaxFanIn: 4, TempDir: t.TempDir()}
+}
+
+// sortStream encodes records with codec, sorts them through files and
+// decodes the result
+func sortStream[T any](t *testing.T, es ExternalSort[T], records []T, compare Comparator[T]) []T {
+	t.Helper()
+	var in, out bytes.Buffer
+	if err := writeAll(es.Codec.NewWriter(&in), records); err != nil {
+		t.Fatal(err)
+	}
+	if err := es.SortStream(context.Background(), &in, &out, compare); err != nil {
+		t.Fatal(err)
+	}
+	reader := es.Codec.NewReader(&out)
+	var got []T
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, record)
+	}
+	assertNoChunks(t, es.TempDir)
+	return got
+}
+
+func assertNoChunks(t *testing.T, dir string) {
+	t.Helper()
+	if left, _ := filepath.Glob(filepath.Join(dir, "sort-chunk-*")); len(left) > 0 {
+		t.Errorf("chunk files left behind: %v", left)
+	}
+}
+
+func TestSortStreamSortsLikeSlicesSort(t *testing.T) {
+	r := random(t)
+	for _, shape := range InputShapes {
+		for _, n := range testSizes(r) {
+			input := shape.Generate(r, n)
+			want := slices.Clone(input)
+			slices.Sort(want)
+			got := sortStream(t, smallExternal[int](t, IntLines{}), input, cmp.Compare[int])
+			if !slices.Equal(got, want) {
+				t.Fatalf("%d %s ints: got %v, want %v", n, shape.Name, preview(got), preview(want))
+			}
+		}
+	}
+}
+
+// Rows with equal keys must stay in input order, across chunk files and
+// merge passes
+func TestSortStreamIsStable(t *testing.T) {
+	r := random(t)
+	for _, shape := range InputShapes {
+		for _, n := range testSizes(r) {
+			input := shape.Generate(r, n)
+			rows := make([][]string, len(input))
+			for i, key := range input {
+				rows[i] = []string{strconv.Itoa(key), strconv.Itoa(i)}
+			}
+			got := sortStream(t, smallExternal[[]string](t, CSV{}), rows, NumericColumn(0))
+			if len(got) != len(rows) {
+				t.Fatalf("%d %s rows: got %d rows back", n, shape.Name, len(got))
+			}
+			for i := 1; i < len(got); i++ {
+				if c := NumericColumn(0)(got[i-1], got[i]); c > 0 {
+					t.Fatalf("%d %s rows: keys out of order at %d", n, shape.Name, i)
+				} else if c == 0 && NumericColumn(1)(got[i-1], got[i]) > 0 {
+					t.Fatalf("%d %s rows: reordered equal keys at %d", n, shape.Name, i)
+				}
+			}
+		}
+	}
+}
+
+// Fields with separators, quotes and line breaks must survive the round
+// trips through chunk files
+func TestSortStreamCSVQuoting(t *testing.T) {
+	awkward := []string{`plain`, `with, comma`, `with "quotes"`, "with\nnewline", `"`, ``, ` padded `}
+	var rows [][]string
+	for i := 0; i < 300; i++ {
+		rows = append(rows, []string{strconv.Itoa((i * 7919) % 300), awkward[i%len(awkward)]})
+	}
+	want := slices.Clone(rows)
+	slices.SortStableFunc(want, NumericColumn(0))
+
+	for _, codec := range []CSV{{}, {Comma: ';'}, {Comma: '\t'}} {
+		got := sortStream(t, smallExternal[[]string](t, codec), rows, NumericColumn(0))
+		if !slices.EqualFunc(got, want, slices.Equal[[]string]) {
+			t.Errorf("comma %q: got %q, want %q", codec.Comma, preview(got), preview(want))
+		}
+	}
+}
+
+func TestSortStreamFixedWidth(t *testing.T) {
+	r := random(t)
+	records := make([][]byte, 500)
+	for i := range records {
+		records[i] = binary.BigEndian.AppendUint32(nil, r.Uint32())
+	}
+	want := slices.Clone(records)
+	slices.SortFunc(want, bytes.Compare)
+
+	got := sortStream(t, smallExternal[[]byte](t, FixedWidth{Width: 4}), records, bytes.Compare)
+	if !slices.EqualFunc(got, want, bytes.Equal) {
+		t.Errorf("got %v, want %v", preview(got), preview(want))
+	}
+}
+
+func TestSortStreamTruncatedFixedWidth(t *testing.T) {
+	for _, size := range []int{1, 4*100 + 3, 4*1000 + 1} {
+		es := smallExternal[[]byte](t, FixedWidth{Width: 4})
+		err := es.SortStream(context.Background(), bytes.NewReader(make([]byte, size)), &bytes.Buffer{}, bytes.Compare)
+		if err == nil || !strings.Contains(err.Error(), "truncated record") {
+			t.Errorf("%d bytes: got %v, want a truncated record error", size, err)
+		}
+		assertNoChunks(t, es.TempDir)
+	}
+}
+
+func TestSortStreamBadInput(t *testing.T) {
+	es := smallExternal[int](t, IntLines{})
+	input := strings.Repeat("1\n", 500) + "x\n"
+	err := es.SortStream(context.Background(), strings.NewReader(input), &bytes.Buffer{}, cmp.Compare[int])
+	if err == nil || !strings.Contains(err.Error(), "line 501") {
+		t.Errorf("got %v, want an error on line 501", err)
+	}
+	assertNoChunks(t, es.TempDir)
+}
+
+func TestSortStreamCancelled(t *testing.T) {
+	es := smallExternal[int](t, IntLines{})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	input := strings.Repeat("2\n1\n", 1000)
+	if err := es.SortStream(ctx, strings.NewReader(input), &bytes.Buffer{}, cmp.Compare[int]); !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want context.Canceled", err)
+	}
+	assertNoChunks(t, es.TempDir)
+}
+
+// Chunk files are closed once written and reopened only while their merge
+// group runs, so no more than MaxFanIn are open however many there are
+func TestSortStreamOpensAtMostFanInChunks(t *testing.T) {
+	openFiles := func() int {
+		fds, err := os.ReadDir("/proc/self/fd")
+		if err != nil {
+			t.Skip("cannot count open files:", err)
+		}
+		return len(fds)
+	}
+	es := smallExternal[int](t, IntLines{})
+	// Opening a file once sets up whatever the runtime keeps open for files
+	if warmup, err := os.CreateTemp(es.TempDir, "warmup-*"); err == nil {
+		warmup.Close()
+	}
+	before := openFiles()
+
+	// 40 chunks of 64 records, merged 4 at a time
+	input := random(t).Perm(40 * 64)
+	most := 0
+	got := sortStream(t, es, input, func(a, b int) int {
+		if a%64 == 0 {
+			most = max(most, openFiles()-before)
+		}
+		return cmp.Compare(a, b)
+	})
+	if !slices.IsSorted(got) || len(got) != len(input) {
+		t.Fatalf("got %v", preview(got))
+	}
+	// The chunks of one group, and the chunk they are merged into
+	if most > es.MaxFanIn+1 {
+		t.Errorf("%d files open at once, want at most %d", most, es.MaxFanIn+1)
+	}
+}
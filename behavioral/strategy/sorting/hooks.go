@@ -0,0 +1,82 @@
+package sorting
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// SortEvent describes a sort run by a Sorter
+type SortEvent struct {
+	// Strategy is the Sorter's strategy and Chosen the one that actually
+	// sorts, which differ when Strategy picks one per input as AutoSorter does
+	Strategy string
+	Chosen   string
+	N        int
+	// Duration and Err are only set once the sort finished; Err is the
+	// context's error when the sort was cancelled
+	Duration time.Duration
+	Err      error
+}
+
+// Hook is told when a Sorter starts and finishes a sort. It runs on the
+// goroutine calling Sort.
+type Hook interface {
+	SortStarted(e SortEvent)
+	SortFinished(e SortEvent)
+}
+
+// LogHook logs sorts to Logger, or to slog.Default() when it is nil: starts
+// and finishes at debug level, cancellations at info level
+type LogHook struct {
+	Logger *slog.Logger
+}
+
+func (h LogHook) SortStarted(e SortEvent) {
+	h.logger().Debug("sort started", "strategy", e.Strategy, "chosen", e.Chosen, "n", e.N)
+}
+
+func (h LogHook) SortFinished(e SortEvent) {
+	if e.Err != nil {
+		h.logger().Info("sort cancelled", "strategy", e.Strategy, "chosen", e.Chosen, "n", e.N,
+			"duration", e.Duration, "err", e.Err)
+		return
+	}
+	h.logger().Debug("sort finished", "strategy", e.Strategy, "chosen", e.Chosen, "n", e.N, "duration", e.Duration)
+}
+
+func (h LogHook) logger() *slog.Logger {
+	if h.Logger == nil {
+		return slog.Default()
+	}
+	return h.Logger
+}
+
+// chooser is implemented by strategies that pick another strategy per input
+type chooser[T any] interface {
+	Choose(data []T, compare Comparator[T]) SortStrategy[T]
+}
+
+// ContextStrategy is implemented by strategies from elsewhere that can stop
+// early. Sorter.SortContext calls SortContext instead of Sort; it must
+// return ctx's error, and no result, once it gives up.
+type ContextStrategy[T any] interface {
+	SortStrategy[T]
+	SortContext(ctx context.Context, data []T, compare Comparator[T]) ([]T, error)
+}
+
+// sortContext runs strategy until it finishes or ctx is done. The strategies
+// of this package check ctx between passes, a ContextStrategy checks it
+// itself, and any other strategy runs to the end before ctx is looked at.
+// Strategies sort a copy, so data is unchanged however far the sort got.
+func sortContext[T any](ctx context.Context, strategy SortStrategy[T], data []T, compare Comparator[T]) ([]T, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	p := progress{ctx: ctx}
+	result := sortWith(strategy, data, compare, p)
+	if p.stopped() {
+		return nil, ctx.Err()
+	}
+	return result, nil
+}
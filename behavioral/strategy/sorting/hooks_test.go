@@ -0,0 +1,126 @@
+package sorting
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"math/rand"
+	"slices"
+	"sync/atomic"
+	"testing"
+)
+
+// backgroundSort compares on a goroutine of its own and checks ctx between
+// passes of its insertion sort
+type backgroundSort struct{}
+
+func (backgroundSort) GetName() string { return "background sort" }
+func (backgroundSort) Stable() bool    { return true }
+
+func (b backgroundSort) Sort(data []int, compare Comparator[int]) []int {
+	result, _ := b.SortContext(context.Background(), data, compare)
+	return result
+}
+
+func (backgroundSort) SortContext(ctx context.Context, data []int, compare Comparator[int]) ([]int, error) {
+	result := slices.Clone(data)
+	done := make(chan error, 1)
+	go func() {
+		for i := 1; i < len(result); i++ {
+			if err := ctx.Err(); err != nil {
+				done <- err
+				return
+			}
+			for j := i; j > 0 && compare(result[j-1], result[j]) > 0; j-- {
+				result[j-1], result[j] = result[j], result[j-1]
+			}
+		}
+		done <- nil
+	}()
+	if err := <-done; err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// cancelAfter returns a comparator that cancels the sort after n comparisons
+func cancelAfter(n int64, cancel context.CancelFunc) Comparator[int] {
+	var calls atomic.Int64
+	return func(a, b int) int {
+		if calls.Add(1) == n {
+			cancel()
+		}
+		return cmp.Compare(a, b)
+	}
+}
+
+func TestSortContextUsesContextStrategy(t *testing.T) {
+	input := rand.New(rand.NewSource(1)).Perm(2_000)
+
+	sorted, err := NewSorter[int](backgroundSort{}).SortContext(context.Background(), input)
+	if err != nil || !slices.IsSorted(sorted) {
+		t.Fatalf("got sorted=%t, err=%v", slices.IsSorted(sorted), err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sorted, err = NewSorterFunc[int](backgroundSort{}, cancelAfter(100, cancel)).SortContext(ctx, input)
+	if !errors.Is(err, context.Canceled) || sorted != nil {
+		t.Errorf("got %d elements and %v, want nil and context.Canceled", len(sorted), err)
+	}
+}
+
+// A cancelled sort returns ctx's error, and leaves the input as it was
+func TestCancelledSortLeavesInputUntouched(t *testing.T) {
+	input := rand.New(rand.NewSource(1)).Perm(20_000)
+	original := slices.Clone(input)
+	for _, strategy := range append(Sorts.All(), ParallelMergeSort[int]{Threshold: 64}, backgroundSort{}, plainSort{}) {
+		ctx, cancel := context.WithCancel(context.Background())
+		sorted, err := NewSorterFunc(strategy, cancelAfter(1000, cancel)).SortContext(ctx, input)
+		cancel()
+		if !errors.Is(err, context.Canceled) || sorted != nil {
+			t.Errorf("%s: got %d elements and %v, want nil and context.Canceled", strategy.GetName(), len(sorted), err)
+		}
+		if !slices.Equal(input, original) {
+			t.Fatalf("%s: cancelled sort modified its input", strategy.GetName())
+		}
+	}
+}
+
+// The strategies of this package give up within a pass of being cancelled
+func TestCancelledSortStopsEarly(t *testing.T) {
+	input := rand.New(rand.NewSource(1)).Perm(20_000)
+	for _, strategy := range append(Sorts.All(), ParallelMergeSort[int]{Threshold: 64}) {
+		ctx, cancel := context.WithCancel(context.Background())
+		counted := Instrument(strategy)
+		_, err := NewSorterFunc[int](counted, cancelAfter(1000, cancel)).SortContext(ctx, input)
+		cancel()
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("%s: err = %v, want context.Canceled", strategy.GetName(), err)
+		}
+		if got := counted.Counts.Comparisons.Load(); got > 2*int64(len(input)) {
+			t.Errorf("%s: %d comparisons after being cancelled at 1000", strategy.GetName(), got)
+		}
+	}
+}
+
+// Only cancellation is turned into an error; a panicking comparator still
+// panics, on the caller's goroutine even for ParallelMergeSort
+func TestComparatorPanicIsNotCancellation(t *testing.T) {
+	input := rand.New(rand.NewSource(1)).Perm(10_000)
+	for _, strategy := range []SortStrategy[int]{MergeSort[int]{}, ParallelMergeSort[int]{Threshold: 64}} {
+		func() {
+			defer func() {
+				if r := recover(); r != "broken comparator" {
+					t.Errorf("%s: recovered %v, want the comparator's panic", strategy.GetName(), r)
+				}
+			}()
+			NewSorterFunc(strategy, func(a, b int) int {
+				if a == 0 || b == 0 {
+					panic("broken comparator")
+				}
+				return cmp.Compare(a, b)
+			}).SortContext(context.Background(), input)
+		}()
+	}
+}
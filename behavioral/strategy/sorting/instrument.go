@@ -0,0 +1,107 @@
+package sorting
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Counts tallies the work a sort does. Swaps exchange two elements; moves
+// copy one element, as merge and radix sort do instead of swapping.
+type Counts struct {
+	Comparisons atomic.Int64
+	Swaps       atomic.Int64
+	Moves       atomic.Int64
+}
+
+// swap and move are no-ops on a nil *Counts, which is what the strategies
+// pass when they are not instrumented
+func (c *Counts) swap() {
+	if c != nil {
+		c.Swaps.Add(1)
+	}
+}
+
+func (c *Counts) move(n int) {
+	if c != nil {
+		c.Moves.Add(int64(n))
+	}
+}
+
+// progress is threaded through the strategies of this package: the counts
+// to record swaps and moves in when instrumented, and the context to give up
+// on when the sort can be cancelled. Either may be nil.
+type progress struct {
+	*Counts
+	ctx context.Context
+}
+
+// stopped reports whether the sort should give up. Strategies check it
+// between passes and return whatever they have; sortContext then discards it.
+func (p progress) stopped() bool {
+	if p.ctx == nil {
+		return false
+	}
+	return p.ctx.Err() != nil
+}
+
+// progressStrategy is implemented by strategies able to report their swaps
+// and moves and to stop early
+type progressStrategy[T any] interface {
+	sortProgress(data []T, compare Comparator[T], p progress) []T
+}
+
+// sortWith sorts with strategy, handing p on to it as far as it can take it.
+// A ContextStrategy gets the context but reports no swaps or moves; any other
+// strategy from elsewhere gets neither and runs to the end.
+func sortWith[T any](strategy SortStrategy[T], data []T, compare Comparator[T], p progress) []T {
+	switch s := strategy.(type) {
+	case progressStrategy[T]:
+		return s.sortProgress(data, compare, p)
+	case ContextStrategy[T]:
+		if p.ctx != nil {
+			result, _ := s.SortContext(p.ctx, data, compare)
+			return result
+		}
+	}
+	return strategy.Sort(data, compare)
+}
+
+// Instrumented wraps a strategy to count the comparisons it makes and, for
+// the strategies in this package, its swaps and moves too. The zero value
+// with Strategy set is ready to use; it must not be copied once it counted.
+type Instrumented[T any] struct {
+	Strategy SortStrategy[T]
+	Counts   Counts
+}
+
+func Instrument[T any](strategy SortStrategy[T]) *Instrumented[T] {
+	return &Instrumented[T]{Strategy: strategy}
+}
+
+func (in *Instrumented[T]) GetName() string {
+	return in.Strategy.GetName()
+}
+
+func (in *Instrumented[T]) Stable() bool {
+	return in.Strategy.Stable()
+}
+
+func (in *Instrumented[T]) Sort(data []T, compare Comparator[T]) []T {
+	return in.sortProgress(data, compare, progress{})
+}
+
+func (in *Instrumented[T]) sortProgress(data []T, compare Comparator[T], p progress) []T {
+	counted := func(a, b T) int {
+		in.Counts.Comparisons.Add(1)
+		return compare(a, b)
+	}
+	p.Counts = &in.Counts
+	return sortWith(in.Strategy, data, counted, p)
+}
+
+// CountsMoves reports whether Swaps and Moves are counted; for strategies
+// from elsewhere only comparisons are
+func (in *Instrumented[T]) CountsMoves() bool {
+	_, ok := in.Strategy.(progressStrategy[T])
+	return ok
+}
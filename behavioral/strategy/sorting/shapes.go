@@ -0,0 +1,60 @@
+package sorting
+
+import "math/rand"
+
+// InputShape generates test and benchmark data with a particular order
+type InputShape struct {
+	Name     string
+	Generate func(r *rand.Rand, n int) []int
+}
+
+// InputShapes are the orders the property tests, the benchmarks and the demo
+// try every strategy on
+var InputShapes = []InputShape{
+	{"random", func(r *rand.Rand, n int) []int {
+		data := make([]int, n)
+		for i := range data {
+			data[i] = r.Intn(2*n+1) - n
+		}
+		return data
+	}},
+	{"sorted", func(r *rand.Rand, n int) []int {
+		data := make([]int, n)
+		for i := range data {
+			data[i] = i
+		}
+		return data
+	}},
+	{"reversed", func(r *rand.Rand, n int) []int {
+		data := make([]int, n)
+		for i := range data {
+			data[i] = n - i
+		}
+		return data
+	}},
+	{"nearly sorted", func(r *rand.Rand, n int) []int {
+		data := make([]int, n)
+		for i := range data {
+			data[i] = i
+		}
+		for swaps := n / 100; swaps > 0; swaps-- {
+			i, j := r.Intn(n), r.Intn(n)
+			data[i], data[j] = data[j], data[i]
+		}
+		return data
+	}},
+	{"many duplicates", func(r *rand.Rand, n int) []int {
+		data := make([]int, n)
+		for i := range data {
+			data[i] = r.Intn(4)
+		}
+		return data
+	}},
+	{"all equal", func(r *rand.Rand, n int) []int {
+		data := make([]int, n)
+		for i := range data {
+			data[i] = 7
+		}
+		return data
+	}},
+}
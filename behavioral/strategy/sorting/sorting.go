@@ -0,0 +1,134 @@
+// Package sorting is a family of interchangeable sorting strategies: the
+// SortStrategy interface, the strategies themselves, the Sorter context that
+// runs them, and the Sorts registry that picks them by name. Other packages
+// register strategies of their own in Sorts from an init function.
+package sorting
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"slices"
+	"time"
+
+	"go-design-patterns/behavioral/strategy/registry"
+)
+
+// SortStrategy interface
+//
+// A strategy sorts a copy of data in the order given by compare, leaving data
+// itself untouched. Stable strategies keep equal elements in their original
+// order, which matters when sorting records by one of their fields.
+//
+// Sorter.SortContext stops the strategies of this package between passes
+// once its context is done. Strategies from elsewhere implement
+// ContextStrategy to stop early too; any other strategy runs to the end and
+// its result is discarded.
+type SortStrategy[T any] interface {
+	Sort(data []T, compare Comparator[T]) []T
+	GetName() string
+	Stable() bool
+}
+
+// Sorts is the registry of the strategies for ints, which the commands and
+// the demo pick from by name. Every strategy registers itself from an init
+// function next to its type. A strategy from another package plugs in the
+// same way, as package shellsort does; importing that package for its side
+// effects is all it takes to make its strategies selectable.
+var Sorts = registry.New[SortStrategy[int]]("sort strategy")
+
+// registerSort registers a strategy under its own name
+func registerSort(factory registry.Factory[SortStrategy[int]]) {
+	Sorts.MustRegister(factory().GetName(), factory)
+}
+
+func init() {
+	registerSort(func() SortStrategy[int] { return BubbleSort[int]{} })
+}
+
+// BubbleSort strategy. Stable: neighbours are only swapped when strictly out
+// of order.
+type BubbleSort[T any] struct{}
+
+func (bs BubbleSort[T]) GetName() string {
+	return "bubble sort"
+}
+
+func (bs BubbleSort[T]) Stable() bool {
+	return true
+}
+
+func (bs BubbleSort[T]) Sort(data []T, compare Comparator[T]) []T {
+	return bs.sortProgress(data, compare, progress{})
+}
+
+func (bs BubbleSort[T]) sortProgress(data []T, compare Comparator[T], p progress) []T {
+	result := slices.Clone(data)
+
+	n := len(result)
+	for i := 0; i < n-1 && !p.stopped(); i++ {
+		for j := 0; j < n-i-1; j++ {
+			if compare(result[j], result[j+1]) > 0 {
+				result[j], result[j+1] = result[j+1], result[j]
+				p.swap()
+			}
+		}
+	}
+	return result
+}
+
+// Sorter context
+type Sorter[T any] struct {
+	strategy SortStrategy[T]
+	compare  Comparator[T]
+	hook     Hook
+}
+
+// NewSorter sorts ordered values ascending
+func NewSorter[T cmp.Ordered](strategy SortStrategy[T]) *Sorter[T] {
+	return NewSorterFunc(strategy, cmp.Compare[T])
+}
+
+// NewSorterFunc sorts values in the order given by compare
+func NewSorterFunc[T any](strategy SortStrategy[T], compare Comparator[T]) *Sorter[T] {
+	return &Sorter[T]{strategy: strategy, compare: compare}
+}
+
+func (s *Sorter[T]) SetStrategy(strategy SortStrategy[T]) {
+	s.strategy = strategy
+}
+
+// SetHook makes the sorter report every sort to hook; nil stops reporting
+func (s *Sorter[T]) SetHook(hook Hook) {
+	s.hook = hook
+}
+
+// SetLogger makes the sorter log every sort to logger
+func (s *Sorter[T]) SetLogger(logger *slog.Logger) {
+	s.SetHook(LogHook{Logger: logger})
+}
+
+func (s *Sorter[T]) Sort(data []T) []T {
+	result, _ := s.SortContext(context.Background(), data)
+	return result
+}
+
+// SortContext sorts like Sort but gives up once ctx is done, returning its
+// error
+func (s *Sorter[T]) SortContext(ctx context.Context, data []T) ([]T, error) {
+	strategy := s.strategy
+	if c, ok := strategy.(chooser[T]); ok {
+		strategy = c.Choose(data, s.compare)
+	}
+	if s.hook == nil {
+		return sortContext(ctx, strategy, data, s.compare)
+	}
+
+	event := SortEvent{Strategy: s.strategy.GetName(), Chosen: strategy.GetName(), N: len(data)}
+	s.hook.SortStarted(event)
+	start := time.Now()
+	result, err := sortContext(ctx, strategy, data, s.compare)
+	event.Duration, event.Err = time.Since(start), err
+	s.hook.SortFinished(event)
+	return result, err
+}
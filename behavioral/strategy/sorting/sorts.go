@@ -0,0 +1,460 @@
+package sorting
+
+import (
+	"math/bits"
+	"runtime"
+	"slices"
+	"sync"
+)
+
+func init() {
+	registerSort(func() SortStrategy[int] { return InsertionSort[int]{} })
+	registerSort(func() SortStrategy[int] { return MergeSort[int]{} })
+	registerSort(func() SortStrategy[int] { return ParallelMergeSort[int]{} })
+	registerSort(func() SortStrategy[int] { return HeapSort[int]{} })
+	registerSort(func() SortStrategy[int] { return QuickSort[int]{} })
+	registerSort(func() SortStrategy[int] { return IntroSort[int]{} })
+	registerSort(func() SortStrategy[int] { return RadixSort[int]{} })
+}
+
+// InsertionSort strategy. Stable: an element only moves past strictly greater
+// ones. Quadratic, but the fastest choice for short or nearly sorted input.
+type InsertionSort[T any] struct{}
+
+func (is InsertionSort[T]) GetName() string {
+	return "insertion sort"
+}
+
+func (is InsertionSort[T]) Stable() bool {
+	return true
+}
+
+func (is InsertionSort[T]) Sort(data []T, compare Comparator[T]) []T {
+	return is.sortProgress(data, compare, progress{})
+}
+
+func (is InsertionSort[T]) sortProgress(data []T, compare Comparator[T], p progress) []T {
+	result := slices.Clone(data)
+	insertionSort(result, compare, p)
+	return result
+}
+
+func insertionSort[T any](data []T, compare Comparator[T], p progress) {
+	for i := 1; i < len(data) && !p.stopped(); i++ {
+		for j := i; j > 0 && compare(data[j-1], data[j]) > 0; j-- {
+			data[j-1], data[j] = data[j], data[j-1]
+			p.swap()
+		}
+	}
+}
+
+// MergeSort strategy. Stable: on ties the merge takes from the left half
+// first. O(n log n) always, using a buffer the size of the input.
+type MergeSort[T any] struct{}
+
+func (ms MergeSort[T]) GetName() string {
+	return "merge sort"
+}
+
+func (ms MergeSort[T]) Stable() bool {
+	return true
+}
+
+func (ms MergeSort[T]) Sort(data []T, compare Comparator[T]) []T {
+	return ms.sortProgress(data, compare, progress{})
+}
+
+func (ms MergeSort[T]) sortProgress(data []T, compare Comparator[T], p progress) []T {
+	result := slices.Clone(data)
+	mergeSort(result, make([]T, len(result)), compare, p)
+	return result
+}
+
+// mergeRun is the length below which merge sorts fall back to insertion sort
+const mergeRun = 12
+
+// mergeSort sorts data using buf, which has the same length, as scratch space
+func mergeSort[T any](data, buf []T, compare Comparator[T], p progress) {
+	if len(data) <= mergeRun {
+		insertionSort(data, compare, p)
+		return
+	}
+	mid := len(data) / 2
+	mergeSort(data[:mid], buf[:mid], compare, p)
+	mergeSort(data[mid:], buf[mid:], compare, p)
+	if p.stopped() {
+		return
+	}
+	merge(data, mid, buf, compare, p)
+}
+
+// merge combines the sorted halves data[:mid] and data[mid:]
+func merge[T any](data []T, mid int, buf []T, compare Comparator[T], p progress) {
+	if compare(data[mid-1], data[mid]) <= 0 {
+		return // already in order
+	}
+	copy(buf, data)
+	i, j, k := 0, mid, 0
+	for i < mid && j < len(data) {
+		if compare(buf[j], buf[i]) < 0 {
+			data[k] = buf[j]
+			j++
+		} else {
+			data[k] = buf[i]
+			i++
+		}
+		k++
+	}
+	k += copy(data[k:], buf[i:mid])
+	copy(data[k:], buf[j:])
+	p.move(2 * len(data)) // out to the buffer and back
+}
+
+// ParallelMergeSort strategy. Stable, like MergeSort, whose halves it sorts
+// on separate goroutines until there is one per CPU.
+type ParallelMergeSort[T any] struct {
+	// Threshold is the length below which halves are sorted sequentially;
+	// 0 means 4096
+	Threshold int
+}
+
+func (pms ParallelMergeSort[T]) GetName() string {
+	return "parallel merge sort"
+}
+
+func (pms ParallelMergeSort[T]) Stable() bool {
+	return true
+}
+
+func (pms ParallelMergeSort[T]) Sort(data []T, compare Comparator[T]) []T {
+	return pms.sortProgress(data, compare, progress{})
+}
+
+func (pms ParallelMergeSort[T]) sortProgress(data []T, compare Comparator[T], p progress) []T {
+	threshold := pms.Threshold
+	if threshold <= 0 {
+		threshold = 4096
+	}
+	result := slices.Clone(data)
+	depth := bits.Len(uint(runtime.GOMAXPROCS(0)))
+	parallelMergeSort(result, make([]T, len(result)), compare, p, threshold, depth)
+	return result
+}
+
+func parallelMergeSort[T any](data, buf []T, compare Comparator[T], p progress, threshold, depth int) {
+	if p.stopped() {
+		return
+	}
+	if len(data) < threshold || depth == 0 {
+		mergeSort(data, buf, compare, p)
+		return
+	}
+	mid := len(data) / 2
+	var wg sync.WaitGroup
+	var panicked any
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		// A panicking comparator must surface on the caller's goroutine
+		// rather than crash the program
+		defer func() { panicked = recover() }()
+		parallelMergeSort(data[:mid], buf[:mid], compare, p, threshold, depth-1)
+	}()
+	func() {
+		defer wg.Wait()
+		parallelMergeSort(data[mid:], buf[mid:], compare, p, threshold, depth-1)
+	}()
+	if panicked != nil {
+		panic(panicked)
+	}
+	if p.stopped() {
+		return
+	}
+	merge(data, mid, buf, compare, p)
+}
+
+// HeapSort strategy. Not stable: sifting moves equal elements past each
+// other. O(n log n) always and sorts in place.
+type HeapSort[T any] struct{}
+
+func (hs HeapSort[T]) GetName() string {
+	return "heap sort"
+}
+
+func (hs HeapSort[T]) Stable() bool {
+	return false
+}
+
+func (hs HeapSort[T]) Sort(data []T, compare Comparator[T]) []T {
+	return hs.sortProgress(data, compare, progress{})
+}
+
+func (hs HeapSort[T]) sortProgress(data []T, compare Comparator[T], p progress) []T {
+	result := slices.Clone(data)
+	heapSort(result, compare, p)
+	return result
+}
+
+func heapSort[T any](data []T, compare Comparator[T], p progress) {
+	for i := len(data)/2 - 1; i >= 0 && !p.stopped(); i-- {
+		siftDown(data, i, len(data), compare, p)
+	}
+	for end := len(data) - 1; end > 0 && !p.stopped(); end-- {
+		data[0], data[end] = data[end], data[0]
+		p.swap()
+		siftDown(data, 0, end, compare, p)
+	}
+}
+
+// siftDown restores the max-heap property of data[:end] below root
+func siftDown[T any](data []T, root, end int, compare Comparator[T], p progress) {
+	for {
+		child := 2*root + 1
+		if child >= end {
+			return
+		}
+		if child+1 < end && compare(data[child], data[child+1]) < 0 {
+			child++
+		}
+		if compare(data[root], data[child]) >= 0 {
+			return
+		}
+		data[root], data[child] = data[child], data[root]
+		p.swap()
+		root = child
+	}
+}
+
+// QuickSort strategy. Not stable: partitioning swaps elements across the
+// slice. The pivot is the median of the first, middle and last elements,
+// which keeps sorted and reversed input at O(n log n); adversarial input can
+// still make it quadratic, which IntroSort guards against.
+type QuickSort[T any] struct{}
+
+func (qs QuickSort[T]) GetName() string {
+	return "quick sort"
+}
+
+func (qs QuickSort[T]) Stable() bool {
+	return false
+}
+
+func (qs QuickSort[T]) Sort(data []T, compare Comparator[T]) []T {
+	return qs.sortProgress(data, compare, progress{})
+}
+
+func (qs QuickSort[T]) sortProgress(data []T, compare Comparator[T], p progress) []T {
+	result := slices.Clone(data)
+	quickSort(result, compare, p, -1)
+	return result
+}
+
+// IntroSort strategy. Not stable. Quicksort that switches to heap sort when
+// recursion gets deeper than 2·log2(n), and to insertion sort for short
+// ranges, so it stays O(n log n) on any input.
+type IntroSort[T any] struct{}
+
+func (is IntroSort[T]) GetName() string {
+	return "introsort"
+}
+
+func (is IntroSort[T]) Stable() bool {
+	return false
+}
+
+func (is IntroSort[T]) Sort(data []T, compare Comparator[T]) []T {
+	return is.sortProgress(data, compare, progress{})
+}
+
+func (is IntroSort[T]) sortProgress(data []T, compare Comparator[T], p progress) []T {
+	result := slices.Clone(data)
+	quickSort(result, compare, p, 2*bits.Len(uint(len(result))))
+	return result
+}
+
+// quickRun is the length below which quicksort hands over to insertion sort
+const quickRun = 12
+
+// quickSort sorts data with median-of-three quicksort. A negative depth means
+// no limit; otherwise heap sort takes over once depth reaches zero.
+func quickSort[T any](data []T, compare Comparator[T], p progress, depth int) {
+	for len(data) > quickRun {
+		if p.stopped() {
+			return
+		}
+		if depth == 0 {
+			heapSort(data, compare, p)
+			return
+		}
+		depth--
+
+		pivot := partition(data, compare, p)
+		// Recurse into the smaller side and loop on the larger one, so the
+		// stack never grows beyond log2(n) frames
+		if pivot < len(data)-pivot {
+			quickSort(data[:pivot], compare, p, depth)
+			data = data[pivot+1:]
+		} else {
+			quickSort(data[pivot+1:], compare, p, depth)
+			data = data[:pivot]
+		}
+	}
+	insertionSort(data, compare, p)
+}
+
+// partition moves the median-of-three pivot to its final index and returns
+// it, with nothing greater before it and nothing smaller after it
+func partition[T any](data []T, compare Comparator[T], p progress) int {
+	lo, mid, hi := 0, len(data)/2, len(data)-1
+	if compare(data[mid], data[lo]) < 0 {
+		data[mid], data[lo] = data[lo], data[mid]
+		p.swap()
+	}
+	if compare(data[hi], data[lo]) < 0 {
+		data[hi], data[lo] = data[lo], data[hi]
+		p.swap()
+	}
+	if compare(data[hi], data[mid]) < 0 {
+		data[hi], data[mid] = data[mid], data[hi]
+		p.swap()
+	}
+	// data[lo] <= pivot <= data[hi]; park the pivot next to the end
+	data[mid], data[hi-1] = data[hi-1], data[mid]
+	p.swap()
+	pivot := hi - 1
+
+	i, j := lo, pivot
+	for {
+		for i++; compare(data[i], data[pivot]) < 0; i++ {
+		}
+		for j--; compare(data[pivot], data[j]) < 0; j-- {
+		}
+		if i >= j {
+			break
+		}
+		data[i], data[j] = data[j], data[i]
+		p.swap()
+	}
+	data[i], data[pivot] = data[pivot], data[i]
+	p.swap()
+	return i
+}
+
+// Integer is every type RadixSort can sort
+type Integer interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+// RadixSort strategy for integers. Stable. It distributes elements by one
+// byte at a time, least significant first, in O(n) passes of 256 buckets,
+// which orders them by value. It then spends n-1 comparisons checking that
+// order against compare: if compare sorts by value, ascending or descending,
+// the result stands (reversed when descending). Any other comparator, for
+// instance one ordering by absolute value, gets a merge sort instead.
+type RadixSort[T Integer] struct{}
+
+func (rs RadixSort[T]) GetName() string {
+	return "radix sort"
+}
+
+func (rs RadixSort[T]) Stable() bool {
+	return true
+}
+
+func (rs RadixSort[T]) Sort(data []T, compare Comparator[T]) []T {
+	return rs.sortProgress(data, compare, progress{})
+}
+
+func (rs RadixSort[T]) sortProgress(data []T, compare Comparator[T], p progress) []T {
+	result := slices.Clone(data)
+	radixSort(result, p)
+	if compare == nil || p.stopped() {
+		return result
+	}
+	switch agreesWithValueOrder(result, compare) {
+	case 1:
+		return result
+	case -1:
+		slices.Reverse(result)
+		return result
+	default:
+		return MergeSort[T]{}.sortProgress(data, compare, p)
+	}
+}
+
+// agreesWithValueOrder reports whether compare orders the ascending values
+// exactly by value (1), exactly by reverse value (-1), or neither (0).
+// Distinct values compare equal under neither order, because radix sort
+// would then reorder elements compare considers equal.
+func agreesWithValueOrder[T Integer](ascending []T, compare Comparator[T]) int {
+	order := 0
+	for i := 1; i < len(ascending); i++ {
+		a, b := ascending[i-1], ascending[i]
+		if a == b {
+			continue
+		}
+		pair := 1
+		switch c := compare(a, b); {
+		case c == 0:
+			return 0
+		case c > 0:
+			pair = -1
+		}
+		if order == 0 {
+			order = pair
+		} else if pair != order {
+			return 0
+		}
+	}
+	if order == 0 {
+		return 1 // every value is the same
+	}
+	return order
+}
+
+func radixSort[T Integer](data []T, p progress) {
+	if len(data) < 2 {
+		return
+	}
+	var zero T
+	signed := ^zero < 0
+	key := func(v T) uint64 {
+		if signed {
+			// Flipping the sign bit of the sign-extended value orders
+			// negatives before positives
+			return uint64(v) ^ (1 << 63)
+		}
+		return uint64(v)
+	}
+
+	buf := make([]T, len(data))
+	src, dst := data, buf
+	for shift := 0; shift < 64; shift += 8 {
+		if p.stopped() {
+			return
+		}
+		var offsets [256]int
+		for _, v := range src {
+			offsets[byte(key(v)>>shift)]++
+		}
+		if slices.Contains(offsets[:], len(src)) {
+			continue // every element has the same digit here
+		}
+		offset := 0
+		for digit, count := range offsets {
+			offsets[digit] = offset
+			offset += count
+		}
+		for _, v := range src {
+			digit := byte(key(v) >> shift)
+			dst[offsets[digit]] = v
+			offsets[digit]++
+		}
+		p.move(len(src))
+		src, dst = dst, src
+	}
+	if &src[0] != &data[0] {
+		p.move(copy(data, src))
+	}
+}
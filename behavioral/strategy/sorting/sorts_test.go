@@ -0,0 +1,241 @@
+package sorting
+
+import (
+	"cmp"
+	"flag"
+	"fmt"
+	"math/rand"
+	"slices"
+	"testing"
+	"time"
+)
+
+var seed = flag.Int64("seed", 0, "random seed for the property tests; 0 picks one and logs it")
+
+// random returns the property tests' source of randomness, logging the seed
+// so a failure can be reproduced with -seed
+func random(t *testing.T) *rand.Rand {
+	s := *seed
+	if s == 0 {
+		s = time.Now().UnixNano()
+	}
+	t.Logf("seed %d", s)
+	return rand.New(rand.NewSource(s))
+}
+
+// strategies returns one of every comparison-based strategy
+func strategies[T any]() []SortStrategy[T] {
+	return []SortStrategy[T]{
+		BubbleSort[T]{},
+		InsertionSort[T]{},
+		MergeSort[T]{},
+		ParallelMergeSort[T]{},
+		HeapSort[T]{},
+		QuickSort[T]{},
+		IntroSort[T]{},
+		AutoSorter[T]{Thresholds: DefaultThresholds, StableOnly: true},
+	}
+}
+
+// intStrategies returns every registered strategy plus a parallel merge sort
+// with a threshold low enough for small inputs to take the parallel path
+func intStrategies() []SortStrategy[int] {
+	return append(Sorts.All(), ParallelMergeSort[int]{Threshold: 64})
+}
+
+// checkSizes always run, in addition to random sizes
+var checkSizes = []int{0, 1, 2, 3, 12, 13, 100, 5000}
+
+func testSizes(r *rand.Rand) []int {
+	sizes := slices.Clone(checkSizes)
+	rounds := 20
+	if testing.Short() {
+		sizes, rounds = sizes[:len(sizes)-1], 3
+	}
+	for i := 0; i < rounds; i++ {
+		sizes = append(sizes, r.Intn(600))
+	}
+	return sizes
+}
+
+// Every strategy must produce what slices.Sort produces, for random inputs of
+// every shape, without modifying its input
+func TestStrategiesSortLikeSlicesSort(t *testing.T) {
+	r := random(t)
+	sizes := testSizes(r)
+	for _, shape := range InputShapes {
+		t.Run(shape.Name, func(t *testing.T) {
+			for _, n := range sizes {
+				input := shape.Generate(r, n)
+				for _, strategy := range intStrategies() {
+					if err := checkSorted(strategy, input, cmp.Compare[int]); err != nil {
+						t.Errorf("%s on %d ints: %v", strategy.GetName(), n, err)
+					}
+				}
+			}
+		})
+	}
+}
+
+// Stable strategies must keep equal keys in their original order
+func TestStableStrategiesKeepEqualKeysInOrder(t *testing.T) {
+	r := random(t)
+	sizes := testSizes(r)
+	for _, shape := range InputShapes {
+		t.Run(shape.Name, func(t *testing.T) {
+			for _, n := range sizes {
+				input := shape.Generate(r, n)
+				for _, strategy := range append(strategies[keyed](), ParallelMergeSort[keyed]{Threshold: 64}) {
+					if err := checkStable(strategy, input); err != nil {
+						t.Errorf("%s on %d records: %v", strategy.GetName(), n, err)
+					}
+				}
+			}
+		})
+	}
+}
+
+// Comparators that do not order by value must be honoured too, including by
+// strategies such as radix sort that look at the values themselves. Stable
+// strategies must match slices.SortStableFunc exactly; the others must match
+// it up to the order of elements that compare equal.
+func TestArbitraryComparators(t *testing.T) {
+	abs := func(v int) int { return max(v, -v) }
+	comparators := []struct {
+		name    string
+		compare Comparator[int]
+	}{
+		{"descending", Reverse(cmp.Compare[int])},
+		{"absolute value", By(abs)},
+		{"even first", By(func(v int) int { return v & 1 }).Then(cmp.Compare[int])},
+		{"last digit", By(func(v int) int { return abs(v) % 10 })},
+		{"all equal", func(a, b int) int { return 0 }},
+	}
+
+	r := random(t)
+	sizes := testSizes(r)
+	for _, c := range comparators {
+		t.Run(c.name, func(t *testing.T) {
+			for _, shape := range InputShapes {
+				for _, n := range sizes {
+					input := shape.Generate(r, n)
+					for _, strategy := range intStrategies() {
+						if err := checkSorted(strategy, input, c.compare); err != nil {
+							t.Errorf("%s on %d %s ints: %v", strategy.GetName(), n, shape.Name, err)
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+// Radix sort works on the bits of the value, so every width and signedness
+// gets a check of its own, in both orders
+func TestRadixSortWidths(t *testing.T) {
+	r := random(t)
+	for _, n := range testSizes(r) {
+		checkRadix[int8](t, r, n)
+		checkRadix[uint16](t, r, n)
+		checkRadix[int64](t, r, n)
+		checkRadix[uint](t, r, n)
+	}
+}
+
+func checkRadix[T Integer](t *testing.T, r *rand.Rand, n int) {
+	t.Helper()
+	input := make([]T, n)
+	for i := range input {
+		input[i] = T(r.Uint64())
+	}
+	for _, compare := range []Comparator[T]{cmp.Compare[T], Reverse(cmp.Compare[T])} {
+		if err := checkSorted[T](RadixSort[T]{}, input, compare); err != nil {
+			t.Errorf("radix sort on %d %T: %v", n, input, err)
+		}
+	}
+}
+
+// checkSorted compares strategy's result with slices.SortStableFunc's. For
+// strategies that are not stable, runs of elements comparing equal may come
+// in any order.
+func checkSorted[T cmp.Ordered](strategy SortStrategy[T], input []T, compare Comparator[T]) error {
+	original := slices.Clone(input)
+	want := slices.Clone(input)
+	slices.SortStableFunc(want, compare)
+
+	got := strategy.Sort(input, compare)
+	if !slices.Equal(input, original) {
+		return fmt.Errorf("input was modified")
+	}
+	if strategy.Stable() {
+		if !slices.Equal(got, want) {
+			return fmt.Errorf("got %v, want %v", preview(got), preview(want))
+		}
+		return nil
+	}
+
+	if len(got) != len(want) {
+		return fmt.Errorf("got %d elements, want %d", len(got), len(want))
+	}
+	for start := 0; start < len(want); {
+		end := start + 1
+		for end < len(want) && compare(want[start], want[end]) == 0 {
+			end++
+		}
+		gotRun, wantRun := slices.Clone(got[start:end]), slices.Clone(want[start:end])
+		slices.Sort(gotRun)
+		slices.Sort(wantRun)
+		if !slices.Equal(gotRun, wantRun) {
+			return fmt.Errorf("got %v, want %v up to the order of equal elements", preview(got), preview(want))
+		}
+		start = end
+	}
+	return nil
+}
+
+// keyed tags a value with its input position to observe stability
+type keyed struct {
+	key, index int
+}
+
+func checkStable(strategy SortStrategy[keyed], input []int) error {
+	records := make([]keyed, len(input))
+	for i, key := range input {
+		records[i] = keyed{key, i}
+	}
+	got := strategy.Sort(records, By(func(k keyed) int { return k.key }))
+	if len(got) != len(records) {
+		return fmt.Errorf("got %d records, want %d", len(got), len(records))
+	}
+	for i := 1; i < len(got); i++ {
+		if got[i-1].key > got[i].key {
+			return fmt.Errorf("keys out of order at %d", i)
+		}
+		if strategy.Stable() && got[i-1].key == got[i].key && got[i-1].index > got[i].index {
+			return fmt.Errorf("claims to be stable but reordered equal keys at %d", i)
+		}
+	}
+	return nil
+}
+
+// preview shortens long slices in error messages
+func preview[T any](data []T) string {
+	if len(data) > 10 {
+		return fmt.Sprintf("%v... (%d elements)", data[:10], len(data))
+	}
+	return fmt.Sprint(data)
+}
+
+func TestInstrumentedZeroValue(t *testing.T) {
+	input := []int{3, 1, 2}
+	in := Instrumented[int]{Strategy: InsertionSort[int]{}}
+	if got := in.Sort(input, cmp.Compare[int]); !slices.Equal(got, []int{1, 2, 3}) {
+		t.Errorf("got %v", got)
+	}
+	if got := in.Counts.Comparisons.Load(); got != 3 {
+		t.Errorf("%d comparisons, want 3", got)
+	}
+	if got := in.Counts.Swaps.Load(); got != 2 {
+		t.Errorf("%d swaps, want 2", got)
+	}
+}
@@ -0,0 +1,164 @@
+// Package build is a generic builder for any struct, configured with
+// functional options:
+//
+//	var servers = build.New[Server]("server").
+//		Default(WithPort(80)).
+//		Require("host").
+//		Check(portInRange)
+//
+//	server, err := servers.With(WithHost("example.com"), WithTLS()).Build()
+//
+// Builders are immutable: every method returns a new builder and leaves the
+// one it was called on as it was. A partly configured builder can therefore
+// be shared and forked, even across goroutines, without one fork seeing the
+// options of another.
+package build
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Option sets one field of the T being built. The field name is what
+// Require and Once refer to.
+type Option[T any] struct {
+	field string
+	set   func(*T)
+}
+
+// Set returns an option setting field with set
+func Set[T any](field string, set func(*T)) Option[T] {
+	return Option[T]{field: field, set: set}
+}
+
+// Field returns the name of the field the option sets
+func (o Option[T]) Field() string {
+	return o.field
+}
+
+// Rule checks a built T, returning what is wrong with it or nil
+type Rule[T any] func(T) error
+
+// Violations of Require and Once wrap these, and ErrRange is for rules
+// bounding a field, like the ones buildergen writes
+var (
+	ErrMissing  = errors.New("required field not set")
+	ErrRepeated = errors.New("field set twice")
+	ErrRange    = errors.New("field out of range")
+)
+
+// Error lists every reason a T could not be built
+type Error struct {
+	// Name is what is being built, as given to New
+	Name       string
+	Violations []error
+}
+
+func (e *Error) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, err := range e.Violations {
+		messages[i] = err.Error()
+	}
+	return fmt.Sprintf("invalid %s: %s", e.Name, strings.Join(messages, "; "))
+}
+
+func (e *Error) Unwrap() []error {
+	return e.Violations
+}
+
+// Builder builds values of T from options. The zero Builder works but names
+// what it builds "value" in errors; New names it.
+type Builder[T any] struct {
+	name     string
+	defaults []Option[T]
+	required []string
+	once     []string
+	rules    []Rule[T]
+	options  []Option[T]
+}
+
+// New returns a builder without defaults, requirements or rules; name is
+// what it builds, for error messages
+func New[T any](name string) Builder[T] {
+	return Builder[T]{name: name}
+}
+
+// Default adds options applied before every other option, which can
+// override them. Defaults do not count as setting a required field.
+func (b Builder[T]) Default(options ...Option[T]) Builder[T] {
+	b.defaults = extend(b.defaults, options...)
+	return b
+}
+
+// Require makes Build fail unless an option sets each of fields
+func (b Builder[T]) Require(fields ...string) Builder[T] {
+	b.required = extend(b.required, fields...)
+	return b
+}
+
+// Once makes Build fail if an option sets one of fields more than once
+func (b Builder[T]) Once(fields ...string) Builder[T] {
+	b.once = extend(b.once, fields...)
+	return b
+}
+
+// Check makes Build check rules on the value it built
+func (b Builder[T]) Check(rules ...Rule[T]) Builder[T] {
+	b.rules = extend(b.rules, rules...)
+	return b
+}
+
+// With adds options, applied in order after the ones given before
+func (b Builder[T]) With(options ...Option[T]) Builder[T] {
+	b.options = extend(b.options, options...)
+	return b
+}
+
+// Build applies the defaults and options to a zero T and checks the result.
+// It returns the zero T and an *Error listing every violation if any
+// requirement or rule fails.
+func (b Builder[T]) Build() (T, error) {
+	var value T
+	set := make(map[string]int)
+	for _, option := range b.defaults {
+		option.set(&value)
+	}
+	for _, option := range b.options {
+		option.set(&value)
+		set[option.field]++
+	}
+
+	var violations []error
+	for _, field := range b.required {
+		if set[field] == 0 {
+			violations = append(violations, fmt.Errorf("%w: %s", ErrMissing, field))
+		}
+	}
+	for _, field := range b.once {
+		if set[field] > 1 {
+			violations = append(violations, fmt.Errorf("%w: %s", ErrRepeated, field))
+		}
+	}
+	for _, rule := range b.rules {
+		if err := rule(value); err != nil {
+			violations = append(violations, err)
+		}
+	}
+	if len(violations) > 0 {
+		var zero T
+		name := b.name
+		if name == "" {
+			name = "value"
+		}
+		return zero, &Error{Name: name, Violations: violations}
+	}
+	return value, nil
+}
+
+// extend appends to a copy of s, never into its spare capacity, which the
+// builder s came from may share with other forks
+func extend[E any](s []E, more ...E) []E {
+	return append(slices.Clip(s), more...)
+}
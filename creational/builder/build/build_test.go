@@ -0,0 +1,183 @@
+package build
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+)
+
+type server struct {
+	Host string
+	Port int
+	TLS  bool
+	Tags []string
+}
+
+func withHost(host string) Option[server] {
+	return Set("host", func(s *server) { s.Host = host })
+}
+
+func withPort(port int) Option[server] {
+	return Set("port", func(s *server) { s.Port = port })
+}
+
+func withTLS() Option[server] {
+	return Set("tls", func(s *server) { s.TLS = true })
+}
+
+func withTag(tag string) Option[server] {
+	return Set("tags", func(s *server) { s.Tags = append(s.Tags, tag) })
+}
+
+func TestForksDoNotShareSpareCapacity(t *testing.T) {
+	// Growing one option at a time leaves spare capacity behind, which a
+	// plain append in With would let the forks below overwrite
+	base := New[server]("server")
+	for _, tag := range []string{"a", "b", "c"} {
+		base = base.With(withTag(tag))
+	}
+
+	left := base.With(withTag("left"))
+	right := base.With(withTag("right"))
+	for _, tc := range []struct {
+		builder Builder[server]
+		want    []string
+	}{
+		{left, []string{"a", "b", "c", "left"}},
+		{right, []string{"a", "b", "c", "right"}},
+		{base, []string{"a", "b", "c"}},
+	} {
+		got, err := tc.builder.Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got.Tags, tc.want) {
+			t.Errorf("got tags %v, want %v", got.Tags, tc.want)
+		}
+	}
+}
+
+func TestEveryMethodLeavesItsReceiverAlone(t *testing.T) {
+	base := New[server]("server").With(withHost("a"), withPort(1))
+	rule := func(server) error { return errors.New("rejected") }
+
+	forks := []Builder[server]{
+		base.Default(withTLS(), withTLS()),
+		base.Require("tls", "tags"),
+		base.Once("host", "port"),
+		base.Check(rule, rule),
+		base.With(withHost("b"), withPort(2)),
+	}
+	for _, fork := range forks {
+		fork.Build()
+	}
+	got, err := base.Build()
+	if err != nil || got.Host != "a" || got.Port != 1 || got.TLS {
+		t.Errorf("base changed: %+v, %v", got, err)
+	}
+}
+
+func TestConcurrentForks(t *testing.T) {
+	base := New[server]("server").With(withHost("shared"), withTag("x"), withTag("y"))
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(port int) {
+			defer wg.Done()
+			got, err := base.With(withPort(port), withTag(fmt.Sprint(port))).Build()
+			if err != nil || got.Port != port || !slices.Equal(got.Tags, []string{"x", "y", fmt.Sprint(port)}) {
+				t.Errorf("fork %d got %+v, %v", port, got, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestDefaultsDoNotSatisfyRequire(t *testing.T) {
+	servers := New[server]("server").
+		Default(withPort(80)).
+		Require("port")
+
+	_, err := servers.Build()
+	if !errors.Is(err, ErrMissing) {
+		t.Fatalf("a default satisfied Require: %v", err)
+	}
+
+	got, err := servers.With(withPort(8080)).Build()
+	if err != nil || got.Port != 8080 {
+		t.Errorf("got %+v, %v; want port 8080", got, err)
+	}
+}
+
+func TestOptionsOverrideDefaults(t *testing.T) {
+	// Defaults apply first, whatever the order they were added in
+	servers := New[server]("server").With(withPort(8080)).Default(withPort(80), withHost("localhost"))
+	got, err := servers.Build()
+	if err != nil || got.Port != 8080 || got.Host != "localhost" {
+		t.Errorf("got %+v, %v; want port 8080 on localhost", got, err)
+	}
+}
+
+func TestOnce(t *testing.T) {
+	servers := New[server]("server").Default(withHost("localhost")).Once("host")
+
+	// A default does not count as setting the field
+	if _, err := servers.With(withHost("a")).Build(); err != nil {
+		t.Errorf("one host after a default: %v", err)
+	}
+	_, err := servers.With(withHost("a"), withPort(1), withHost("b")).Build()
+	if !errors.Is(err, ErrRepeated) {
+		t.Errorf("two hosts: got %v, want ErrRepeated", err)
+	}
+	// Fields without Once may be set repeatedly
+	if _, err := servers.With(withTag("a"), withTag("b")).Build(); err != nil {
+		t.Errorf("two tags: %v", err)
+	}
+}
+
+func TestBuildReportsEveryViolation(t *testing.T) {
+	tooLow := errors.New("port too low")
+	servers := New[server]("server").
+		Require("host").
+		Once("tls").
+		Check(func(s server) error {
+			if s.Port < 1024 {
+				return tooLow
+			}
+			return nil
+		})
+
+	got, err := servers.With(withTLS(), withTLS(), withPort(80)).Build()
+	var buildErr *Error
+	if !errors.As(err, &buildErr) {
+		t.Fatalf("got %v, want an *Error", err)
+	}
+	if buildErr.Name != "server" || len(buildErr.Violations) != 3 {
+		t.Errorf("got %v", err)
+	}
+	for _, want := range []error{ErrMissing, ErrRepeated, tooLow} {
+		if !errors.Is(err, want) {
+			t.Errorf("%v does not wrap %v", err, want)
+		}
+	}
+	if got.Port != 0 || got.TLS {
+		t.Errorf("a failed Build returned %+v, want the zero value", got)
+	}
+	if msg := err.Error(); msg != "invalid server: required field not set: host; field set twice: tls; port too low" {
+		t.Errorf("message %q", msg)
+	}
+}
+
+func TestZeroBuilder(t *testing.T) {
+	var zero Builder[server]
+	got, err := zero.With(withHost("a")).Build()
+	if err != nil || got.Host != "a" {
+		t.Errorf("got %+v, %v", got, err)
+	}
+	_, err = zero.Require("port").Build()
+	if err == nil || err.Error() != "invalid value: required field not set: port" {
+		t.Errorf("got %v", err)
+	}
+}
@@ -0,0 +1,528 @@
+// Command buildergen writes a fluent builder for a struct, so the builder
+// cannot drift out of sync with the struct it builds. It reads the struct
+// with go/ast and writes, on top of the build package:
+//
+//	NewOrderBuilder() *OrderBuilder
+//	WithX(x T) *OrderBuilder        for most fields
+//	AddX() *OrderBuilder            for bool fields, which can be added once
+//	AddX(items ...E) *OrderBuilder  for slice fields, appending to them
+//	Check(rule) *OrderBuilder       for validation rules of your own
+//	Build() (Order, error)
+//
+// Struct tags add validation:
+//
+//	Customer string   `builder:"required"`         an option must set it
+//	Burgers  []Burger `builder:"min=1,max=5"`      bounds the length
+//	Table    int      `builder:"default=1,min=1"`  a default; bounds the value
+//	Secret   string   `builder:"-"`                no method for this field
+//
+// min and max bound the value of numbers and the length of strings, slices
+// and maps; default is for booleans, numbers and strings. Only types spelt
+// with a builtin name count as those: the struct is read without type
+// checking. Run it from go generate in the struct's package:
+//
+//	//go:generate go run ./buildergen -type Order
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	gobuild "go/build"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const defaultBuildPath = "go-design-patterns/creational/builder/build"
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("buildergen: ")
+	typeList := flag.String("type", "", "comma-separated names of the structs to write builders for")
+	output := flag.String("output", "", "output file for a single type; default <type>_builder.go, lowercased")
+	buildPath := flag.String("build", defaultBuildPath, "import path of the build package")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: buildergen -type T[,U...] [-output file] [-build path] [dir]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *typeList == "" || flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	names := strings.Split(*typeList, ",")
+	if *output != "" && len(names) > 1 {
+		log.Fatal("-output needs a single -type")
+	}
+	dir := "."
+	if flag.NArg() == 1 {
+		dir = flag.Arg(0)
+	}
+
+	pkg, err := parsePackage(dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, name := range names {
+		src, err := pkg.generate(strings.TrimSpace(name), *buildPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		file := *output
+		if file == "" {
+			file = strings.ToLower(name) + "_builder.go"
+		}
+		if err := os.WriteFile(filepath.Join(dir, file), src, 0o644); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// pkg is the parsed source of a package, without its tests
+type pkg struct {
+	name  string
+	fset  *token.FileSet
+	files []*ast.File
+}
+
+func parsePackage(dir string) (*pkg, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	p := &pkg{fset: token.NewFileSet()}
+	for _, path := range paths {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(p.fset, path, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		if p.name == "" {
+			p.name = file.Name.Name
+		}
+		if file.Name.Name == p.name {
+			p.files = append(p.files, file)
+		}
+	}
+	if p.name == "" {
+		return nil, fmt.Errorf("no Go files in %s", dir)
+	}
+	return p, nil
+}
+
+// findStruct returns the struct declared as name and the file declaring it
+func (p *pkg) findStruct(name string) (*ast.StructType, *ast.File, error) {
+	for _, file := range p.files {
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				ts := spec.(*ast.TypeSpec)
+				if ts.Name.Name != name {
+					continue
+				}
+				st, ok := ts.Type.(*ast.StructType)
+				if !ok {
+					return nil, nil, fmt.Errorf("%s: %s is not a struct", p.fset.Position(ts.Pos()), name)
+				}
+				if ts.TypeParams != nil {
+					return nil, nil, fmt.Errorf("%s: %s is generic, which is not supported", p.fset.Position(ts.Pos()), name)
+				}
+				return st, file, nil
+			}
+		}
+	}
+	return nil, nil, fmt.Errorf("no struct %s in package %s", name, p.name)
+}
+
+type kind int
+
+const (
+	other kind = iota
+	boolean
+	integer
+	float
+	text
+	slice
+	mapping
+)
+
+// isUnsigned reports whether expr spells an unsigned integer type
+func isUnsigned(expr ast.Expr) bool {
+	id, ok := expr.(*ast.Ident)
+	return ok && (strings.HasPrefix(id.Name, "uint") || id.Name == "byte")
+}
+
+// bitSize returns the size of an integer type as strconv.ParseInt wants it
+func bitSize(typ string) int {
+	switch typ {
+	case "int8", "uint8", "byte":
+		return 8
+	case "int16", "uint16":
+		return 16
+	case "int32", "uint32", "rune":
+		return 32
+	case "int64", "uint64":
+		return 64
+	}
+	return strconv.IntSize // int, uint and uintptr
+}
+
+// kindOf tells the kinds of types apart by how they are spelt
+func kindOf(expr ast.Expr) kind {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		switch t.Name {
+		case "bool":
+			return boolean
+		case "int", "int8", "int16", "int32", "int64", "rune",
+			"uint", "uint8", "uint16", "uint32", "uint64", "uintptr", "byte":
+			return integer
+		case "float32", "float64":
+			return float
+		case "string":
+			return text
+		}
+	case *ast.ArrayType:
+		if t.Len == nil {
+			return slice
+		}
+	case *ast.MapType:
+		return mapping
+	}
+	return other
+}
+
+// field is a struct field and what its tag asks of the builder
+type field struct {
+	name     string
+	param    string
+	typ      string
+	elem     string // of a slice
+	kind     kind
+	unsigned bool
+	required bool
+	def      string // Go expression
+	min, max string // Go constants
+}
+
+// measured returns the expression min and max bound, and what it is called
+// in errors
+func (f field) measured() (expr, what string) {
+	switch f.kind {
+	case text, slice, mapping:
+		return "len(v." + f.name + ")", "the length of " + f.name
+	}
+	return "v." + f.name, f.name
+}
+
+func (p *pkg) fields(st *ast.StructType) ([]field, error) {
+	var fields []field
+	for _, f := range st.Fields.List {
+		tag := ""
+		if f.Tag != nil {
+			raw, _ := strconv.Unquote(f.Tag.Value)
+			tag = reflect.StructTag(raw).Get("builder")
+		}
+		if tag == "-" {
+			continue
+		}
+		for _, name := range f.Names {
+			if !name.IsExported() {
+				continue
+			}
+			fd := field{name: name.Name, param: paramName(name.Name), typ: types.ExprString(f.Type), kind: kindOf(f.Type), unsigned: isUnsigned(f.Type)}
+			if fd.kind == slice {
+				fd.elem = types.ExprString(f.Type.(*ast.ArrayType).Elt)
+			}
+			if err := fd.parseTag(tag); err != nil {
+				return nil, fmt.Errorf("%s: field %s: %w", p.fset.Position(name.Pos()), name.Name, err)
+			}
+			fields = append(fields, fd)
+		}
+	}
+	return fields, nil
+}
+
+func (f *field) parseTag(tag string) error {
+	for _, item := range strings.Split(tag, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(item), "=")
+		switch key {
+		case "":
+		case "required":
+			f.required = true
+		case "min", "max":
+			bound, err := f.bound(value)
+			if err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+			if key == "min" {
+				f.min = bound
+			} else {
+				f.max = bound
+			}
+		case "default":
+			def, err := f.defaultValue(value)
+			if err != nil {
+				return fmt.Errorf("default: %w", err)
+			}
+			f.def = def
+		default:
+			return fmt.Errorf("unknown builder tag option %q", key)
+		}
+	}
+	if f.required && f.def != "" {
+		return fmt.Errorf("a required field has no use for a default")
+	}
+	return nil
+}
+
+func (f *field) bound(value string) (string, error) {
+	switch f.kind {
+	case integer:
+		// The generated comparison would not compile with a constant the
+		// field cannot hold
+		var err error
+		if f.unsigned {
+			_, err = strconv.ParseUint(value, 0, bitSize(f.typ))
+			// ParseUint takes no sign, so a negative number is a syntax error to it
+			_, signedErr := strconv.ParseInt(value, 0, 64)
+			if err != nil && strings.HasPrefix(value, "-") && !errors.Is(signedErr, strconv.ErrSyntax) {
+				return "", fmt.Errorf("%s is negative, but %s is unsigned", value, f.typ)
+			}
+		} else {
+			_, err = strconv.ParseInt(value, 0, bitSize(f.typ))
+		}
+		if errors.Is(err, strconv.ErrRange) {
+			return "", fmt.Errorf("%s is out of range for %s", value, f.typ)
+		}
+		if err != nil {
+			return "", fmt.Errorf("%q is not an integer", value)
+		}
+	case float:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return "", fmt.Errorf("%q is not a number", value)
+		}
+	case text, slice, mapping:
+		if n, err := strconv.Atoi(value); err != nil || n < 0 {
+			return "", fmt.Errorf("%q is not a length", value)
+		}
+	default:
+		return "", fmt.Errorf("only numbers, strings, slices and maps can be bounded, not %s", f.typ)
+	}
+	return value, nil
+}
+
+func (f *field) defaultValue(value string) (string, error) {
+	switch f.kind {
+	case boolean:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", fmt.Errorf("%q is not a bool", value)
+		}
+		return strconv.FormatBool(b), nil
+	case integer, float:
+		return f.bound(value)
+	case text:
+		return strconv.Quote(value), nil
+	}
+	return "", fmt.Errorf("only booleans, numbers and strings can have a default, not %s", f.typ)
+}
+
+// reserved are the names a parameter must not shadow in generated code
+var reserved = []string{"b", "v", "append", "len", "build", "fmt", "maps", "slices"}
+
+func paramName(field string) string {
+	r, size := utf8.DecodeRuneInString(field)
+	param := string(unicode.ToLower(r)) + field[size:]
+	if token.IsKeyword(param) || slices.Contains(reserved, param) {
+		param += "Value"
+	}
+	return param
+}
+
+// imports returns the imports of file that the field types refer to
+func imports(st *ast.StructType, file *ast.File) []string {
+	used := make(map[string]bool)
+	ast.Inspect(st, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok {
+				used[id.Name] = true
+			}
+		}
+		return true
+	})
+	var specs []string
+	for _, spec := range file.Imports {
+		importPath, _ := strconv.Unquote(spec.Path.Value)
+		name := path.Base(importPath)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		if used[name] {
+			specs = append(specs, strings.TrimSpace(fmt.Sprintf("%s %s", identOrEmpty(spec.Name), spec.Path.Value)))
+		}
+	}
+	return specs
+}
+
+// isStd reports whether an import spec is of the standard library
+func isStd(spec string) bool {
+	importPath, _ := strconv.Unquote(spec[strings.IndexByte(spec, '"'):])
+	found, err := gobuild.Default.Import(importPath, "", gobuild.FindOnly)
+	return err == nil && found.Goroot
+}
+
+func identOrEmpty(id *ast.Ident) string {
+	if id == nil {
+		return ""
+	}
+	return id.Name
+}
+
+func (p *pkg) generate(typeName, buildPath string) ([]byte, error) {
+	st, file, err := p.findStruct(typeName)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := p.fields(st)
+	if err != nil {
+		return nil, err
+	}
+
+	builder := typeName + "Builder"
+	specs := append(imports(st, file), strconv.Quote(buildPath))
+	var required, once, checks []string
+	var defaults []field
+	for _, f := range fields {
+		if f.required {
+			required = append(required, strconv.Quote(f.name))
+		}
+		if f.kind == boolean {
+			once = append(once, strconv.Quote(f.name))
+		}
+		if f.def != "" {
+			defaults = append(defaults, f)
+		}
+		if f.min != "" || f.max != "" {
+			checks = append(checks, "check"+typeName+f.name)
+			specs = append(specs, `"fmt"`)
+		}
+		switch f.kind {
+		case slice:
+			specs = append(specs, `"slices"`)
+		case mapping:
+			specs = append(specs, `"maps"`)
+		}
+	}
+	slices.Sort(specs)
+	specs = slices.Compact(specs)
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by buildergen; DO NOT EDIT.\n\npackage %s\n\nimport (\n", p.name)
+	// The standard library first, then the rest
+	for _, std := range []bool{true, false} {
+		for _, spec := range specs {
+			if isStd(spec) == std {
+				fmt.Fprintf(&b, "\t%s\n", spec)
+			}
+		}
+		fmt.Fprintln(&b)
+	}
+	fmt.Fprintf(&b, ")\n\n")
+
+	fmt.Fprintf(&b, "// %s builds %s values, checking the builder tags of %s.\n", builder, typeName, typeName)
+	fmt.Fprintf(&b, "// It is a value, like time.Time: each method returns a new builder and\n")
+	fmt.Fprintf(&b, "// leaves its receiver as it was, so a partly built one can be forked.\n")
+	fmt.Fprintf(&b, "type %s struct {\n\tbuilder build.Builder[%s]\n}\n\n", builder, typeName)
+
+	fmt.Fprintf(&b, "// New%s returns a builder checking the builder tags of %s\n", builder, typeName)
+	fmt.Fprintf(&b, "func New%s() %s {\n", builder, builder)
+	fmt.Fprintf(&b, "\treturn %s{builder: build.New[%s](%q)", builder, typeName, strings.ToLower(typeName))
+	for _, f := range defaults {
+		fmt.Fprintf(&b, ".\n\t\tDefault(build.Set(%q, func(v *%s) { v.%s = %s }))", f.name, typeName, f.name, f.def)
+	}
+	if len(required) > 0 {
+		fmt.Fprintf(&b, ".\n\t\tRequire(%s)", strings.Join(required, ", "))
+	}
+	if len(once) > 0 {
+		fmt.Fprintf(&b, ".\n\t\tOnce(%s)", strings.Join(once, ", "))
+	}
+	if len(checks) > 0 {
+		fmt.Fprintf(&b, ".\n\t\tCheck(%s)", strings.Join(checks, ", "))
+	}
+	fmt.Fprintf(&b, "}\n}\n\n")
+
+	for _, f := range fields {
+		set := func(value string) string {
+			return fmt.Sprintf("b.with(build.Set(%q, func(v *%s) { v.%s = %s }))", f.name, typeName, f.name, value)
+		}
+		switch f.kind {
+		case boolean:
+			fmt.Fprintf(&b, "// Add%s sets %s\n", f.name, f.name)
+			fmt.Fprintf(&b, "func (b %s) Add%s() %s {\n\treturn %s\n}\n\n", builder, f.name, builder, set("true"))
+		case slice:
+			fmt.Fprintf(&b, "// Add%s appends to %s\n", f.name, f.name)
+			fmt.Fprintf(&b, "func (b %s) Add%s(%s ...%s) %s {\n", builder, f.name, f.param, f.elem, builder)
+			fmt.Fprintf(&b, "\t%s = slices.Clone(%s)\n", f.param, f.param)
+			fmt.Fprintf(&b, "\treturn %s\n}\n\n", set(fmt.Sprintf("append(v.%s, %s...)", f.name, f.param)))
+		case mapping:
+			fmt.Fprintf(&b, "// With%s sets %s to a copy of %s\n", f.name, f.name, f.param)
+			fmt.Fprintf(&b, "func (b %s) With%s(%s %s) %s {\n", builder, f.name, f.param, f.typ, builder)
+			fmt.Fprintf(&b, "\t%s = maps.Clone(%s)\n", f.param, f.param)
+			fmt.Fprintf(&b, "\treturn %s\n}\n\n", set(f.param))
+		default:
+			fmt.Fprintf(&b, "// With%s sets %s\n", f.name, f.name)
+			fmt.Fprintf(&b, "func (b %s) With%s(%s %s) %s {\n\treturn %s\n}\n\n", builder, f.name, f.param, f.typ, builder, set(f.param))
+		}
+	}
+
+	fmt.Fprintf(&b, "// Check makes Build check rule too\n")
+	fmt.Fprintf(&b, "func (b %s) Check(rule build.Rule[%s]) %s {\n", builder, typeName, builder)
+	fmt.Fprintf(&b, "\treturn %s{builder: b.builder.Check(rule)}\n}\n\n", builder)
+	fmt.Fprintf(&b, "// Build returns the %s, or a *build.Error listing everything wrong with it\n", typeName)
+	fmt.Fprintf(&b, "func (b %s) Build() (%s, error) {\n\treturn b.builder.Build()\n}\n\n", builder, typeName)
+	fmt.Fprintf(&b, "func (b %s) with(option build.Option[%s]) %s {\n", builder, typeName, builder)
+	fmt.Fprintf(&b, "\treturn %s{builder: b.builder.With(option)}\n}\n", builder)
+
+	for _, f := range fields {
+		if f.min == "" && f.max == "" {
+			continue
+		}
+		expr, what := f.measured()
+		fmt.Fprintf(&b, "\nfunc check%s%s(v %s) error {\n", typeName, f.name, typeName)
+		if f.min != "" {
+			fmt.Fprintf(&b, "\tif %s < %s {\n", expr, f.min)
+			fmt.Fprintf(&b, "\t\treturn fmt.Errorf(\"%%w: %s is %%v, want at least %s\", build.ErrRange, %s)\n\t}\n", what, f.min, expr)
+		}
+		if f.max != "" {
+			fmt.Fprintf(&b, "\tif %s > %s {\n", expr, f.max)
+			fmt.Fprintf(&b, "\t\treturn fmt.Errorf(\"%%w: %s is %%v, want at most %s\", build.ErrRange, %s)\n\t}\n", what, f.max, expr)
+		}
+		fmt.Fprintf(&b, "\treturn nil\n}\n")
+	}
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("generated invalid code for %s: %w\n%s", typeName, err, b.Bytes())
+	}
+	return src, nil
+}
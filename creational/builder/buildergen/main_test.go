@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func TestGenerateGolden(t *testing.T) {
+	for _, tc := range []struct {
+		dir, typeName string
+	}{
+		{"kitchen", "Ticket"},
+	} {
+		t.Run(tc.typeName, func(t *testing.T) {
+			p, err := parsePackage(filepath.Join("testdata", tc.dir))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := p.generate(tc.typeName, defaultBuildPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			golden := filepath.Join("testdata", strings.ToLower(tc.typeName)+"_builder.golden")
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("generated code differs from %s; rerun with -update if the change is intended\n%s", golden, got)
+			}
+		})
+	}
+}
+
+func TestNegativeBoundOnUnsigned(t *testing.T) {
+	p, err := parsePackage(filepath.Join("testdata", "unsigned"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		typeName, want string
+	}{
+		{"Negative", "unsigned.go:4:2: field Covers: min: -1 is negative, but uint is unsigned"},
+		{"NegativeDefault", "unsigned.go:8:2: field Weight: default: -5 is negative, but uint16 is unsigned"},
+		{"TooBig", "unsigned.go:12:2: field Level: max: 300 is out of range for uint8"},
+		{"TooSmall", "unsigned.go:16:2: field Offset: min: -200 is out of range for int8"},
+		{"TooBigDefault", "unsigned.go:20:2: field Code: default: 0x80000000 is out of range for rune"},
+		{"NotANumber", "unsigned.go:24:2: field Count: max: \"-ten\" is not an integer"},
+	} {
+		_, err := p.generate(tc.typeName, defaultBuildPath)
+		if err == nil || !strings.HasSuffix(err.Error(), tc.want) {
+			t.Errorf("generate(%s) = %v, want an error ending in %q", tc.typeName, err, tc.want)
+		}
+	}
+
+	src, err := p.generate("Wide", defaultBuildPath)
+	if err != nil {
+		t.Fatalf("generate(Wide) = %v, want bounds that fit their fields accepted", err)
+	}
+	for _, want := range []string{"v.Size > 18446744073709551615", "v.Offset < -128", "v.Mask = 0xff"} {
+		if !bytes.Contains(src, []byte(want)) {
+			t.Errorf("generated code for Wide lacks %q", want)
+		}
+	}
+}
+
+func TestParamName(t *testing.T) {
+	for field, want := range map[string]string{
+		"Customer": "customer",
+		"Type":     "typeValue",
+		"Func":     "funcValue",
+		"Len":      "lenValue",
+		"B":        "bValue",
+		"URL":      "uRL",
+	} {
+		if got := paramName(field); got != want {
+			t.Errorf("paramName(%q) = %q, want %q", field, got, want)
+		}
+	}
+}
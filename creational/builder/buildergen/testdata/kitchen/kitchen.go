@@ -0,0 +1,24 @@
+package kitchen
+
+import (
+	clock "time"
+
+	"example.com/menu"
+)
+
+// Ticket has a field of every kind buildergen treats differently
+type Ticket struct {
+	Dishes   []menu.Dish       `builder:"required,min=1,max=8"`
+	Notes    map[string]string `builder:"max=4"`
+	Chef     *menu.Chef
+	Covers   uint8          `builder:"default=2,min=1,max=12"`
+	Tip      float64        `builder:"min=0"`
+	Wait     clock.Duration `builder:"required"`
+	Rush     bool           `builder:"default=false"`
+	Table    string         `builder:"default=bar,max=3"`
+	Type     string
+	Func     int `builder:"min=-1"`
+	Len      int
+	Secret   string `builder:"-"`
+	internal int
+}
@@ -0,0 +1,32 @@
+package unsigned
+
+type Negative struct {
+	Covers uint `builder:"min=-1"`
+}
+
+type NegativeDefault struct {
+	Weight uint16 `builder:"default=-5"`
+}
+
+type TooBig struct {
+	Level uint8 `builder:"max=300"`
+}
+
+type TooSmall struct {
+	Offset int8 `builder:"min=-200"`
+}
+
+type TooBigDefault struct {
+	Code rune `builder:"default=0x80000000"`
+}
+
+type NotANumber struct {
+	Count uint32 `builder:"max=-ten"`
+}
+
+// Wide bounds that fit their field must be accepted
+type Wide struct {
+	Size   uint64 `builder:"max=18446744073709551615"`
+	Offset int8   `builder:"min=-128,max=127"`
+	Mask   byte   `builder:"default=0xff"`
+}
@@ -1,10 +1,16 @@
 package main
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+
+	"go-design-patterns/creational/builder/build"
+)
 
 // Burger is the product being built
 type Burger struct {
 	Size       int
+	Bun        string
 	Cheese     bool
 	Pepperoni  bool
 	Lettuce    bool
@@ -12,75 +18,223 @@ type Burger struct {
 }
 
 func (b Burger) String() string {
-	return fmt.Sprintf("Burger{size=%d, cheese=%t, pepperoni=%t, lettuce=%t, tomato=%t}",
-		b.Size, b.Cheese, b.Pepperoni, b.Lettuce, b.Tomato)
+	return fmt.Sprintf("Burger{size=%d, bun=%s, cheese=%t, pepperoni=%t, lettuce=%t, tomato=%t}",
+		b.Size, b.Bun, b.Cheese, b.Pepperoni, b.Lettuce, b.Tomato)
 }
 
-// BurgerBuilder builds burgers step by step
-type BurgerBuilder struct {
-	burger Burger
+// Toppings lists the toppings the burger has
+func (b Burger) Toppings() []Topping {
+	var toppings []Topping
+	for _, t := range []Topping{Cheese, Pepperoni, Lettuce, Tomato} {
+		if b.Has(t) {
+			toppings = append(toppings, t)
+		}
+	}
+	return toppings
 }
 
-// NewBurgerBuilder creates a new burger builder
-func NewBurgerBuilder(size int) *BurgerBuilder {
-	return &BurgerBuilder{
-		burger: Burger{Size: size},
+// Has reports whether the burger has topping t
+func (b Burger) Has(t Topping) bool {
+	switch t {
+	case Cheese:
+		return b.Cheese
+	case Pepperoni:
+		return b.Pepperoni
+	case Lettuce:
+		return b.Lettuce
+	case Tomato:
+		return b.Tomato
 	}
+	return false
+}
+
+// WithSize sets the size of the burger, which every burger needs
+func WithSize(size int) build.Option[Burger] {
+	return build.Set("size", func(b *Burger) { b.Size = size })
+}
+
+// WithBun replaces the default sesame bun
+func WithBun(bun string) build.Option[Burger] {
+	return build.Set("bun", func(b *Burger) { b.Bun = bun })
+}
+
+// WithCheese adds cheese to the burger
+func WithCheese() build.Option[Burger] {
+	return build.Set(string(Cheese), func(b *Burger) { b.Cheese = true })
+}
+
+// WithPepperoni adds pepperoni to the burger
+func WithPepperoni() build.Option[Burger] {
+	return build.Set(string(Pepperoni), func(b *Burger) { b.Pepperoni = true })
+}
+
+// WithLettuce adds lettuce to the burger
+func WithLettuce() build.Option[Burger] {
+	return build.Set(string(Lettuce), func(b *Burger) { b.Lettuce = true })
+}
+
+// WithTomato adds tomato to the burger
+func WithTomato() build.Option[Burger] {
+	return build.Set(string(Tomato), func(b *Burger) { b.Tomato = true })
+}
+
+// Burgers returns the builder every burger starts from: a sesame bun by
+// default, a size required, each topping at most once, and DefaultRules
+func Burgers() build.Builder[Burger] {
+	return build.New[Burger]("burger").
+		Default(WithBun("sesame")).
+		Require("size").
+		Once(string(Cheese), string(Pepperoni), string(Lettuce), string(Tomato)).
+		Check(DefaultRules...)
+}
+
+// NewBurger builds a burger from functional options
+func NewBurger(options ...build.Option[Burger]) (Burger, error) {
+	return Burgers().With(options...).Build()
+}
+
+// BurgerBuilder builds burgers step by step. It is a value, like time.Time:
+// each step returns a new builder and leaves the one it was called on as it
+// was, so a half-built burger can be forked into several. The result of a
+// step must be used; bb.AddCheese() on its own does nothing.
+type BurgerBuilder struct {
+	builder build.Builder[Burger]
+}
+
+// NewBurgerBuilder creates a new burger builder checking DefaultRules
+func NewBurgerBuilder(size int) BurgerBuilder {
+	return BurgerBuilder{builder: Burgers().With(WithSize(size))}
 }
 
 // AddCheese adds cheese to the burger
-func (bb *BurgerBuilder) AddCheese() *BurgerBuilder {
-	bb.burger.Cheese = true
-	return bb
+func (bb BurgerBuilder) AddCheese() BurgerBuilder {
+	return bb.with(WithCheese())
 }
 
 // AddPepperoni adds pepperoni to the burger
-func (bb *BurgerBuilder) AddPepperoni() *BurgerBuilder {
-	bb.burger.Pepperoni = true
-	return bb
+func (bb BurgerBuilder) AddPepperoni() BurgerBuilder {
+	return bb.with(WithPepperoni())
 }
 
 // AddLettuce adds lettuce to the burger
-func (bb *BurgerBuilder) AddLettuce() *BurgerBuilder {
-	bb.burger.Lettuce = true
-	return bb
+func (bb BurgerBuilder) AddLettuce() BurgerBuilder {
+	return bb.with(WithLettuce())
 }
 
 // AddTomato adds tomato to the burger
-func (bb *BurgerBuilder) AddTomato() *BurgerBuilder {
-	bb.burger.Tomato = true
-	return bb
+func (bb BurgerBuilder) AddTomato() BurgerBuilder {
+	return bb.with(WithTomato())
 }
 
-// Build returns the final burger
-func (bb *BurgerBuilder) Build() Burger {
-	return bb.burger
+// AddBun replaces the default sesame bun
+func (bb BurgerBuilder) AddBun(bun string) BurgerBuilder {
+	return bb.with(WithBun(bun))
+}
+
+func (bb BurgerBuilder) with(option build.Option[Burger]) BurgerBuilder {
+	return BurgerBuilder{builder: bb.builder.With(option)}
+}
+
+// AddRule makes Build check rule too
+func (bb BurgerBuilder) AddRule(rule Rule) BurgerBuilder {
+	return BurgerBuilder{builder: bb.builder.Check(rule)}
+}
+
+// Build returns the final burger, or a *BuildError listing everything wrong
+// with it
+func (bb BurgerBuilder) Build() (Burger, error) {
+	return bb.builder.Build()
 }
 
 func main() {
 	fmt.Println("=== Builder Pattern Demo ===")
 	
 	// Build a custom burger using method chaining
-	customBurger := NewBurgerBuilder(14).
+	customBurger, err := NewBurgerBuilder(14).
 		AddPepperoni().
 		AddLettuce().
 		AddTomato().
 		Build()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
 	
 	fmt.Printf("Custom Burger: %s\n", customBurger)
 	
 	// Build a simple cheese burger
-	cheeseBurger := NewBurgerBuilder(10).
+	cheeseBurger, err := NewBurgerBuilder(10).
 		AddCheese().
 		AddTomato().
 		Build()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
 	
 	fmt.Printf("Cheese Burger: %s\n", cheeseBurger)
 	
 	// Build a simple burger with no toppings
-	simpleBurger := NewBurgerBuilder(8).Build()
+	simpleBurger, err := NewBurgerBuilder(8).Build()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
 	
 	fmt.Printf("Simple Burger: %s\n", simpleBurger)
+
+	// Build checks the burger and reports every violation at once
+	fmt.Println()
+	_, err = NewBurgerBuilder(-3).
+		AddCheese().
+		AddCheese().
+		AddPepperoni().
+		AddLettuce().
+		AddTomato().
+		Build()
+	fmt.Println(err)
+	fmt.Printf("Size error: %t, incompatible toppings: %t\n", errors.Is(err, ErrSize), errors.Is(err, ErrIncompatible))
+
+	// Custom rules add to the default ones
+	kidsMenu := func(b Burger) error {
+		if b.Size > 8 {
+			return fmt.Errorf("kids menu burgers are at most 8, not %d", b.Size)
+		}
+		return nil
+	}
+	_, err = NewBurgerBuilder(10).
+		AddRule(kidsMenu).
+		AddRule(NotTogether(Lettuce, Tomato)).
+		AddLettuce().
+		AddTomato().
+		Build()
+	fmt.Println(err)
+
+	// Functional options build a burger in one call, on top of defaults
+	fmt.Println()
+	ryeBurger, err := NewBurger(WithSize(12), WithBun("rye"), WithLettuce())
+	fmt.Printf("Rye Burger: %s %v\n", ryeBurger, err)
+	_, err = NewBurger(WithCheese())
+	fmt.Println(err)
+
+	// Builders never change once made, so a half-built one forks safely
+	base := NewBurgerBuilder(12).AddLettuce()
+	withCheese, _ := base.AddCheese().Build()
+	withTomato, _ := base.AddTomato().Build()
+	plain, _ := base.Build()
+	fmt.Printf("Forks:\n  %s\n  %s\n  %s\n", withCheese, withTomato, plain)
+
+	// OrderBuilder is generated from the tags of Order by buildergen
+	fmt.Println()
+	order, err := NewOrderBuilder().
+		WithCustomer("Ana").
+		AddBurgers(customBurger, cheeseBurger).
+		AddTakeaway().
+		Build()
+	fmt.Printf("Order: %s, %d burgers, table %d, takeaway %t %v\n",
+		order.Customer, len(order.Burgers), order.Table, order.Takeaway, err)
+	_, err = NewOrderBuilder().WithTable(99).AddTakeaway().AddTakeaway().Build()
+	fmt.Println(err)
 	
 	fmt.Println("\nBuilder pattern avoids telescoping constructor anti-pattern!")
 }
@@ -0,0 +1,13 @@
+package main
+
+//go:generate go run ./buildergen -type Order
+
+// Order is what a customer orders. Its builder, OrderBuilder, is generated
+// from the builder tags below; run go generate after changing them.
+type Order struct {
+	Customer string   `builder:"required"`
+	Burgers  []Burger `builder:"min=1,max=5"`
+	Table    int      `builder:"default=1,min=1,max=40"`
+	Takeaway bool
+	Note     string `builder:"max=140"`
+}
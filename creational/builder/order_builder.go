@@ -0,0 +1,93 @@
+// Code generated by buildergen; DO NOT EDIT.
+
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	"go-design-patterns/creational/builder/build"
+)
+
+// OrderBuilder builds Order values, checking the builder tags of Order.
+// It is a value, like time.Time: each method returns a new builder and
+// leaves its receiver as it was, so a partly built one can be forked.
+type OrderBuilder struct {
+	builder build.Builder[Order]
+}
+
+// NewOrderBuilder returns a builder checking the builder tags of Order
+func NewOrderBuilder() OrderBuilder {
+	return OrderBuilder{builder: build.New[Order]("order").
+		Default(build.Set("Table", func(v *Order) { v.Table = 1 })).
+		Require("Customer").
+		Once("Takeaway").
+		Check(checkOrderBurgers, checkOrderTable, checkOrderNote)}
+}
+
+// WithCustomer sets Customer
+func (b OrderBuilder) WithCustomer(customer string) OrderBuilder {
+	return b.with(build.Set("Customer", func(v *Order) { v.Customer = customer }))
+}
+
+// AddBurgers appends to Burgers
+func (b OrderBuilder) AddBurgers(burgers ...Burger) OrderBuilder {
+	burgers = slices.Clone(burgers)
+	return b.with(build.Set("Burgers", func(v *Order) { v.Burgers = append(v.Burgers, burgers...) }))
+}
+
+// WithTable sets Table
+func (b OrderBuilder) WithTable(table int) OrderBuilder {
+	return b.with(build.Set("Table", func(v *Order) { v.Table = table }))
+}
+
+// AddTakeaway sets Takeaway
+func (b OrderBuilder) AddTakeaway() OrderBuilder {
+	return b.with(build.Set("Takeaway", func(v *Order) { v.Takeaway = true }))
+}
+
+// WithNote sets Note
+func (b OrderBuilder) WithNote(note string) OrderBuilder {
+	return b.with(build.Set("Note", func(v *Order) { v.Note = note }))
+}
+
+// Check makes Build check rule too
+func (b OrderBuilder) Check(rule build.Rule[Order]) OrderBuilder {
+	return OrderBuilder{builder: b.builder.Check(rule)}
+}
+
+// Build returns the Order, or a *build.Error listing everything wrong with it
+func (b OrderBuilder) Build() (Order, error) {
+	return b.builder.Build()
+}
+
+func (b OrderBuilder) with(option build.Option[Order]) OrderBuilder {
+	return OrderBuilder{builder: b.builder.With(option)}
+}
+
+func checkOrderBurgers(v Order) error {
+	if len(v.Burgers) < 1 {
+		return fmt.Errorf("%w: the length of Burgers is %v, want at least 1", build.ErrRange, len(v.Burgers))
+	}
+	if len(v.Burgers) > 5 {
+		return fmt.Errorf("%w: the length of Burgers is %v, want at most 5", build.ErrRange, len(v.Burgers))
+	}
+	return nil
+}
+
+func checkOrderTable(v Order) error {
+	if v.Table < 1 {
+		return fmt.Errorf("%w: Table is %v, want at least 1", build.ErrRange, v.Table)
+	}
+	if v.Table > 40 {
+		return fmt.Errorf("%w: Table is %v, want at most 40", build.ErrRange, v.Table)
+	}
+	return nil
+}
+
+func checkOrderNote(v Order) error {
+	if len(v.Note) > 140 {
+		return fmt.Errorf("%w: the length of Note is %v, want at most 140", build.ErrRange, len(v.Note))
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"go-design-patterns/creational/builder/build"
+)
+
+// Topping names something put on a burger
+type Topping string
+
+const (
+	Cheese    Topping = "cheese"
+	Pepperoni Topping = "pepperoni"
+	Lettuce   Topping = "lettuce"
+	Tomato    Topping = "tomato"
+)
+
+// Rule checks a burger about to be built, returning what is wrong with it or
+// nil
+type Rule = build.Rule[Burger]
+
+// Violations wrap one of these, so callers can tell them apart with errors.Is
+var (
+	ErrSize            = errors.New("size out of range")
+	ErrTooManyToppings = errors.New("too many toppings")
+	ErrIncompatible    = errors.New("incompatible toppings")
+	ErrDuplicate       = build.ErrRepeated
+)
+
+// SizeBetween requires the size to be within lo and hi, inclusive
+func SizeBetween(lo, hi int) Rule {
+	return func(b Burger) error {
+		if b.Size < lo || b.Size > hi {
+			return fmt.Errorf("%w: %d is not between %d and %d", ErrSize, b.Size, lo, hi)
+		}
+		return nil
+	}
+}
+
+// MaxToppings limits the number of toppings
+func MaxToppings(limit int) Rule {
+	return func(b Burger) error {
+		if n := len(b.Toppings()); n > limit {
+			return fmt.Errorf("%w: %d, at most %d", ErrTooManyToppings, n, limit)
+		}
+		return nil
+	}
+}
+
+// NotTogether forbids a burger to have both toppings
+func NotTogether(a, b Topping) Rule {
+	return func(burger Burger) error {
+		if burger.Has(a) && burger.Has(b) {
+			return fmt.Errorf("%w: %s and %s", ErrIncompatible, a, b)
+		}
+		return nil
+	}
+}
+
+// DefaultRules are checked by every builder; AddRule adds to them
+var DefaultRules = []Rule{
+	SizeBetween(6, 20),
+	MaxToppings(3),
+	NotTogether(Cheese, Pepperoni),
+}
+
+// BuildError lists every reason a burger could not be built
+type BuildError = build.Error
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBuildReportsEveryBrokenRule(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		build   func() (Burger, error)
+		want    []error
+		message string
+	}{
+		{"builder", func() (Burger, error) {
+			return NewBurgerBuilder(30).AddCheese().AddPepperoni().AddLettuce().AddTomato().Build()
+		}, []error{ErrSize, ErrTooManyToppings, ErrIncompatible},
+			"invalid burger: size out of range: 30 is not between 6 and 20; " +
+				"too many toppings: 4, at most 3; incompatible toppings: cheese and pepperoni"},
+		{"options", func() (Burger, error) {
+			return NewBurger(WithSize(4), WithCheese(), WithCheese(), WithPepperoni())
+		}, []error{ErrDuplicate, ErrSize, ErrIncompatible},
+			"invalid burger: field set twice: cheese; size out of range: 4 is not between 6 and 20; " +
+				"incompatible toppings: cheese and pepperoni"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			burger, err := tc.build()
+			var buildErr *BuildError
+			if !errors.As(err, &buildErr) {
+				t.Fatalf("Build() = %v, %v, want a *BuildError", burger, err)
+			}
+			if len(buildErr.Violations) != len(tc.want) {
+				t.Errorf("%d violations %v, want %d", len(buildErr.Violations), buildErr.Violations, len(tc.want))
+			}
+			// Each violation wraps its own sentinel, in the order checked
+			for i, want := range tc.want {
+				if !errors.Is(err, want) {
+					t.Errorf("errors.Is(err, %v) = false", want)
+				}
+				if i < len(buildErr.Violations) && !errors.Is(buildErr.Violations[i], want) {
+					t.Errorf("violation %d = %v, want one wrapping %v", i, buildErr.Violations[i], want)
+				}
+			}
+			if err.Error() != tc.message {
+				t.Errorf("Error() = %q, want %q", err, tc.message)
+			}
+			if burger != (Burger{}) {
+				t.Errorf("a failed Build returned %v, want the zero burger", burger)
+			}
+		})
+	}
+}
+
+func TestRulesPassValidBurgers(t *testing.T) {
+	for _, builder := range []BurgerBuilder{
+		NewBurgerBuilder(6),
+		NewBurgerBuilder(20).AddCheese().AddLettuce().AddTomato(),
+		NewBurgerBuilder(12).AddPepperoni().AddLettuce().AddTomato(),
+	} {
+		if burger, err := builder.Build(); err != nil {
+			t.Errorf("Build() = %v, %v", burger, err)
+		}
+	}
+
+	// An added rule is checked alongside the defaults
+	errNoBun := errors.New("no bun")
+	noBrioche := func(b Burger) error {
+		if b.Bun == "brioche" {
+			return errNoBun
+		}
+		return nil
+	}
+	_, err := NewBurgerBuilder(21).AddBun("brioche").AddRule(noBrioche).Build()
+	if !errors.Is(err, errNoBun) || !errors.Is(err, ErrSize) {
+		t.Errorf("Build() = %v, want it to wrap ErrSize and the added rule's error", err)
+	}
+}
@@ -8,67 +8,67 @@ echo
 echo "📦 CREATIONAL PATTERNS"
 echo "======================"
 echo "🏭 Simple Factory:"
-go run creational/simplefactory/demo.go
+go run ./creational/simplefactory
 echo
 echo "🏭 Factory Method:"
-go run creational/factorymethod/demo.go
+go run ./creational/factorymethod
 echo
 echo "🏭 Abstract Factory:"
-go run creational/abstractfactory/demo.go
+go run ./creational/abstractfactory
 echo
 echo "🏗️ Builder:"
-go run creational/builder/demo.go
+go run ./creational/builder
 echo
 echo "📋 Prototype:"
-go run creational/prototype/demo.go
+go run ./creational/prototype
 echo
 echo "👤 Singleton:"
-go run creational/singleton/demo.go
+go run ./creational/singleton
 
 echo
 echo "🏗️ STRUCTURAL PATTERNS"
 echo "======================"
 echo "🔌 Adapter:"
-go run structural/adapter/demo.go
+go run ./structural/adapter
 echo
 echo "🌉 Bridge:"
-go run structural/bridge/demo.go
+go run ./structural/bridge
 echo
 echo "🌳 Composite:"
-go run structural/composite/demo.go
+go run ./structural/composite
 echo
 echo "🎨 Decorator:"
-go run structural/decorator/demo.go
+go run ./structural/decorator
 echo
 echo "🎭 Facade:"
-go run structural/facade/demo.go
+go run ./structural/facade
 echo
 echo "🪶 Flyweight:"
-go run structural/flyweight/demo.go
+go run ./structural/flyweight
 echo
 echo "🛡️ Proxy:"
-go run structural/proxy/demo.go
+go run ./structural/proxy
 
 echo
 echo "🎭 BEHAVIORAL PATTERNS"
 echo "======================"
 echo "⛓️ Chain of Responsibility:"
-go run behavioral/chainofresponsibility/demo.go
+go run ./behavioral/chainofresponsibility
 echo
 echo "📢 Command:"
-go run behavioral/command/demo.go
+go run ./behavioral/command
 echo
 echo "🔄 Iterator:"
-go run behavioral/iterator/demo.go
+go run ./behavioral/iterator
 echo
 echo "👁️ Observer:"
-go run behavioral/observer/demo.go
+go run ./behavioral/observer
 echo
 echo "🎯 Strategy:"
-go run behavioral/strategy/demo.go
+go run ./behavioral/strategy
 echo
 echo "🔄 State:"
-go run behavioral/state/demo.go
+go run ./behavioral/state
 
 echo
 echo "✅ Completed demos!"